@@ -0,0 +1,906 @@
+// Package adaptertest 提供适配器的一致性测试，任何适配器均可在自身的测试中调用 Run 校验其行为
+package adaptertest
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/iamdanielyin/db"
+)
+
+// MetadataName 测试使用的元数据名称
+const MetadataName = "Item"
+
+// Item 测试使用的档案，原始名称为item，字段原始名称依次为id、name、status、score、remark
+//
+// 适配器需保证对应的集合（表）已存在，且未指定ID时由数据源生成主键
+type Item struct {
+	ID     string
+	Name   string
+	Status int
+	Score  float64
+	Remark string
+}
+
+// Items 每个测试开始前写入的数据
+var Items = []Item{
+	{ID: "1", Name: "Apple", Status: 1, Score: 9.5, Remark: "red fruit"},
+	{ID: "2", Name: "Banana", Status: 2, Score: 7},
+	{ID: "3", Name: "Cherry", Status: 1, Score: 8, Remark: "small.red"},
+	{ID: "4", Name: "apricot", Status: 3, Score: 6.5},
+	{ID: "5", Name: "Date", Status: 2, Score: 9, Remark: "sweet"},
+}
+
+// VersionedItem 与Item使用同一集合（表），以Status作为乐观锁版本号
+type VersionedItem struct {
+	ID     string
+	Name   string
+	Status int `db:"version"`
+}
+
+func (VersionedItem) Metadata() db.Metadata {
+	return db.Metadata{NativeName: "item"}
+}
+
+type Options struct {
+	SkipTransaction bool // 数据源不支持事务时跳过事务相关测试
+}
+
+func WithOptionSkipTransaction(skip bool) func(*Options) {
+	return func(o *Options) {
+		o.SkipTransaction = skip
+	}
+}
+
+// Run 执行全部一致性测试，connect 在每个子测试开始时调用，需返回一个新的数据源连接
+func Run(t *testing.T, connect func(t *testing.T) *db.Connection, opts ...func(*Options)) {
+	var options Options
+	for _, fn := range opts {
+		fn(&options)
+	}
+	tests := []struct {
+		name string
+		fn   func(*testing.T, *db.Connection)
+		tx   bool
+	}{
+		{name: "Insert", fn: testInsert},
+		{name: "FindOne", fn: testFindOne},
+		{name: "Conditions", fn: testConditions},
+		{name: "OrderBy", fn: testOrderBy},
+		{name: "Paginate", fn: testPaginate},
+		{name: "Scroll", fn: testScroll},
+		{name: "Project", fn: testProject},
+		{name: "Update", fn: testUpdate},
+		{name: "Delete", fn: testDelete},
+		{name: "Updater", fn: testUpdater},
+		{name: "Upsert", fn: testUpsert},
+		{name: "FindOneAndModify", fn: testFindOneAndModify},
+		{name: "Version", fn: testVersion},
+		{name: "BulkWrite", fn: testBulkWrite},
+		{name: "Cursor", fn: testCursor},
+		{name: "Aggregate", fn: testAggregate},
+		{name: "Distinct", fn: testDistinct},
+		{name: "Transaction", fn: testTransaction, tx: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.tx && options.SkipTransaction {
+				t.Skip("transaction is not supported")
+			}
+			tt.fn(t, setup(t, connect))
+		})
+	}
+}
+
+func setup(t *testing.T, connect func(t *testing.T) *db.Connection) *db.Connection {
+	sess := connect(t)
+	if err := sess.RegisterMetadata(&Item{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.UnregisterMetadata(MetadataName)
+	})
+	model := sess.Model(MetadataName)
+	if _, err := model.Find().Unscoped().DeleteMany(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := model.InsertMany(Items); err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+// findIDs 返回按ID排序后的匹配结果
+func findIDs(t *testing.T, res db.Result) []string {
+	t.Helper()
+	var items []Item
+	if err := res.All(&items); err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func orderedIDs(t *testing.T, res db.Result) []string {
+	t.Helper()
+	var items []Item
+	if err := res.All(&items); err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func count(t *testing.T, sess *db.Connection, filters ...interface{}) int {
+	t.Helper()
+	n, err := sess.Model(MetadataName).Find(filters...).Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func testInsert(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	one, err := model.InsertOne(&Item{Name: "Elderberry", Status: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if one.StringID() == "" {
+		t.Error("InsertOne().StringID() should not be empty")
+	}
+	many, err := model.InsertMany([]Item{{Name: "Fig", Status: 4}, {Name: "Grape", Status: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := many.StringIDs()
+	if len(ids) != 2 || ids[0] == "" || ids[1] == "" || ids[0] == ids[1] {
+		t.Errorf("InsertMany().StringIDs() = %v, want 2 distinct ids", ids)
+	}
+	if n := count(t, sess, db.Cond{"Status": 4}); n != 3 {
+		t.Errorf("Count() = %d, want 3", n)
+	}
+	if n := count(t, sess); n != len(Items)+3 {
+		t.Errorf("Count() = %d, want %d", n, len(Items)+3)
+	}
+}
+
+func testFindOne(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	var item Item
+	if err := model.Find(db.Cond{"ID": "3"}).One(&item); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(item, Items[2]) {
+		t.Errorf("One() = %+v, want %+v", item, Items[2])
+	}
+	// 无匹配记录时不返回错误且不修改dst
+	missing := Item{Name: "unchanged"}
+	if err := model.Find(db.Cond{"ID": "404"}).One(&missing); err != nil {
+		t.Errorf("One() without match returned error: %v", err)
+	}
+	if missing.Name != "unchanged" {
+		t.Errorf("One() without match modified dst: %+v", missing)
+	}
+	err := model.Find(db.Cond{"ID": "404"}).One(&missing, db.WithQueryOptionReturnNotFound(true))
+	if !errors.Is(err, db.ErrNotFound) || missing.Name != "unchanged" {
+		t.Errorf("One(ReturnNotFound) without match = %+v, %v, want ErrNotFound", missing, err)
+	}
+	var items []Item
+	if err := model.Find(db.Cond{"ID": "404"}).All(&items); err != nil {
+		t.Errorf("All() without match returned error: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("All() without match = %+v", items)
+	}
+}
+
+func testConditions(t *testing.T, sess *db.Connection) {
+	tests := []struct {
+		name    string
+		filters []interface{}
+		want    []string
+	}{
+		{name: "Eq", filters: []interface{}{db.Cond{"Name": "Apple"}}, want: []string{"1"}},
+		{name: "EqOperator", filters: []interface{}{db.Cond{"Status =": 2}}, want: []string{"2", "5"}},
+		{name: "NotEq", filters: []interface{}{db.Cond{"Status !=": 1}}, want: []string{"2", "4", "5"}},
+		{name: "Prefix", filters: []interface{}{db.Cond{"Name *=": "ap"}}, want: []string{"1", "4"}},
+		{name: "Suffix", filters: []interface{}{db.Cond{"Name =*": "RY"}}, want: []string{"3"}},
+		{name: "Contains", filters: []interface{}{db.Cond{"Name *": "an"}}, want: []string{"2"}},
+		{name: "ContainsLiteral", filters: []interface{}{db.Cond{"Remark *": "."}}, want: []string{"3"}},
+		{name: "Gt", filters: []interface{}{db.Cond{"Score >": 8}}, want: []string{"1", "5"}},
+		{name: "Gte", filters: []interface{}{db.Cond{"Score >=": 8}}, want: []string{"1", "3", "5"}},
+		{name: "Lt", filters: []interface{}{db.Cond{"Status <": 2}}, want: []string{"1", "3"}},
+		{name: "Lte", filters: []interface{}{db.Cond{"Status <=": 2}}, want: []string{"1", "2", "3", "5"}},
+		{name: "RegExp", filters: []interface{}{db.Cond{"Name ~=": "^[AB]"}}, want: []string{"1", "2"}},
+		{name: "RegExpOptions", filters: []interface{}{db.Cond{"Name ~=": "/^a/i"}}, want: []string{"1", "4"}},
+		{name: "In", filters: []interface{}{db.Cond{"Status $in": []int{2, 3}}}, want: []string{"2", "4", "5"}},
+		{name: "InEmpty", filters: []interface{}{db.Cond{"Status $in": []int{}}}, want: []string{}},
+		{name: "NotIn", filters: []interface{}{db.Cond{"Status $nin": []int{2, 3}}}, want: []string{"1", "3"}},
+		{name: "Exists", filters: []interface{}{db.Cond{"Remark $exists": true}}, want: []string{"1", "3", "5"}},
+		{name: "NotExists", filters: []interface{}{db.Cond{"Remark $exists": false}}, want: []string{"2", "4"}},
+		{name: "MultipleKeys", filters: []interface{}{db.Cond{"Status": 1, "Score >": 9}}, want: []string{"1"}},
+		{name: "MultipleFilters", filters: []interface{}{db.Cond{"Status": 2}, db.Cond{"Score <": 9}}, want: []string{"2"}},
+		{
+			name:    "And",
+			filters: []interface{}{db.And(db.Cond{"Status": 1}, db.Cond{"Score <": 9})},
+			want:    []string{"3"},
+		},
+		{
+			name:    "Or",
+			filters: []interface{}{db.Or(db.Cond{"Status": 3}, db.Cond{"Name": "Apple"})},
+			want:    []string{"1", "4"},
+		},
+		{
+			name: "Nested",
+			filters: []interface{}{db.And(
+				db.Or(db.Cond{"Status": 1}, db.Cond{"Status": 2}),
+				db.Cond{"Remark $exists": true},
+			)},
+			want: []string{"1", "3", "5"},
+		},
+	}
+	model := sess.Model(MetadataName)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findIDs(t, model.Find(tt.filters...)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() = %v, want %v", got, tt.want)
+			}
+			if n := count(t, sess, tt.filters...); n != len(tt.want) {
+				t.Errorf("Count() = %d, want %d", n, len(tt.want))
+			}
+		})
+	}
+	t.Run("ResultAndOr", func(t *testing.T) {
+		res := model.Find(db.Cond{"Status $in": []int{1, 2}}).And(db.Cond{"Score >=": 9})
+		if got, want := findIDs(t, res), []string{"1", "5"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("And() = %v, want %v", got, want)
+		}
+		res = model.Find().Or(db.Cond{"Status": 3}, db.Cond{"Name": "Banana"})
+		if got, want := findIDs(t, res), []string{"2", "4"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Or() = %v, want %v", got, want)
+		}
+	})
+}
+
+func testOrderBy(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	if got, want := orderedIDs(t, model.Find().OrderBy("-Score")), []string{"1", "5", "3", "2", "4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderBy(-Score) = %v, want %v", got, want)
+	}
+	if got, want := orderedIDs(t, model.Find().OrderBy("Status", "-Score")), []string{"1", "3", "5", "2", "4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderBy(Status, -Score) = %v, want %v", got, want)
+	}
+	var item Item
+	if err := model.Find().OrderBy("Score").One(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.ID != "4" {
+		t.Errorf("OrderBy(Score).One() = %+v, want ID 4", item)
+	}
+}
+
+func testPaginate(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	tests := []struct {
+		size, page uint
+		want       []string
+	}{
+		{size: 2, page: 0, want: []string{"1", "2"}},
+		{size: 2, page: 1, want: []string{"1", "2"}},
+		{size: 2, page: 2, want: []string{"3", "4"}},
+		{size: 2, page: 3, want: []string{"5"}},
+		{size: 2, page: 4, want: []string{}},
+		{size: 0, page: 2, want: []string{"1", "2", "3", "4", "5"}},
+	}
+	for _, tt := range tests {
+		got := orderedIDs(t, model.Find().OrderBy("ID").Paginate(tt.size).Page(tt.page))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Paginate(%d).Page(%d) = %v, want %v", tt.size, tt.page, got, tt.want)
+		}
+	}
+
+	res := model.Find(db.Cond{"Status !=": 3}).Paginate(3).Page(2)
+	if n, err := res.TotalRecords(); err != nil || n != 4 {
+		t.Errorf("TotalRecords() = %d, %v, want 4", n, err)
+	}
+	if n, err := res.TotalPages(); err != nil || n != 2 {
+		t.Errorf("TotalPages() = %d, %v, want 2", n, err)
+	}
+	// 未分页时视为仅有一页
+	if n, err := model.Find().TotalPages(); err != nil || n != 1 {
+		t.Errorf("TotalPages() without Paginate = %d, %v, want 1", n, err)
+	}
+}
+
+func testScroll(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	scroll := func(res db.Result) ([]string, db.PageTokens) {
+		t.Helper()
+		var items []Item
+		tokens, err := res.Scroll(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, len(items))
+		for i, item := range items {
+			ids[i] = item.ID
+		}
+		return ids, tokens
+	}
+	pages := [][]string{{"1", "5"}, {"3", "2"}, {"4"}}
+	var tokens []db.PageTokens
+	for i, want := range pages {
+		res := model.Find().OrderBy("-Score").Paginate(2)
+		if i > 0 {
+			res.After(tokens[i-1].Next)
+		}
+		got, tk := scroll(res)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("page %d = %v, want %v", i+1, got, want)
+		}
+		if (tk.Next == "") != (i == len(pages)-1) || (tk.Prev == "") != (i == 0) {
+			t.Errorf("page %d tokens = %+v", i+1, tk)
+		}
+		tokens = append(tokens, tk)
+	}
+	// 向前翻页
+	got, tk := scroll(model.Find().OrderBy("-Score").Paginate(2).Before(tokens[2].Prev))
+	if !reflect.DeepEqual(got, pages[1]) || tk.Prev == "" || tk.Next == "" {
+		t.Errorf("Before(page 3) = %v, %+v, want %v", got, tk, pages[1])
+	}
+	got, tk = scroll(model.Find().OrderBy("-Score").Paginate(2).Before(tk.Prev))
+	if !reflect.DeepEqual(got, pages[0]) || tk.Prev != "" {
+		t.Errorf("Before(page 2) = %v, %+v, want %v", got, tk, pages[0])
+	}
+
+	// 排序字段相同时按主键排序
+	got, tk = scroll(model.Find(db.Cond{"Status $in": []int{1, 2}}).OrderBy("Status").Paginate(3))
+	if !reflect.DeepEqual(got, []string{"1", "3", "2"}) {
+		t.Errorf("page 1 = %v, want [1 3 2]", got)
+	}
+	if got, _ = scroll(model.Find(db.Cond{"Status $in": []int{1, 2}}).OrderBy("Status").Paginate(3).After(tk.Next)); !reflect.DeepEqual(got, []string{"5"}) {
+		t.Errorf("page 2 = %v, want [5]", got)
+	}
+
+	var items []Item
+	if _, err := model.Find().OrderBy("Name").After(tk.Next).Scroll(&items); err == nil {
+		t.Error("Scroll() should reject a token of different sort fields")
+	}
+	if _, err := model.Find().After("invalid").Scroll(&items); err == nil {
+		t.Error("Scroll() should reject an invalid token")
+	}
+}
+
+func testProject(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	var item Item
+	if err := model.Find(db.Cond{"ID": "1"}).Project("Name", "Status").One(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "Apple" || item.Status != 1 || item.Score != 0 || item.Remark != "" {
+		t.Errorf("Project(Name, Status) = %+v", item)
+	}
+	item = Item{}
+	if err := model.Find(db.Cond{"ID": "1"}).Project("-Score", "-Remark").One(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.ID != "1" || item.Name != "Apple" || item.Score != 0 || item.Remark != "" {
+		t.Errorf("Project(-Score, -Remark) = %+v", item)
+	}
+}
+
+func testUpdate(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	// 多条记录匹配时仅修改其中一条
+	n, err := model.Find(db.Cond{"Status": 1}).UpdateOne(map[string]interface{}{"Remark": "updated"})
+	if err != nil || n != 1 {
+		t.Fatalf("UpdateOne() = %d, %v, want 1", n, err)
+	}
+	if n := count(t, sess, db.Cond{"Status": 1, "Remark": "updated"}); n != 1 {
+		t.Errorf("UpdateOne() updated %d items, want 1", n)
+	}
+
+	n, err = model.Find(db.Cond{"Status": 2}).UpdateMany(map[string]interface{}{"Status": 5, "Score": 1.5})
+	if err != nil || n != 2 {
+		t.Fatalf("UpdateMany() = %d, %v, want 2", n, err)
+	}
+	var item Item
+	if err := model.Find(db.Cond{"ID": "2"}).One(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != 5 || item.Score != 1.5 || item.Name != "Banana" {
+		t.Errorf("UpdateMany() result = %+v", item)
+	}
+
+	n, err = model.Find(db.Cond{"ID": "4"}).UpdateOne(&Item{Name: "Avocado"})
+	if err != nil || n != 1 {
+		t.Fatalf("UpdateOne(struct) = %d, %v, want 1", n, err)
+	}
+	// 结构体中的零值字段不会被更新
+	if err := model.Find(db.Cond{"ID": "4"}).One(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "Avocado" || item.Status != 3 || item.Score != 6.5 {
+		t.Errorf("UpdateOne(struct) result = %+v", item)
+	}
+
+	if n, err := model.Find(db.Cond{"ID": "404"}).UpdateOne(map[string]interface{}{"Name": "x"}); err != nil || n != 0 {
+		t.Errorf("UpdateOne() without match = %d, %v, want 0", n, err)
+	}
+	if n, err := model.Find(db.Cond{"ID": "404"}).UpdateMany(map[string]interface{}{"Name": "x"}); err != nil || n != 0 {
+		t.Errorf("UpdateMany() without match = %d, %v, want 0", n, err)
+	}
+}
+
+func testDelete(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	n, err := model.Find(db.Cond{"Status": 2}).DeleteOne()
+	if err != nil || n != 1 {
+		t.Fatalf("DeleteOne() = %d, %v, want 1", n, err)
+	}
+	if n := count(t, sess, db.Cond{"Status": 2}); n != 1 {
+		t.Errorf("Count() after DeleteOne() = %d, want 1", n)
+	}
+	n, err = model.Find(db.Cond{"Status $in": []int{1, 3}}).DeleteMany()
+	if err != nil || n != 3 {
+		t.Fatalf("DeleteMany() = %d, %v, want 3", n, err)
+	}
+	if n := count(t, sess); n != 1 {
+		t.Errorf("Count() after DeleteMany() = %d, want 1", n)
+	}
+	if n, err := model.Find(db.Cond{"ID": "404"}).DeleteOne(); err != nil || n != 0 {
+		t.Errorf("DeleteOne() without match = %d, %v, want 0", n, err)
+	}
+	if n, err := model.Find(db.Cond{"ID": "404"}).DeleteMany(); err != nil || n != 0 {
+		t.Errorf("DeleteMany() without match = %d, %v, want 0", n, err)
+	}
+}
+
+func testUpdater(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	n, err := model.Find(db.Cond{"ID": "1"}).UpdateOne(db.Update().Inc("Status", 2).Set("Name", "Apple2").Unset("Remark"))
+	if err != nil || n != 1 {
+		t.Fatalf("UpdateOne(Updater) = %d, %v, want 1", n, err)
+	}
+	var item Item
+	if err := model.Find(db.Cond{"ID": "1"}).One(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != 3 || item.Name != "Apple2" || item.Remark != "" || item.Score != 9.5 {
+		t.Errorf("UpdateOne(Updater) result = %+v", item)
+	}
+
+	// 减少数值
+	n, err = model.Find(db.Cond{"Status": 2}).UpdateMany(db.Update().Inc("Score", -0.5))
+	if err != nil || n != 2 {
+		t.Fatalf("UpdateMany(Inc) = %d, %v, want 2", n, err)
+	}
+	if got := findIDs(t, model.Find(db.Cond{"Score $in": []float64{6.5, 8.5}})); !reflect.DeepEqual(got, []string{"2", "4", "5"}) {
+		t.Errorf("UpdateMany(Inc) matched %v, want [2 4 5]", got)
+	}
+
+	res, err := model.Find(db.Cond{"Name": "Kiwi"}).Upsert(db.Update().Inc("Status", 1).Set("Score", 3.5).SetOnInsert("Remark", "new"))
+	if err != nil || !res.Inserted() {
+		t.Fatalf("Upsert(Updater) = %v, %v, want inserted", res, err)
+	}
+	item = Item{}
+	if err := model.Find(db.Cond{"Name": "Kiwi"}).One(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != 1 || item.Score != 3.5 || item.Remark != "new" {
+		t.Errorf("Upsert(Updater) inserted %+v", item)
+	}
+
+	// 修改已有记录时忽略SetOnInsert
+	if _, err := model.Find(db.Cond{"Name": "Kiwi"}).Upsert(db.Update().Inc("Status", 1).SetOnInsert("Remark", "again")); err != nil {
+		t.Fatal(err)
+	}
+	item = Item{}
+	if err := model.Find(db.Cond{"Name": "Kiwi"}).One(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != 2 || item.Remark != "new" {
+		t.Errorf("Upsert(SetOnInsert) updated %+v", item)
+	}
+}
+
+func testUpsert(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	// 未匹配时新增，等值条件作为新记录的初始值
+	res, err := model.Find(db.Cond{"Name": "Fig"}).Upsert(&Item{Status: 5, Score: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Inserted() || res.StringID() == "" {
+		t.Errorf("Upsert() = inserted %v, id %q, want a new record", res.Inserted(), res.StringID())
+	}
+	var item Item
+	if err := model.Find(db.Cond{"Name": "Fig"}).One(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != 5 || item.Score != 1 {
+		t.Errorf("Upsert() inserted %+v", item)
+	}
+
+	res, err = model.Find(db.Cond{"Name": "Fig"}).Upsert(map[string]interface{}{"Status": 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inserted() {
+		t.Error("Upsert() on an existing record inserted a new one")
+	}
+	if n := count(t, sess, db.Cond{"Name": "Fig"}); n != 1 {
+		t.Errorf("Count(Name = Fig) = %d, want 1", n)
+	}
+	if n := count(t, sess, db.Cond{"Name": "Fig", "Status": 6}); n != 1 {
+		t.Errorf("Upsert() did not update the existing record")
+	}
+	if n := count(t, sess); n != len(Items)+1 {
+		t.Errorf("Count() = %d, want %d", n, len(Items)+1)
+	}
+}
+
+func testFindOneAndModify(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	// 默认返回修改前的记录
+	var item Item
+	if err := model.Find(db.Cond{"Status": 1}).OrderBy("-Score").FindOneAndUpdate(&Item{Remark: "top"}, &item, false); err != nil {
+		t.Fatal(err)
+	}
+	if item.ID != "1" || item.Remark != "red fruit" {
+		t.Errorf("FindOneAndUpdate() = %+v, want the original item 1", item)
+	}
+	if n := count(t, sess, db.Cond{"ID": "1", "Remark": "top"}); n != 1 {
+		t.Error("FindOneAndUpdate() did not update item 1")
+	}
+
+	item = Item{}
+	if err := model.Find(db.Cond{"Status": 2}).OrderBy("Score").FindOneAndUpdate(map[string]interface{}{"Remark": "low"}, &item, true); err != nil {
+		t.Fatal(err)
+	}
+	if item.ID != "2" || item.Remark != "low" || item.Name != "Banana" {
+		t.Errorf("FindOneAndUpdate(returnNew) = %+v, want the updated item 2", item)
+	}
+
+	item = Item{}
+	if err := model.Find(db.Cond{"Status": 1}).OrderBy("Score").FindOneAndDelete(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.ID != "3" || item.Name != "Cherry" {
+		t.Errorf("FindOneAndDelete() = %+v, want item 3", item)
+	}
+	if n := count(t, sess, db.Cond{"ID": "3"}); n != 0 {
+		t.Error("FindOneAndDelete() did not delete item 3")
+	}
+	if n := count(t, sess); n != len(Items)-1 {
+		t.Errorf("Count() = %d, want %d", n, len(Items)-1)
+	}
+
+	// 未匹配时不修改目标
+	item = Item{}
+	if err := model.Find(db.Cond{"ID": "404"}).FindOneAndUpdate(&Item{Remark: "x"}, &item, true); err != nil || item.ID != "" {
+		t.Errorf("FindOneAndUpdate() without match = %+v, %v", item, err)
+	}
+	if err := model.Find(db.Cond{"ID": "404"}).FindOneAndDelete(&item); err != nil || item.ID != "" {
+		t.Errorf("FindOneAndDelete() without match = %+v, %v", item, err)
+	}
+	err := model.Find(db.Cond{"ID": "404"}).FindOneAndUpdate(&Item{Remark: "x"}, &item, true, db.WithQueryOptionReturnNotFound(true))
+	if !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("FindOneAndUpdate(ReturnNotFound) without match = %v, want ErrNotFound", err)
+	}
+	err = model.Find(db.Cond{"ID": "404"}).FindOneAndDelete(&item, db.WithQueryOptionReturnNotFound(true))
+	if !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("FindOneAndDelete(ReturnNotFound) without match = %v, want ErrNotFound", err)
+	}
+}
+
+func testVersion(t *testing.T, sess *db.Connection) {
+	if err := sess.RegisterMetadata(&VersionedItem{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.UnregisterMetadata("VersionedItem")
+	})
+	model := sess.Model("VersionedItem")
+	var first, second VersionedItem
+	if err := model.Find(db.Cond{"ID": "1"}).One(&first); err != nil {
+		t.Fatal(err)
+	}
+	second = first
+
+	first.Name = "Apple2"
+	if n, err := model.Find(db.Cond{"ID": "1"}).UpdateOne(&first); err != nil || n != 1 || first.Status != 2 {
+		t.Fatalf("UpdateOne() = %d, %v, Status = %d, want 1, nil, 2", n, err, first.Status)
+	}
+	// 读取后版本号已被修改，写入时不能匹配到记录
+	second.Name = "Apple3"
+	if n, err := model.Find(db.Cond{"ID": "1"}).UpdateOne(&second); !errors.Is(err, db.ErrStaleObject) || n != 0 {
+		t.Errorf("UpdateOne() with a stale version = %d, %v, want 0, ErrStaleObject", n, err)
+	}
+	if n, err := model.Find(db.Cond{"ID": "1", "Status": 1}).DeleteOne(); err != nil || n != 0 {
+		t.Errorf("DeleteOne() with a stale version = %d, %v, want 0", n, err)
+	}
+	var got Item
+	if err := sess.Model(MetadataName).Find(db.Cond{"ID": "1"}).One(&got); err != nil || got.Name != "Apple2" || got.Status != 2 {
+		t.Errorf("One() = %+v, %v, want Name Apple2 and Status 2", got, err)
+	}
+}
+
+func testBulkWrite(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	// 无序写入时失败的操作不影响其余操作，结果与错误中的下标均对应原始操作
+	res, err := model.BulkWrite([]db.WriteOp{
+		db.InsertOp(&Item{Name: "Fig", Status: 4}),
+		{Action: "UNKNOWN"},
+		db.UpdateManyOp(map[string]interface{}{"Status": 5}, db.Cond{"Status": 2}),
+		db.DeleteOneOp(db.Cond{"ID": "4"}),
+	}, false)
+	var bwe *db.BulkWriteError
+	if !errors.As(err, &bwe) || len(bwe.Errors) != 1 || bwe.Errors[0].Index != 1 {
+		t.Fatalf("BulkWrite(unordered) error = %v, want operation 1 failed", err)
+	}
+	if res.InsertedCount != 1 || res.UpdatedCount != 2 || res.DeletedCount != 1 {
+		t.Errorf("BulkWrite(unordered) = %+v, want 1 inserted, 2 updated and 1 deleted", res)
+	}
+	if r := res.Inserted[0]; r == nil || r.StringID() == "" {
+		t.Errorf("Inserted[0] = %v, want the new id", r)
+	}
+	if n := count(t, sess, db.Cond{"Status": 5}); n != 2 {
+		t.Errorf("Count(Status=5) = %d, want 2", n)
+	}
+	if got := findIDs(t, model.Find(db.Cond{"ID $in": []string{"1", "2", "3", "4", "5"}})); !reflect.DeepEqual(got, []string{"1", "2", "3", "5"}) {
+		t.Errorf("remaining ids = %v, want [1 2 3 5]", got)
+	}
+
+	// 有序写入在首个失败处停止，批量写入不是原子操作，失败前已执行的操作不会回滚
+	res, err = model.BulkWrite([]db.WriteOp{
+		db.InsertOp(&Item{Name: "Grape", Status: 6}),
+		{Action: "UNKNOWN"},
+		db.DeleteOneOp(db.Cond{"ID": "1"}),
+	}, true)
+	if !errors.As(err, &bwe) || len(bwe.Errors) != 1 || bwe.Err(1) == nil {
+		t.Fatalf("BulkWrite(ordered) error = %v, want operation 1 failed", err)
+	}
+	if res.InsertedCount != 1 || res.DeletedCount != 0 {
+		t.Errorf("BulkWrite(ordered) = %+v, want 1 inserted and none deleted", res)
+	}
+	if n := count(t, sess, db.Cond{"ID": "1"}); n != 1 {
+		t.Errorf("operation after the failure should not be executed")
+	}
+	if n := count(t, sess, db.Cond{"Name": "Grape"}); n != 1 {
+		t.Errorf("operation before the failure should not be rolled back")
+	}
+}
+
+func testCursor(t *testing.T, sess *db.Connection) {
+	cur, err := sess.Model(MetadataName).Find(db.Cond{"Status !=": 3}).OrderBy("ID").Cursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+
+	// 连续调用HasNext不会跳过记录
+	if !cur.HasNext() || !cur.HasNext() {
+		t.Fatal("HasNext() = false, want true")
+	}
+	var ids []string
+	for cur.HasNext() {
+		var item Item
+		if err := cur.Next(&item); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, item.ID)
+		if len(ids) > len(Items) {
+			t.Fatalf("Cursor() returned more than %d items: %v", len(Items), ids)
+		}
+	}
+	if want := []string{"1", "2", "3", "5"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Cursor() = %v, want %v", ids, want)
+	}
+	if cur.HasNext() {
+		t.Error("HasNext() after the last item = true, want false")
+	}
+	if err := cur.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+}
+
+// Stat 分组查询的结果
+type Stat struct {
+	Status int
+	Total  int
+	Sum    float64
+	Avg    float64
+	Low    float64
+	High   float64
+	Names  int
+}
+
+func testAggregate(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	aggs := []db.Aggregation{
+		db.Count("Total"),
+		db.Sum("Score", "Sum"),
+		db.Avg("Score", "Avg"),
+		db.Min("Score", "Low"),
+		db.Max("Score", "High"),
+		db.CountDistinct("Name", "Names"),
+	}
+	var stats []Stat
+	if err := model.Find().GroupBy("Status").Aggregate(aggs...).OrderBy("Status").All(&stats); err != nil {
+		t.Fatal(err)
+	}
+	want := []Stat{
+		{Status: 1, Total: 2, Sum: 17.5, Avg: 8.75, Low: 8, High: 9.5, Names: 2},
+		{Status: 2, Total: 2, Sum: 16, Avg: 8, Low: 7, High: 9, Names: 2},
+		{Status: 3, Total: 1, Sum: 6.5, Avg: 6.5, Low: 6.5, High: 6.5, Names: 1},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("GroupBy(Status) = %+v, want %+v", stats, want)
+	}
+
+	res := model.Find(db.Cond{"Name !=": "Apple"}).GroupBy("Status").Aggregate(aggs...).Having(db.Cond{"Total >": 1}).OrderBy("-Sum")
+	stats = nil
+	if err := res.All(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Status != 2 || stats[0].Sum != 16 {
+		t.Errorf("Having(Total > 1) = %+v", stats)
+	}
+	if n, err := res.Count(); err != nil || n != 1 {
+		t.Errorf("Count() with Having = %d, %v, want 1", n, err)
+	}
+
+	stats = nil
+	if err := model.Find().GroupBy("Status").OrderBy("Status").Paginate(2).Page(2).All(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Status != 3 {
+		t.Errorf("GroupBy(Status).Paginate(2).Page(2) = %+v", stats)
+	}
+
+	// 未分组时对全部匹配记录聚合
+	var stat Stat
+	if err := model.Find(db.Cond{"Status !=": 3}).Aggregate(db.Count("Total"), db.Max("Score", "High")).One(&stat); err != nil {
+		t.Fatal(err)
+	}
+	if stat.Total != 4 || stat.High != 9.5 {
+		t.Errorf("Aggregate(Count, Max) = %+v", stat)
+	}
+	stats = nil
+	if err := model.Find(db.Cond{"Status": 99}).Aggregate(db.Count("Total")).All(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 0 {
+		t.Errorf("Aggregate() without records = %+v, want empty", stats)
+	}
+	if n, err := model.Find().GroupBy("Status").Count(); err != nil || n != 3 {
+		t.Errorf("GroupBy(Status).Count() = %d, %v, want 3", n, err)
+	}
+	if err := model.Find().GroupBy("Unknown").All(&stats); err == nil {
+		t.Error("GroupBy(Unknown) succeeded, want error")
+	}
+}
+
+func testDistinct(t *testing.T, sess *db.Connection) {
+	model := sess.Model(MetadataName)
+	var statuses []int
+	if err := model.Find().Distinct("Status", &statuses); err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(statuses)
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("Distinct(Status) = %v, want %v", statuses, want)
+	}
+	var remarks []string
+	if err := model.Find(db.Cond{"Status": 1}).Distinct("Remark", &remarks); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(remarks)
+	if want := []string{"red fruit", "small.red"}; !reflect.DeepEqual(remarks, want) {
+		t.Errorf("Distinct(Remark) = %v, want %v", remarks, want)
+	}
+	statuses = nil
+	if err := model.Find(db.Cond{"Status": 99}).Distinct("Status", &statuses); err != nil || len(statuses) != 0 {
+		t.Errorf("Distinct() without records = %v, %v, want empty", statuses, err)
+	}
+}
+
+func testTransaction(t *testing.T, sess *db.Connection) {
+	t.Run("Commit", func(t *testing.T) {
+		tx, err := sess.StartTransaction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Model(MetadataName).InsertOne(&Item{ID: "6", Name: "Fig"}); err != nil {
+			_ = tx.Rollback()
+			t.Fatal(err)
+		}
+		// 事务内可以读取到未提交的写入
+		n, err := tx.Model(MetadataName).Find(db.Cond{"ID": "6"}).Count()
+		if err != nil || n != 1 {
+			_ = tx.Rollback()
+			t.Fatalf("Count() in transaction = %d, %v, want 1", n, err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, sess, db.Cond{"ID": "6"}); n != 1 {
+			t.Errorf("Count() after Commit() = %d, want 1", n)
+		}
+	})
+	t.Run("Rollback", func(t *testing.T) {
+		tx, err := sess.StartTransaction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Model(MetadataName).Find(db.Cond{"ID": "1"}).DeleteOne(); err != nil {
+			_ = tx.Rollback()
+			t.Fatal(err)
+		}
+		if _, err := tx.Model(MetadataName).InsertOne(&Item{ID: "7", Name: "Grape"}); err != nil {
+			_ = tx.Rollback()
+			t.Fatal(err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, sess, db.Cond{"ID $in": []string{"1", "7"}}); n != 1 {
+			t.Errorf("Count() after Rollback() = %d, want 1", n)
+		}
+	})
+	t.Run("WithTransaction", func(t *testing.T) {
+		err := sess.WithTransaction(func(tx db.Tx) error {
+			_, err := tx.Model(MetadataName).Find(db.Cond{"ID": "2"}).UpdateOne(map[string]interface{}{"Name": "Blueberry"})
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, sess, db.Cond{"Name": "Blueberry"}); n != 1 {
+			t.Errorf("Count() after WithTransaction() = %d, want 1", n)
+		}
+
+		errAbort := errors.New("abort")
+		err = sess.WithTransaction(func(tx db.Tx) error {
+			if _, err := tx.Model(MetadataName).Find(db.Cond{"ID": "3"}).DeleteOne(); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Errorf("WithTransaction() = %v, want %v", err, errAbort)
+		}
+		if n := count(t, sess, db.Cond{"ID": "3"}); n != 1 {
+			t.Errorf("Count() after aborted WithTransaction() = %d, want 1", n)
+		}
+	})
+	t.Run("BulkWrite", func(t *testing.T) {
+		// 批量写入在事务中执行时绑定该事务，失败后可整体回滚
+		err := sess.WithTransaction(func(tx db.Tx) error {
+			_, err := tx.Model(MetadataName).BulkWrite([]db.WriteOp{
+				db.InsertOp(&Item{ID: "8", Name: "Kiwi"}),
+				db.DeleteOneOp(db.Cond{"ID": "5"}),
+				{Action: "UNKNOWN"},
+			}, true)
+			return err
+		})
+		var bwe *db.BulkWriteError
+		if !errors.As(err, &bwe) {
+			t.Fatalf("WithTransaction() = %v, want a bulk write error", err)
+		}
+		if n := count(t, sess, db.Cond{"ID $in": []string{"5", "8"}}); n != 1 {
+			t.Errorf("Count() after aborted BulkWrite() = %d, want 1", n)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/iamdanielyin/db"
+	"github.com/iamdanielyin/db/adapter/adaptertest"
+)
+
+func TestAdapter(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) *db.Connection {
+		sess, err := db.Connect(db.DataSource{Name: t.Name(), Adapter: "memory", URI: "memory://" + t.Name()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	})
+}
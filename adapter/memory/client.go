@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"github.com/iamdanielyin/db"
+)
+
+type memoryClient struct {
+	adapter *memoryAdapter
+	source  db.DataSource
+	logger  db.Logger
+	store   *store
+}
+
+func (c *memoryClient) Raw(s string, i ...interface{}) db.RawScript {
+	return &memoryRaw{}
+}
+
+func (c *memoryClient) StartTransaction() (db.Tx, error) {
+	mt := &memoryTx{
+		client:   c,
+		snapshot: c.store.snapshot(),
+	}
+	return mt, nil
+}
+
+func (c *memoryClient) WithTransaction(fn func(db.Tx) error) error {
+	tx, err := c.StartTransaction()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (c *memoryClient) Name() string {
+	return c.source.Name
+}
+
+func (c *memoryClient) Logger() db.Logger {
+	return c.logger
+}
+
+func (c *memoryClient) Source() db.DataSource {
+	return c.source
+}
+
+func (c *memoryClient) Model(metadata db.Metadata) db.Collection {
+	return &memoryCollection{
+		client: c,
+		sess:   metadata.Session(),
+		meta:   metadata,
+		name:   metadata.MustNativeName(),
+	}
+}
+
+func (c *memoryClient) Disconnect(context.Context) error {
+	return nil
+}
+
+// memoryRaw 内存数据库不支持本地化脚本
+type memoryRaw struct{}
+
+func (r *memoryRaw) Query() (db.QueryResult, error) {
+	return nil, db.Errorf(`raw scripts are not supported by the %s adapter`, Adapter)
+}
+
+func (r *memoryRaw) Exec() (db.ExecResult, error) {
+	return nil, db.Errorf(`raw scripts are not supported by the %s adapter`, Adapter)
+}
+
+func (r *memoryRaw) WithContext(context.Context) db.RawScript {
+	return r
+}
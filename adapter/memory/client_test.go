@@ -0,0 +1,228 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/iamdanielyin/db"
+	_ "github.com/iamdanielyin/db/adapter/memory"
+)
+
+type MemUser struct {
+	ID       string
+	Username string
+	Age      int
+	Tags     []string
+}
+
+func connect(t *testing.T) *db.Connection {
+	sess, err := db.Connect(db.DataSource{Name: t.Name(), Adapter: "memory", URI: "memory://" + t.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.RegisterMetadata(&MemUser{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.UnregisterMetadata("MemUser")
+	})
+	return sess
+}
+
+func TestCRUD(t *testing.T) {
+	connect(t)
+	model := db.Model("MemUser")
+
+	res, err := model.InsertOne(&MemUser{Username: "foo", Age: 20, Tags: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StringID() == "" {
+		t.Error("StringID() should not be empty")
+	}
+	if _, err := model.InsertMany([]MemUser{{Username: "bar", Age: 30}, {Username: "baz", Age: 40}}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = model.InsertOne(&MemUser{ID: res.StringID()})
+	var dke *db.DuplicateKeyError
+	if !errors.Is(err, db.ErrDuplicateKey) || !errors.As(err, &dke) {
+		t.Errorf("InsertOne() with duplicate key error = %v, want ErrDuplicateKey", err)
+	}
+
+	var user MemUser
+	if err := model.Find(db.Cond{"ID": res.StringID()}).One(&user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "foo" || len(user.Tags) != 1 {
+		t.Errorf("One() = %+v", user)
+	}
+
+	var users []MemUser
+	if err := model.Find(db.Cond{"Age >": 20}).OrderBy("-Age").Project("Username").All(&users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Username != "baz" || users[0].Age != 0 || users[0].ID == "" {
+		t.Errorf("All() = %+v", users)
+	}
+
+	users = nil
+	if err := model.Find().OrderBy("Age").Paginate(2).Page(2).All(&users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Username != "baz" {
+		t.Errorf("All() with pagination = %+v", users)
+	}
+	if n, _ := model.Find().Paginate(2).TotalPages(); n != 2 {
+		t.Errorf("TotalPages() = %d, want 2", n)
+	}
+
+	if n, err := model.Find(db.Cond{"Age >=": 30}).UpdateOne(map[string]interface{}{"Username": "BAR"}); err != nil || n != 1 {
+		t.Fatalf("UpdateOne() = %d, %v", n, err)
+	}
+	if n, err := model.Find(db.Cond{"Age >=": 30}).UpdateMany(&MemUser{Age: 50}); err != nil || n != 2 {
+		t.Fatalf("UpdateMany() = %d, %v", n, err)
+	}
+	if n, _ := model.Find(db.Cond{"Age": 50, "Username": "BAR"}).Count(); n != 1 {
+		t.Errorf("Count() = %d, want 1", n)
+	}
+	if n, err := model.Find(db.Cond{"Age": 50}).DeleteOne(); err != nil || n != 1 {
+		t.Fatalf("DeleteOne() = %d, %v", n, err)
+	}
+	if n, err := model.Find().DeleteMany(); err != nil || n != 2 {
+		t.Fatalf("DeleteMany() = %d, %v", n, err)
+	}
+}
+
+func TestUpdater(t *testing.T) {
+	connect(t)
+	model := db.Model("MemUser")
+	res, err := model.InsertOne(&MemUser{Username: "foo", Age: 20, Tags: []string{"a", "b", "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cond := db.Cond{"ID": res.StringID()}
+
+	var user MemUser
+	err = model.Find(cond).FindOneAndUpdate(db.Update().Inc("Age", 1).Push("Tags", "c").Pull("Tags", "a"), &user, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Age != 21 || !reflect.DeepEqual(user.Tags, []string{"b", "c"}) {
+		t.Errorf("FindOneAndUpdate(Updater) = %+v", user)
+	}
+
+	// 修改失败时记录保持不变
+	if _, err := model.Find(cond).UpdateOne(db.Update().Set("Age", 30).Inc("Username", 1)); err == nil {
+		t.Error("Inc() on a string field should fail")
+	}
+	if err := model.Find(cond).One(&user); err != nil || user.Age != 21 {
+		t.Errorf("One() = %+v, %v, want Age 21", user, err)
+	}
+
+	// 字段中间件按Updater修改的字段匹配
+	var called int
+	_ = db.RegisterMiddleware("MemUser:beforeUpdate:Age", func(*db.Scope) { called++ })
+	if _, err := model.Find(cond).UpdateOne(db.Update().Set("Username", "bar")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := model.Find(cond).UpdateOne(db.Update().Inc("Age", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if called != 1 {
+		t.Errorf("field middleware called %d times, want 1", called)
+	}
+}
+
+func TestBulkWrite(t *testing.T) {
+	connect(t)
+	model := db.Model("MemUser")
+	if _, err := model.InsertOne(&MemUser{ID: "u1", Username: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	ops := []db.WriteOp{
+		db.InsertOp(&MemUser{ID: "u1", Username: "dup"}),
+		db.InsertOp(&MemUser{ID: "u2", Username: "bar"}),
+	}
+	_, err := model.BulkWrite(ops, true)
+	var bwe *db.BulkWriteError
+	if !errors.As(err, &bwe) || len(bwe.Errors) != 1 || bwe.Errors[0].Index != 0 {
+		t.Fatalf("BulkWrite(ordered) error = %v, want operation 0 failed", err)
+	}
+	if n, _ := model.Find().Count(); n != 1 {
+		t.Errorf("Count() = %d, want 1", n)
+	}
+	res, err := model.BulkWrite(ops, false)
+	if !errors.As(err, &bwe) || bwe.Err(0) == nil || bwe.Err(1) != nil {
+		t.Fatalf("BulkWrite(unordered) error = %v, want operation 0 failed", err)
+	}
+	if r := res.Inserted[1]; r == nil || r.StringID() != "u2" {
+		t.Errorf("Inserted[1] = %v, want u2", r)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	connect(t)
+	model := db.Model("MemUser")
+	if _, err := model.InsertMany([]MemUser{{Username: "a", Age: 2}, {Username: "b", Age: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	cur, err := model.Find().OrderBy("Age").Cursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+	var names []string
+	for cur.HasNext() {
+		var user MemUser
+		if err := cur.Next(&user); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, user.Username)
+	}
+	if len(names) != 2 || names[0] != "b" {
+		t.Errorf("cursor = %v", names)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	sess := connect(t)
+	count := func() int {
+		n, _ := db.Model("MemUser").Find().Count()
+		return n
+	}
+
+	err := sess.WithTransaction(func(tx db.Tx) error {
+		if _, err := tx.Model("MemUser").InsertOne(&MemUser{Username: "rollback"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if err == nil || count() != 0 {
+		t.Fatalf("WithTransaction() error = %v, count = %d", err, count())
+	}
+
+	err = sess.WithTransaction(func(tx db.Tx) error {
+		if _, err := tx.Model("MemUser").InsertOne(&MemUser{Username: "outer"}); err != nil {
+			return err
+		}
+		// 嵌套事务回滚到保存点，不影响外层事务
+		_ = sess.WithTransactionContext(tx.Context(), func(tx db.Tx) error {
+			if _, err := tx.Model("MemUser").InsertOne(&MemUser{Username: "inner"}); err != nil {
+				return err
+			}
+			return errors.New("rollback inner")
+		})
+		return nil
+	})
+	if err != nil || count() != 1 {
+		t.Fatalf("WithTransaction() error = %v, count = %d", err, count())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.Model("MemUser").WithContext(ctx).InsertOne(&MemUser{Username: "canceled"}); err == nil {
+		t.Error("InsertOne() with canceled context should fail")
+	}
+}
@@ -0,0 +1,275 @@
+package memory
+
+import (
+	"github.com/iamdanielyin/db"
+	"github.com/iamdanielyin/structs"
+	"github.com/iancoleman/strcase"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// nativeKey 将字段名转换为存储时使用的原始名称，未注册的字段保持原样
+func nativeKey(meta db.Metadata, key string) string {
+	if f, has := meta.FieldByName(key); has {
+		return f.MustNativeName()
+	}
+	return key
+}
+
+// primaryKey 返回元数据主键的原始名称
+func primaryKey(meta db.Metadata) string {
+	for _, f := range meta.Properties {
+		if ok, _ := strconv.ParseBool(f.Primary); ok {
+			return f.MustNativeName()
+		}
+	}
+	if f, has := meta.FieldByName("ID"); has {
+		return f.MustNativeName()
+	}
+	return "_id"
+}
+
+func encode(meta db.Metadata, i interface{}) (docs []document, err error) {
+	reflectValue := reflect.Indirect(reflect.ValueOf(i))
+	switch reflectValue.Kind() {
+	case reflect.Struct:
+		doc := make(document)
+		for _, field := range structs.New(i).Fields() {
+			if !field.IsExported() || field.IsZero() || isRelationship(meta, field.Name()) {
+				continue
+			}
+			doc[nativeKey(meta, field.Name())] = field.Value()
+		}
+		docs = []document{doc}
+	case reflect.Map:
+		doc := make(document)
+		for _, k := range reflectValue.MapKeys() {
+			key, ok := k.Interface().(string)
+			if !ok {
+				return nil, db.Errorf(`unsupported document key: %v`, k.Interface())
+			}
+			if isRelationship(meta, key) {
+				continue
+			}
+			doc[nativeKey(meta, key)] = reflectValue.MapIndex(k).Interface()
+		}
+		docs = []document{doc}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < reflectValue.Len(); i++ {
+			res, err := encode(meta, reflectValue.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, res...)
+		}
+	default:
+		return nil, db.Errorf(`unsupported document type: %T`, i)
+	}
+	return
+}
+
+func decodeAll(meta db.Metadata, docs []document, dst interface{}) error {
+	reflectValue := reflect.ValueOf(dst)
+	if reflectValue.Kind() != reflect.Ptr || reflectValue.IsNil() {
+		return db.Errorf(`destination must be a non-nil pointer: %T`, dst)
+	}
+	sliceValue := reflect.Indirect(reflectValue)
+	if sliceValue.Kind() != reflect.Slice {
+		return db.Errorf(`destination must be a pointer to slice: %T`, dst)
+	}
+	result := reflect.MakeSlice(sliceValue.Type(), len(docs), len(docs))
+	for i, doc := range docs {
+		if err := decodeDocument(meta, doc, result.Index(i)); err != nil {
+			return err
+		}
+	}
+	sliceValue.Set(result)
+	return nil
+}
+
+// decodeValues 将单个字段的取值列表赋给目标切片
+func decodeValues(values []interface{}, dst interface{}) error {
+	reflectValue := reflect.ValueOf(dst)
+	if reflectValue.Kind() != reflect.Ptr || reflectValue.IsNil() {
+		return db.Errorf(`destination must be a non-nil pointer: %T`, dst)
+	}
+	sliceValue := reflect.Indirect(reflectValue)
+	if sliceValue.Kind() != reflect.Slice {
+		return db.Errorf(`destination must be a pointer to slice: %T`, dst)
+	}
+	result := reflect.MakeSlice(sliceValue.Type(), len(values), len(values))
+	for i, v := range values {
+		if err := assign(result.Index(i), v); err != nil {
+			return err
+		}
+	}
+	sliceValue.Set(result)
+	return nil
+}
+
+func decodeOne(meta db.Metadata, doc document, dst interface{}) error {
+	reflectValue := reflect.ValueOf(dst)
+	if reflectValue.Kind() != reflect.Ptr || reflectValue.IsNil() {
+		return db.Errorf(`destination must be a non-nil pointer: %T`, dst)
+	}
+	return decodeDocument(meta, doc, reflectValue.Elem())
+}
+
+func decodeDocument(meta db.Metadata, doc document, dst reflect.Value) error {
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return decodeDocument(meta, doc, dst.Elem())
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(map[string]interface{}(doc.clone())))
+	case reflect.Map:
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		for k, v := range doc {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := assign(elem, v); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+		}
+	case reflect.Struct:
+		t := dst.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			v, has := lookupStructField(meta, doc, sf)
+			if !has {
+				continue
+			}
+			if err := assign(dst.Field(i), v); err != nil {
+				return db.Errorf(`decode field %s failed: %v`, sf.Name, err)
+			}
+		}
+	default:
+		return db.Errorf(`unsupported destination type: %s`, dst.Type())
+	}
+	return nil
+}
+
+func lookupStructField(meta db.Metadata, doc document, sf reflect.StructField) (interface{}, bool) {
+	var keys []string
+	for _, name := range []string{"bson", "json"} {
+		if tag, ok := sf.Tag.Lookup(name); ok {
+			if tag = strings.Split(tag, ",")[0]; tag == "-" {
+				return nil, false
+			} else if tag != "" {
+				keys = append(keys, tag)
+			}
+		}
+	}
+	keys = append(keys, nativeKey(meta, sf.Name), sf.Name, strcase.ToSnake(sf.Name))
+	for _, key := range keys {
+		if v, has := doc[key]; has {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func assign(dst reflect.Value, v interface{}) error {
+	if v == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	src := reflect.ValueOf(v)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if err := assign(elem.Elem(), v); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case reflect.Interface:
+		if src.Type().Implements(dst.Type()) {
+			dst.Set(src)
+			return nil
+		}
+	}
+	if src.Kind() == reflect.Ptr {
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return assign(dst, src.Elem().Interface())
+	}
+	switch {
+	case isNumberKind(src.Kind()) && isNumberKind(dst.Kind()),
+		src.Kind() == reflect.String && dst.Kind() == reflect.String,
+		src.Kind() == reflect.Bool && dst.Kind() == reflect.Bool:
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+	switch src.Kind() {
+	case reflect.Map:
+		if dst.Kind() == reflect.Struct || dst.Kind() == reflect.Map {
+			doc := make(document)
+			for _, k := range src.MapKeys() {
+				doc[k.String()] = src.MapIndex(k).Interface()
+			}
+			return decodeDocument(db.Metadata{}, doc, dst)
+		}
+	case reflect.Struct:
+		if dst.Kind() == reflect.Map || dst.Kind() == reflect.Struct {
+			docs, err := encode(db.Metadata{}, v)
+			if err != nil {
+				return err
+			}
+			return decodeDocument(db.Metadata{}, docs[0], dst)
+		}
+	case reflect.Slice, reflect.Array:
+		if dst.Kind() == reflect.Slice {
+			result := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+			for i := 0; i < src.Len(); i++ {
+				if err := assign(result.Index(i), src.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			dst.Set(result)
+			return nil
+		}
+	}
+	data, err := db.JSONMarshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := reflect.New(dst.Type())
+	if err := db.JSONAPI().Unmarshal(data, tmp.Interface()); err != nil {
+		return err
+	}
+	dst.Set(tmp.Elem())
+	return nil
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isRelationship 引用字段由上层回调单独写入，不随主档案保存
+func isRelationship(meta db.Metadata, name string) bool {
+	if f, has := meta.FieldByName(name); has {
+		return f.Relationship.Type != ""
+	}
+	return false
+}
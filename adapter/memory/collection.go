@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"context"
+	"github.com/iamdanielyin/db"
+)
+
+type memoryCollection struct {
+	client *memoryClient
+	sess   *db.Connection
+	meta   db.Metadata
+	name   string
+	ctx    context.Context
+}
+
+func (c *memoryCollection) WithContext(ctx context.Context) db.Collection {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// checkContext 操作执行前检查上下文是否已取消或超时
+func checkContext(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return db.Errorf(`%w`, err)
+	}
+	return nil
+}
+
+func (c *memoryCollection) Name() string {
+	return c.meta.Name
+}
+
+func (c *memoryCollection) Metadata() db.Metadata {
+	return c.meta
+}
+
+func (c *memoryCollection) Session() *db.Connection {
+	return c.sess
+}
+
+func (c *memoryCollection) InsertOne(v interface{}, fns ...func(*db.InsertOptions)) (db.InsertOneResult, error) {
+	ids, err := c.insert(v)
+	if err != nil {
+		return nil, err
+	}
+	return &insertOneResult{id: ids[0]}, nil
+}
+
+func (c *memoryCollection) InsertMany(v interface{}, fns ...func(*db.InsertOptions)) (db.InsertManyResult, error) {
+	ids, err := c.insert(v)
+	if err != nil {
+		return nil, err
+	}
+	return &insertManyResult{ids: ids}, nil
+}
+
+func (c *memoryCollection) insert(v interface{}) ([]interface{}, error) {
+	if err := checkContext(c.ctx); err != nil {
+		return nil, err
+	}
+	docs, err := encode(c.meta, v)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, db.Errorf(`no documents to insert`)
+	}
+	store := c.client.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return c.append(docs)
+}
+
+// append 写入记录并返回主键，调用方需持有锁
+func (c *memoryCollection) append(docs []document) ([]interface{}, error) {
+	var (
+		pk    = primaryKey(c.meta)
+		store = c.client.store
+		ids   []interface{}
+	)
+	for _, doc := range docs {
+		if id, has := doc[pk]; !has || id == nil {
+			doc[pk] = newID()
+		}
+		for _, exists := range store.tables[c.name] {
+			if equal(exists[pk], doc[pk]) {
+				return nil, &db.DuplicateKeyError{Err: db.Errorf(`duplicate key %s: %v`, pk, doc[pk])}
+			}
+		}
+		ids = append(ids, doc[pk])
+	}
+	store.tables[c.name] = append(store.tables[c.name], docs...)
+	return ids, nil
+}
+
+// BulkWrite 依次执行各个操作
+func (c *memoryCollection) BulkWrite(ops []db.WriteOp, ordered bool) (db.BulkWriteResult, error) {
+	return db.SequentialBulkWrite(c, ops, ordered)
+}
+
+func (c *memoryCollection) Find(i ...interface{}) db.Result {
+	return &memoryResult{mc: c, conditions: i, ctx: c.ctx}
+}
+
+type insertOneResult struct {
+	id interface{}
+}
+
+func (i *insertOneResult) StringID() string {
+	return toStringID(i.id)
+}
+
+func (i *insertOneResult) IntID() int {
+	return toIntID(i.id)
+}
+
+type upsertResult struct {
+	insertOneResult
+	inserted bool
+}
+
+func (u *upsertResult) Inserted() bool {
+	return u.inserted
+}
+
+type insertManyResult struct {
+	ids []interface{}
+}
+
+func (i *insertManyResult) StringIDs() (v []string) {
+	for _, id := range i.ids {
+		v = append(v, toStringID(id))
+	}
+	return
+}
+
+func (i *insertManyResult) IntIDs() (v []int) {
+	for _, id := range i.ids {
+		v = append(v, toIntID(id))
+	}
+	return
+}
+
+func toStringID(id interface{}) string {
+	if s, ok := id.(string); ok {
+		return s
+	}
+	return ""
+}
+
+func toIntID(id interface{}) int {
+	if n, ok := normalize(id).(float64); ok {
+		return int(n)
+	}
+	return 0
+}
@@ -0,0 +1,316 @@
+package memory
+
+import (
+	"fmt"
+	"github.com/iamdanielyin/db"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type matcher func(document) bool
+
+// QueryMatcher 将查询条件编译为内存匹配函数
+func QueryMatcher(meta db.Metadata, filters ...interface{}) (func(map[string]interface{}) bool, error) {
+	m, err := compileFilters(meta, db.OperatorAnd, filters)
+	if err != nil {
+		return nil, err
+	}
+	return func(doc map[string]interface{}) bool {
+		return m(doc)
+	}, nil
+}
+
+func compileFilters(meta db.Metadata, operator string, filters []interface{}) (matcher, error) {
+	var matchers []matcher
+	for _, filter := range filters {
+		var (
+			m   matcher
+			err error
+		)
+		switch v := filter.(type) {
+		case db.Cond:
+			m, err = compileCond(meta, v)
+		case *db.Cond:
+			m, err = compileCond(meta, *v)
+		case db.Union:
+			m, err = compileUnion(meta, &v)
+		case *db.Union:
+			m, err = compileUnion(meta, v)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return combine(operator, matchers), nil
+}
+
+func compileUnion(meta db.Metadata, u *db.Union) (matcher, error) {
+	var filters []interface{}
+	for _, item := range u.Conditions() {
+		filters = append(filters, item)
+	}
+	return compileFilters(meta, u.Operator(), filters)
+}
+
+func combine(operator string, matchers []matcher) matcher {
+	if operator == db.OperatorOr {
+		return func(doc document) bool {
+			for _, m := range matchers {
+				if m(doc) {
+					return true
+				}
+			}
+			return len(matchers) == 0
+		}
+	}
+	return func(doc document) bool {
+		for _, m := range matchers {
+			if !m(doc) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func compileCond(meta db.Metadata, c db.Cond) (matcher, error) {
+	var matchers []matcher
+	for _, item := range c.Entries() {
+		m, err := compileEntry(meta, item)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return combine(db.OperatorAnd, matchers), nil
+}
+
+func compileEntry(meta db.Metadata, item db.ConditionEntry) (matcher, error) {
+	key := nativeKey(meta, item.Key)
+	value := item.Value
+
+	switch item.Operator {
+	case db.OperatorEq:
+		return func(doc document) bool {
+			return matchValue(doc[key], func(v interface{}) bool { return equal(v, value) })
+		}, nil
+	case db.OperatorNotEq:
+		return func(doc document) bool {
+			return !matchValue(doc[key], func(v interface{}) bool { return equal(v, value) })
+		}, nil
+	case db.OperatorPrefix:
+		return compileRegexp(key, "^"+quoteValue(value), "i")
+	case db.OperatorSuffix:
+		return compileRegexp(key, quoteValue(value)+"$", "i")
+	case db.OperatorContains:
+		return compileRegexp(key, quoteValue(value), "i")
+	case db.OperatorRegExp:
+		s := fmt.Sprintf("%v", value)
+		if lastIdx := strings.LastIndex(s, "/"); strings.HasPrefix(s, "/") && lastIdx > 0 {
+			return compileRegexp(key, s[1:lastIdx], s[lastIdx+1:])
+		}
+		return compileRegexp(key, s, "")
+	case db.OperatorGt, db.OperatorGte, db.OperatorLt, db.OperatorLte:
+		op := item.Operator
+		return func(doc document) bool {
+			return matchValue(doc[key], func(v interface{}) bool {
+				n, ok := compare(v, value)
+				if !ok {
+					return false
+				}
+				switch op {
+				case db.OperatorGt:
+					return n > 0
+				case db.OperatorGte:
+					return n >= 0
+				case db.OperatorLt:
+					return n < 0
+				default:
+					return n <= 0
+				}
+			})
+		}, nil
+	case db.OperatorIn, db.OperatorNotIn:
+		values, err := toSlice(value)
+		if err != nil {
+			return nil, err
+		}
+		in := func(doc document) bool {
+			return matchValue(doc[key], func(v interface{}) bool {
+				for _, item := range values {
+					if equal(v, item) {
+						return true
+					}
+				}
+				return false
+			})
+		}
+		if item.Operator == db.OperatorNotIn {
+			return func(doc document) bool { return !in(doc) }, nil
+		}
+		return in, nil
+	case db.OperatorExists:
+		want, _ := value.(bool)
+		return func(doc document) bool {
+			v, has := doc[key]
+			return (has && v != nil) == want
+		}, nil
+	}
+	return nil, db.Errorf(`unsupported operator: %s`, item.Operator)
+}
+
+// quoteValue 字符串运算符按字面值匹配，不区分大小写
+func quoteValue(v interface{}) string {
+	return regexp.QuoteMeta(fmt.Sprintf("%v", v))
+}
+
+func compileRegexp(key, pattern, options string) (matcher, error) {
+	var flags string
+	for _, o := range options {
+		switch o {
+		case 'i', 'm', 's':
+			flags += string(o)
+		}
+	}
+	if flags != "" {
+		pattern = fmt.Sprintf("(?%s)%s", flags, pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, db.Errorf(`invalid regular expression: %v`, err)
+	}
+	return func(doc document) bool {
+		return matchValue(doc[key], func(v interface{}) bool {
+			s, ok := v.(string)
+			return ok && re.MatchString(s)
+		})
+	}, nil
+}
+
+// matchValue 对数组字段按任一元素匹配，与MongoDB的查询语义保持一致
+func matchValue(v interface{}, fn func(interface{}) bool) bool {
+	if fn(v) {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < rv.Len(); i++ {
+			if fn(rv.Index(i).Interface()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toSlice(v interface{}) ([]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, db.Errorf(`expected an array value but got %T`, v)
+	}
+	values := make([]interface{}, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, nil
+}
+
+func normalize(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	return rv.Interface()
+}
+
+func equal(a, b interface{}) bool {
+	if n, ok := compare(a, b); ok {
+		return n == 0
+	}
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// compare 比较两个值的大小，不可比较时第二个返回值为false
+func compare(a, b interface{}) (int, bool) {
+	a, b = normalize(a), normalize(b)
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			return compareOrdered(av < bv, av > bv), true
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), true
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			return compareOrdered(!av && bv, av && !bv), true
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return compareOrdered(av.Before(bv), av.After(bv)), true
+		}
+	}
+	return 0, false
+}
+
+func compareOrdered(less, greater bool) int {
+	if less {
+		return -1
+	}
+	if greater {
+		return 1
+	}
+	return 0
+}
+
+// sortRank 定义不同类型值在排序时的先后顺序
+func sortRank(v interface{}) int {
+	switch normalize(v).(type) {
+	case nil:
+		return 0
+	case float64:
+		return 1
+	case string:
+		return 2
+	case bool:
+		return 4
+	case time.Time:
+		return 5
+	}
+	return 3
+}
+
+func compareForSort(a, b interface{}) int {
+	if n, ok := compare(a, b); ok {
+		return n
+	}
+	ra, rb := sortRank(a), sortRank(b)
+	if ra != rb {
+		return compareOrdered(ra < rb, ra > rb)
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
@@ -0,0 +1,78 @@
+package memory_test
+
+import (
+	"github.com/iamdanielyin/db"
+	"github.com/iamdanielyin/db/adapter/memory"
+	"testing"
+	"time"
+)
+
+func TestQueryMatcher(t *testing.T) {
+	var (
+		now  = time.Now()
+		meta = db.Metadata{Name: "User", Properties: db.Fields{
+			"EmailAddress": {Name: "EmailAddress", Type: db.String},
+		}}
+		doc = map[string]interface{}{
+			"username":      "Foo.Bar",
+			"email_address": "foo@example.com",
+			"status":        int64(1),
+			"score":         95.5,
+			"tags":          []string{"a", "b"},
+			"created_at":    now,
+			"deleted_at":    nil,
+		}
+	)
+	tests := []struct {
+		name string
+		args []interface{}
+		want bool
+	}{
+		{name: "eq", args: []interface{}{db.Cond{"status": 1}}, want: true},
+		{name: "eq mismatch", args: []interface{}{db.Cond{"status": 2}}, want: false},
+		{name: "native name", args: []interface{}{db.Cond{"EmailAddress": "foo@example.com"}}, want: true},
+		{name: "not eq", args: []interface{}{db.Cond{"status !=": 2}}, want: true},
+		{name: "prefix", args: []interface{}{db.Cond{"username *=": "foo."}}, want: true},
+		{name: "prefix literal", args: []interface{}{db.Cond{"username *=": "foo?"}}, want: false},
+		{name: "suffix", args: []interface{}{db.Cond{"username =*": "BAR"}}, want: true},
+		{name: "contains", args: []interface{}{db.Cond{"email_address *": "@example"}}, want: true},
+		{name: "gt", args: []interface{}{db.Cond{"score >": 95}}, want: true},
+		{name: "gte", args: []interface{}{db.Cond{"status >=": 1}}, want: true},
+		{name: "lt", args: []interface{}{db.Cond{"score <": 95}}, want: false},
+		{name: "lte time", args: []interface{}{db.Cond{"created_at <=": now}}, want: true},
+		{name: "regexp", args: []interface{}{db.Cond{"username ~=": "/^foo\\.b/i"}}, want: true},
+		{name: "regexp case", args: []interface{}{db.Cond{"username ~=": "^foo"}}, want: false},
+		{name: "in", args: []interface{}{db.Cond{"status $in": []int{1, 2}}}, want: true},
+		{name: "in array field", args: []interface{}{db.Cond{"tags $in": []string{"b", "c"}}}, want: true},
+		{name: "nin", args: []interface{}{db.Cond{"status $nin": []int{1, 2}}}, want: false},
+		{name: "exists", args: []interface{}{db.Cond{"status $exists": true}}, want: true},
+		{name: "exists nil", args: []interface{}{db.Cond{"deleted_at $exists": false}}, want: true},
+		{name: "exists missing", args: []interface{}{db.Cond{"phone $exists": true}}, want: false},
+		{
+			name: "or",
+			args: []interface{}{db.Or(db.Cond{"status": 2}, db.Cond{"username *=": "foo"})},
+			want: true,
+		},
+		{
+			name: "and",
+			args: []interface{}{db.And(db.Cond{"status": 1}, db.Cond{"score <": 60})},
+			want: false,
+		},
+		{
+			name: "nested",
+			args: []interface{}{db.Cond{"status": 1}, db.Or(db.And(db.Cond{"score >": 90}, db.Cond{"tags": "a"}), db.Cond{"status": 3})},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := memory.QueryMatcher(meta, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := match(doc); got != tt.want {
+				t.Errorf("QueryMatcher() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
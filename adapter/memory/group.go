@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"fmt"
+	"github.com/iamdanielyin/db"
+)
+
+// accumulator 累计单个分组中某个聚合函数的结果
+type accumulator struct {
+	agg      db.Aggregation
+	count    int
+	sum      float64
+	value    interface{}
+	distinct map[string]bool
+}
+
+func (a *accumulator) add(v interface{}) {
+	if a.agg.Func == db.AggregateCount {
+		a.count++
+		return
+	}
+	if v == nil {
+		return
+	}
+	switch a.agg.Func {
+	case db.AggregateCountDistinct:
+		a.distinct[fmt.Sprintf("%T:%v", normalize(v), normalize(v))] = true
+	case db.AggregateSum, db.AggregateAvg:
+		if f, ok := normalize(v).(float64); ok {
+			a.sum += f
+			a.count++
+		}
+	case db.AggregateMin:
+		if a.value == nil || compareForSort(v, a.value) < 0 {
+			a.value = v
+		}
+	case db.AggregateMax:
+		if a.value == nil || compareForSort(v, a.value) > 0 {
+			a.value = v
+		}
+	}
+}
+
+func (a *accumulator) result(typ string) interface{} {
+	switch a.agg.Func {
+	case db.AggregateCount:
+		return a.count
+	case db.AggregateCountDistinct:
+		return len(a.distinct)
+	case db.AggregateSum:
+		if typ == db.Int {
+			return int64(a.sum)
+		}
+		return a.sum
+	case db.AggregateAvg:
+		if a.count == 0 {
+			return nil
+		}
+		return a.sum / float64(a.count)
+	}
+	return a.value
+}
+
+// group 按分组字段聚合记录，分组按首次出现的顺序排列，未指定分组字段时全部记录作为一个分组
+func group(meta, groupMeta db.Metadata, docs []document, groupBys []string, aggs []db.Aggregation) []document {
+	type bucket struct {
+		doc  document
+		accs []*accumulator
+	}
+	var (
+		keys    []string
+		buckets = make(map[string]*bucket)
+	)
+	for _, doc := range docs {
+		values := make([]interface{}, len(groupBys))
+		for i, name := range groupBys {
+			values[i] = normalize(doc[nativeKey(meta, name)])
+		}
+		key := fmt.Sprintf("%#v", values)
+		b, has := buckets[key]
+		if !has {
+			b = &bucket{doc: make(document)}
+			for _, name := range groupBys {
+				if v := doc[nativeKey(meta, name)]; v != nil {
+					b.doc[nativeKey(groupMeta, name)] = v
+				}
+			}
+			for _, agg := range aggs {
+				b.accs = append(b.accs, &accumulator{agg: agg, distinct: make(map[string]bool)})
+			}
+			buckets[key] = b
+			keys = append(keys, key)
+		}
+		for _, acc := range b.accs {
+			var v interface{}
+			if acc.agg.Field != "" {
+				v = doc[nativeKey(meta, acc.agg.Field)]
+			}
+			acc.add(v)
+		}
+	}
+	result := make([]document, 0, len(keys))
+	for _, key := range keys {
+		b := buckets[key]
+		for _, acc := range b.accs {
+			f, _ := groupMeta.FieldByName(acc.agg.Alias)
+			if v := acc.result(f.Type); v != nil {
+				b.doc[f.MustNativeName()] = v
+			}
+		}
+		result = append(result, b.doc)
+	}
+	return result
+}
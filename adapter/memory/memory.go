@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"context"
+	"github.com/iamdanielyin/db"
+)
+
+const Adapter = `memory`
+
+type memoryAdapter struct {
+}
+
+func init() {
+	db.RegisterAdapter(Adapter, &memoryAdapter{})
+}
+
+func (a *memoryAdapter) Name() string {
+	return Adapter
+}
+
+func (a *memoryAdapter) Connect(_ context.Context, source db.DataSource, logger db.Logger) (db.Client, error) {
+	client := &memoryClient{
+		adapter: a,
+		source:  source,
+		logger:  logger,
+		store:   newStore(),
+	}
+	return client, nil
+}
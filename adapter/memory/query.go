@@ -0,0 +1,559 @@
+package memory
+
+import (
+	"context"
+	"github.com/iamdanielyin/db"
+	"math"
+	"sort"
+	"strings"
+)
+
+type memoryResult struct {
+	mc         *memoryCollection
+	conditions []interface{}
+	projection []string
+	orderBys   []string
+	pageNum    uint
+	pageSize   uint
+	after      string
+	before     string
+	unscoped   bool
+	groupBys   []string
+	aggs       []db.Aggregation
+	havings    []interface{}
+	ctx        context.Context
+}
+
+func (r *memoryResult) WithContext(ctx context.Context) db.Result {
+	r.ctx = ctx
+	return r
+}
+
+func (r *memoryResult) And(i ...db.Conditional) db.Result {
+	r.conditions = append(r.conditions, db.And(i...))
+	return r
+}
+
+func (r *memoryResult) Or(i ...db.Conditional) db.Result {
+	r.conditions = append(r.conditions, db.Or(i...))
+	return r
+}
+
+func (r *memoryResult) Project(p ...string) db.Result {
+	r.projection = p
+	return r
+}
+
+func (r *memoryResult) OrderBy(s ...string) db.Result {
+	r.orderBys = append(r.orderBys, s...)
+	return r
+}
+
+func (r *memoryResult) Paginate(u uint) db.Result {
+	r.pageSize = u
+	return r
+}
+
+func (r *memoryResult) Page(u uint) db.Result {
+	r.pageNum = u
+	return r
+}
+
+func (r *memoryResult) After(token string) db.Result {
+	r.after = token
+	return r
+}
+
+func (r *memoryResult) Before(token string) db.Result {
+	r.before = token
+	return r
+}
+
+// Scroll 键集分页，忽略Page设置的页码
+func (r *memoryResult) Scroll(dst interface{}) (db.PageTokens, error) {
+	if len(r.groupBys) > 0 || len(r.aggs) > 0 {
+		return db.PageTokens{}, db.Errorf(`keyset pagination is not supported by grouped queries`)
+	}
+	return db.KeysetScroll(&memoryResult{mc: r.mc, conditions: r.conditions, ctx: r.ctx}, r.mc.meta, db.Keyset{
+		OrderBys:   r.orderBys,
+		Projection: r.projection,
+		Size:       r.pageSize,
+		After:      r.after,
+		Before:     r.before,
+	}, dst)
+}
+
+func (r *memoryResult) Preload(string, ...func(*db.PreloadOptions)) db.Result {
+	return r
+}
+
+func (r *memoryResult) GroupBy(fields ...string) db.Result {
+	r.groupBys = append(r.groupBys, fields...)
+	return r
+}
+
+func (r *memoryResult) Aggregate(aggs ...db.Aggregation) db.Result {
+	r.aggs = append(r.aggs, aggs...)
+	return r
+}
+
+func (r *memoryResult) Having(i ...db.Conditional) db.Result {
+	for _, item := range i {
+		r.havings = append(r.havings, item)
+	}
+	return r
+}
+
+func (r *memoryResult) Unscoped() db.Result {
+	r.unscoped = true
+	return r
+}
+
+func (r *memoryResult) One(dst interface{}, fns ...func(*db.QueryOptions)) error {
+	docs, meta, err := r.find()
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return db.NotFound(fns...)
+	}
+	return decodeOne(meta, docs[0], dst)
+}
+
+func (r *memoryResult) All(dst interface{}) error {
+	docs, meta, err := r.find()
+	if err != nil {
+		return err
+	}
+	return decodeAll(meta, docs, dst)
+}
+
+func (r *memoryResult) Cursor() (db.Cursor, error) {
+	docs, meta, err := r.find()
+	if err != nil {
+		return nil, err
+	}
+	return &memoryCursor{meta: meta, docs: docs}, nil
+}
+
+func (r *memoryResult) Count() (int, error) {
+	docs, _, err := r.rows()
+	if err != nil {
+		return 0, err
+	}
+	return len(docs), nil
+}
+
+func (r *memoryResult) Distinct(field string, dst interface{}) error {
+	docs, err := r.match()
+	if err != nil {
+		return err
+	}
+	var (
+		key    = nativeKey(r.mc.meta, field)
+		values []interface{}
+	)
+	for _, doc := range docs {
+		v := doc[key]
+		if v == nil {
+			continue
+		}
+		var has bool
+		for _, item := range values {
+			if equal(item, v) {
+				has = true
+				break
+			}
+		}
+		if !has {
+			values = append(values, v)
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return compareForSort(values[i], values[j]) < 0
+	})
+	return decodeValues(values, dst)
+}
+
+func (r *memoryResult) TotalRecords() (int, error) {
+	return r.Count()
+}
+
+func (r *memoryResult) TotalPages() (int, error) {
+	if r.pageSize == 0 {
+		return 1, nil
+	}
+	totalRecords, err := r.TotalRecords()
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Ceil(float64(totalRecords) / float64(r.pageSize))), nil
+}
+
+func (r *memoryResult) UpdateOne(i interface{}, fns ...func(*db.UpdateOptions)) (int, error) {
+	return r.update(i, 1)
+}
+
+func (r *memoryResult) UpdateMany(i interface{}, fns ...func(*db.UpdateOptions)) (int, error) {
+	return r.update(i, -1)
+}
+
+func (r *memoryResult) Upsert(i interface{}) (db.UpsertResult, error) {
+	modify, err := newModifier(r.mc.meta, i)
+	if err != nil {
+		return nil, err
+	}
+	m, err := r.matcher()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		meta  = r.mc.meta
+		pk    = primaryKey(meta)
+		store = r.mc.client.store
+	)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if idx := r.first(m); idx >= 0 {
+		updated, err := r.apply(idx, modify)
+		if err != nil {
+			return nil, err
+		}
+		return &upsertResult{insertOneResult: insertOneResult{id: updated[pk]}}, nil
+	}
+	doc := make(document)
+	for k, v := range db.EqualValues(r.conditions...) {
+		doc[nativeKey(meta, k)] = v
+	}
+	if err := modify(doc); err != nil {
+		return nil, err
+	}
+	insertOnly(meta, i, doc)
+	ids, err := r.mc.append([]document{doc})
+	if err != nil {
+		return nil, err
+	}
+	return &upsertResult{insertOneResult: insertOneResult{id: ids[0]}, inserted: true}, nil
+}
+
+func (r *memoryResult) FindOneAndUpdate(i interface{}, dst interface{}, returnNew bool, fns ...func(*db.QueryOptions)) error {
+	modify, err := newModifier(r.mc.meta, i)
+	if err != nil {
+		return err
+	}
+	m, err := r.matcher()
+	if err != nil {
+		return err
+	}
+	store := r.mc.client.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	idx := r.first(m)
+	if idx < 0 {
+		return db.NotFound(fns...)
+	}
+	doc := store.tables[r.mc.name][idx]
+	updated, err := r.apply(idx, modify)
+	if err != nil {
+		return err
+	}
+	if returnNew {
+		doc = updated
+	}
+	return r.decodeProjected(doc, dst)
+}
+
+func (r *memoryResult) FindOneAndDelete(dst interface{}, fns ...func(*db.QueryOptions)) error {
+	m, err := r.matcher()
+	if err != nil {
+		return err
+	}
+	store := r.mc.client.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	idx := r.first(m)
+	if idx < 0 {
+		return db.NotFound(fns...)
+	}
+	table := store.tables[r.mc.name]
+	doc := table[idx]
+	store.tables[r.mc.name] = append(table[:idx:idx], table[idx+1:]...)
+	return r.decodeProjected(doc, dst)
+}
+
+func (r *memoryResult) DeleteOne(fns ...func(*db.DeleteOptions)) (int, error) {
+	return r.delete(1)
+}
+
+func (r *memoryResult) DeleteMany(fns ...func(*db.DeleteOptions)) (int, error) {
+	return r.delete(-1)
+}
+
+func (r *memoryResult) matcher() (func(map[string]interface{}) bool, error) {
+	if err := checkContext(r.ctx); err != nil {
+		return nil, err
+	}
+	return QueryMatcher(r.mc.meta, r.conditions...)
+}
+
+// match 返回所有满足条件的记录副本（未排序、未分页）
+func (r *memoryResult) match() ([]document, error) {
+	m, err := r.matcher()
+	if err != nil {
+		return nil, err
+	}
+	store := r.mc.client.store
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var docs []document
+	for _, doc := range store.tables[r.mc.name] {
+		if m(doc) {
+			docs = append(docs, doc.clone())
+		}
+	}
+	return docs, nil
+}
+
+// rows 返回满足条件的记录（未排序、未分页），分组查询时返回分组结果及其元数据
+func (r *memoryResult) rows() ([]document, db.Metadata, error) {
+	docs, err := r.match()
+	if err != nil {
+		return nil, r.mc.meta, err
+	}
+	if len(r.groupBys) == 0 && len(r.aggs) == 0 {
+		return docs, r.mc.meta, nil
+	}
+	meta, err := db.GroupMetadata(r.mc.meta, r.groupBys, r.aggs)
+	if err != nil {
+		return nil, meta, err
+	}
+	docs = group(r.mc.meta, meta, docs, r.groupBys, r.aggs)
+	if len(r.havings) > 0 {
+		m, err := QueryMatcher(meta, r.havings...)
+		if err != nil {
+			return nil, meta, err
+		}
+		var matched []document
+		for _, doc := range docs {
+			if m(doc) {
+				matched = append(matched, doc)
+			}
+		}
+		docs = matched
+	}
+	return docs, meta, nil
+}
+
+func (r *memoryResult) find() ([]document, db.Metadata, error) {
+	docs, meta, err := r.rows()
+	if err != nil {
+		return nil, meta, err
+	}
+	r.sort(meta, docs)
+	if r.pageSize > 0 {
+		var skip uint
+		if r.pageNum > 0 {
+			skip = (r.pageNum - 1) * r.pageSize
+		}
+		if skip >= uint(len(docs)) {
+			docs = nil
+		} else {
+			end := skip + r.pageSize
+			if end > uint(len(docs)) {
+				end = uint(len(docs))
+			}
+			docs = docs[skip:end]
+		}
+	}
+	// 分组结果不支持投影
+	if len(r.projection) > 0 && len(r.groupBys) == 0 && len(r.aggs) == 0 {
+		for i, doc := range docs {
+			docs[i] = r.project(doc)
+		}
+	}
+	return docs, meta, nil
+}
+
+func (r *memoryResult) sort(meta db.Metadata, docs []document) {
+	if len(r.orderBys) == 0 {
+		return
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return r.less(meta, docs[i], docs[j])
+	})
+}
+
+func (r *memoryResult) less(meta db.Metadata, a, b document) bool {
+	for _, item := range r.orderBys {
+		var (
+			key  = item
+			desc = false
+		)
+		if strings.HasPrefix(item, "-") {
+			key = item[1:]
+			desc = true
+		}
+		key = nativeKey(meta, key)
+		n := compareForSort(a[key], b[key])
+		if n == 0 {
+			continue
+		}
+		if desc {
+			return n > 0
+		}
+		return n < 0
+	}
+	return false
+}
+
+func (r *memoryResult) project(doc document) document {
+	var (
+		meta     = r.mc.meta
+		includes = make(map[string]bool)
+		excludes = make(map[string]bool)
+	)
+	for _, item := range r.projection {
+		if strings.HasPrefix(item, "-") {
+			excludes[nativeKey(meta, item[1:])] = true
+		} else {
+			includes[nativeKey(meta, item)] = true
+		}
+	}
+	result := make(document)
+	if len(includes) > 0 {
+		includes[primaryKey(meta)] = true
+		for k := range includes {
+			if v, has := doc[k]; has && !excludes[k] {
+				result[k] = v
+			}
+		}
+		return result
+	}
+	for k, v := range doc {
+		if !excludes[k] {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// first 返回按排序规则第一条满足条件的记录在表中的位置，未匹配时返回-1，调用方需持有锁
+func (r *memoryResult) first(m func(map[string]interface{}) bool) int {
+	var (
+		table = r.mc.client.store.tables[r.mc.name]
+		first = -1
+	)
+	for idx, doc := range table {
+		if m(doc) && (first < 0 || r.less(r.mc.meta, doc, table[first])) {
+			first = idx
+		}
+	}
+	return first
+}
+
+// apply 修改指定位置的记录并返回修改后的记录，调用方需持有锁
+func (r *memoryResult) apply(idx int, modify modifier) (document, error) {
+	table := r.mc.client.store.tables[r.mc.name]
+	updated := table[idx].clone()
+	if err := modify(updated); err != nil {
+		return nil, err
+	}
+	table[idx] = updated
+	return updated, nil
+}
+
+func (r *memoryResult) decodeProjected(doc document, dst interface{}) error {
+	if len(r.projection) > 0 {
+		doc = r.project(doc)
+	}
+	return decodeOne(r.mc.meta, doc.clone(), dst)
+}
+
+func (r *memoryResult) update(i interface{}, limit int) (int, error) {
+	modify, err := newModifier(r.mc.meta, i)
+	if err != nil {
+		return 0, err
+	}
+	m, err := r.matcher()
+	if err != nil {
+		return 0, err
+	}
+	store := r.mc.client.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// 全部记录修改成功后再写入，避免部分修改
+	var (
+		table   = store.tables[r.mc.name]
+		updates = make(map[int]document)
+	)
+	for idx, doc := range table {
+		if limit >= 0 && len(updates) >= limit {
+			break
+		}
+		if !m(doc) {
+			continue
+		}
+		updated := doc.clone()
+		if err := modify(updated); err != nil {
+			return 0, err
+		}
+		updates[idx] = updated
+	}
+	for idx, doc := range updates {
+		table[idx] = doc
+	}
+	return len(updates), nil
+}
+
+func (r *memoryResult) delete(limit int) (int, error) {
+	m, err := r.matcher()
+	if err != nil {
+		return 0, err
+	}
+	store := r.mc.client.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var (
+		n    int
+		kept []document
+	)
+	for _, doc := range store.tables[r.mc.name] {
+		if (limit < 0 || n < limit) && m(doc) {
+			n++
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	store.tables[r.mc.name] = kept
+	return n, nil
+}
+
+type memoryCursor struct {
+	meta db.Metadata
+	docs []document
+	pos  int
+}
+
+func (c *memoryCursor) HasNext() bool {
+	return c.pos < len(c.docs)
+}
+
+func (c *memoryCursor) Next(dst interface{}) error {
+	if c.pos >= len(c.docs) {
+		return db.Errorf(`cursor exhausted`)
+	}
+	doc := c.docs[c.pos]
+	c.pos++
+	return decodeOne(c.meta, doc, dst)
+}
+
+func (c *memoryCursor) Close() error {
+	c.docs = nil
+	return nil
+}
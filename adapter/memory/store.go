@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var idCounter uint64
+
+type document map[string]interface{}
+
+func (d document) clone() document {
+	if d == nil {
+		return nil
+	}
+	doc := make(document, len(d))
+	for k, v := range d {
+		doc[k] = v
+	}
+	return doc
+}
+
+type store struct {
+	mu     sync.RWMutex
+	tables map[string][]document
+}
+
+func newStore() *store {
+	return &store{tables: make(map[string][]document)}
+}
+
+func (s *store) snapshot() map[string][]document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tables := make(map[string][]document, len(s.tables))
+	for name, docs := range s.tables {
+		copied := make([]document, len(docs))
+		for i, doc := range docs {
+			copied[i] = doc.clone()
+		}
+		tables[name] = copied
+	}
+	return tables
+}
+
+func (s *store) restore(tables map[string][]document) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tables = tables
+}
+
+// newID 生成与ObjectID格式一致的24位十六进制字符串
+func newID() string {
+	return fmt.Sprintf("%08x%016x", uint32(time.Now().Unix()), atomic.AddUint64(&idCounter, 1))
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"github.com/iamdanielyin/db"
+	"sync"
+)
+
+type memoryTx struct {
+	mu         sync.Mutex
+	client     *memoryClient
+	snapshot   map[string][]document
+	savepoints map[string]map[string][]document
+	done       bool
+}
+
+func (mt *memoryTx) Model(name string) db.Collection {
+	meta, err := db.LookupMetadata(name)
+	if err != nil {
+		panic(err)
+	}
+	return mt.client.Model(meta)
+}
+
+func (mt *memoryTx) Context() context.Context {
+	return context.Background()
+}
+
+func (mt *memoryTx) Commit() error {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	if mt.done {
+		return db.Errorf(`transaction has already been committed or rolled back`)
+	}
+	mt.done = true
+	mt.snapshot = nil
+	return nil
+}
+
+func (mt *memoryTx) Rollback() error {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	if mt.done {
+		return db.Errorf(`transaction has already been committed or rolled back`)
+	}
+	mt.done = true
+	mt.client.store.restore(mt.snapshot)
+	mt.snapshot = nil
+	return nil
+}
+
+func (mt *memoryTx) Savepoint(name string) error {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	if mt.savepoints == nil {
+		mt.savepoints = make(map[string]map[string][]document)
+	}
+	mt.savepoints[name] = mt.client.store.snapshot()
+	return nil
+}
+
+func (mt *memoryTx) RollbackToSavepoint(name string) error {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	snapshot, has := mt.savepoints[name]
+	if !has {
+		return db.Errorf(`savepoint "%s" does not exist`, name)
+	}
+	mt.client.store.restore(snapshot)
+	delete(mt.savepoints, name)
+	return nil
+}
+
+func (mt *memoryTx) ReleaseSavepoint(name string) error {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	delete(mt.savepoints, name)
+	return nil
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"github.com/iamdanielyin/db"
+	"reflect"
+)
+
+// modifier 修改记录副本，返回错误时不会写入
+type modifier func(document) error
+
+func newModifier(meta db.Metadata, i interface{}) (modifier, error) {
+	if u, ok := i.(*db.Updater); ok {
+		entries := u.NativeEntries(meta)
+		return func(doc document) error {
+			for _, item := range entries {
+				if err := applyEntry(doc, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	}
+	docs, err := encode(meta, i)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) != 1 {
+		return nil, db.Errorf(`unsupported update document: %T`, i)
+	}
+	return func(doc document) error {
+		for k, v := range docs[0] {
+			doc[k] = v
+		}
+		return nil
+	}, nil
+}
+
+func applyEntry(doc document, item db.UpdateEntry) error {
+	switch item.Operator {
+	case db.UpdateOperatorSet:
+		doc[item.Key] = item.Value
+	case db.UpdateOperatorUnset:
+		delete(doc, item.Key)
+	case db.UpdateOperatorSetOnInsert:
+		// 仅在Upsert新增记录时写入，见insertOnly
+	case db.UpdateOperatorInc:
+		v, err := increment(doc[item.Key], item.Value)
+		if err != nil {
+			return db.Errorf(`cannot increment field "%s": %v`, item.Key, err)
+		}
+		doc[item.Key] = v
+	case db.UpdateOperatorPush, db.UpdateOperatorPull:
+		var values []interface{}
+		if v := doc[item.Key]; v != nil {
+			var err error
+			if values, err = toSlice(v); err != nil {
+				return db.Errorf(`field "%s" is not an array`, item.Key)
+			}
+		}
+		if item.Operator == db.UpdateOperatorPush {
+			doc[item.Key] = append(values, item.Value)
+			break
+		}
+		kept := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			if !equal(v, item.Value) {
+				kept = append(kept, v)
+			}
+		}
+		doc[item.Key] = kept
+	default:
+		return db.Errorf(`unsupported update operator: %s`, item.Operator)
+	}
+	return nil
+}
+
+// increment 整数相加时结果为int64，否则为float64，字段不存在时视为0
+func increment(v, delta interface{}) (interface{}, error) {
+	if !isNumberKind(reflect.ValueOf(delta).Kind()) {
+		return nil, db.Errorf(`expected a number but got %T`, delta)
+	}
+	if v == nil {
+		return delta, nil
+	}
+	if !isNumberKind(reflect.ValueOf(v).Kind()) {
+		return nil, db.Errorf(`expected a number but got %T`, v)
+	}
+	a, b := reflect.ValueOf(v), reflect.ValueOf(delta)
+	if isIntKind(a.Kind()) && isIntKind(b.Kind()) {
+		return toInt64(a) + toInt64(b), nil
+	}
+	return normalize(v).(float64) + normalize(delta).(float64), nil
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return isNumberKind(k) && k != reflect.Float32 && k != reflect.Float64
+}
+
+func toInt64(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+// insertOnly 写入Upsert新增记录时才设置的字段
+func insertOnly(meta db.Metadata, i interface{}, doc document) {
+	if u, ok := i.(*db.Updater); ok {
+		for _, item := range u.NativeEntries(meta) {
+			if item.Operator == db.UpdateOperatorSetOnInsert {
+				doc[item.Key] = item.Value
+			}
+		}
+	}
+}
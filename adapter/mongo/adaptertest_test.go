@@ -0,0 +1,96 @@
+package mongo_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/iamdanielyin/db"
+	"github.com/iamdanielyin/db/adapter/adaptertest"
+	_ "github.com/iamdanielyin/db/adapter/mongo"
+)
+
+func TestAdapter(t *testing.T) {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI is not set")
+	}
+	// 事务需要副本集或分片集群
+	skipTx := os.Getenv("MONGO_REPLICA_SET") == ""
+	adaptertest.Run(t, func(t *testing.T) *db.Connection {
+		sess, err := db.Connect(db.DataSource{Name: t.Name(), Adapter: "mongo", URI: uri})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_ = db.Disconnect(t.Name())
+		})
+		return sess
+	}, adaptertest.WithOptionSkipTransaction(skipTx))
+}
+
+type scrollEvent struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty" db:"native=_id"`
+	Kind int
+}
+
+// TestScrollObjectID 使用默认的ObjectID主键进行键集分页
+func TestScrollObjectID(t *testing.T) {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI is not set")
+	}
+	sess, err := db.Connect(db.DataSource{Name: t.Name(), Adapter: "mongo", URI: uri})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Disconnect(t.Name())
+	})
+	if err := sess.RegisterMetadata(&scrollEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.UnregisterMetadata("scrollEvent")
+	})
+	model := sess.Model("scrollEvent")
+	if _, err := model.Find().Unscoped().DeleteMany(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := model.InsertMany([]scrollEvent{{Kind: 1}, {Kind: 1}, {Kind: 2}, {Kind: 1}, {Kind: 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		seen  = make(map[primitive.ObjectID]bool)
+		kinds []int
+		token string
+	)
+	for page := 1; ; page++ {
+		var events []scrollEvent
+		res := model.Find().OrderBy("Kind").Paginate(2)
+		if token != "" {
+			res.After(token)
+		}
+		tokens, err := res.Scroll(&events)
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		for _, e := range events {
+			if seen[e.ID] {
+				t.Fatalf("page %d returned %s twice", page, e.ID.Hex())
+			}
+			seen[e.ID] = true
+			kinds = append(kinds, e.Kind)
+		}
+		if tokens.Next == "" {
+			break
+		}
+		token = tokens.Next
+	}
+	if want := []int{1, 1, 1, 2, 2}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("Scroll() kinds = %v, want %v", kinds, want)
+	}
+}
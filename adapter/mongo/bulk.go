@@ -0,0 +1,112 @@
+package mongo
+
+import (
+	"github.com/iamdanielyin/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BulkWrite 使用原生批量写入，未指定主键的新增记录会预先生成ObjectID以便返回主键
+func (c *mongoCollection) BulkWrite(ops []db.WriteOp, ordered bool) (db.BulkWriteResult, error) {
+	result := db.BulkWriteResult{Inserted: make(map[int]db.InsertOneResult)}
+	if len(ops) == 0 {
+		return result, nil
+	}
+	models, ids, err := c.writeModels(ops)
+	if err != nil {
+		return result, err
+	}
+	ctx, cancel := c.context(defaultWriteTimeout)
+	defer cancel()
+	res, err := c.coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(ordered))
+	if res != nil {
+		result.InsertedCount = int(res.InsertedCount)
+		result.UpdatedCount = int(res.MatchedCount)
+		result.DeletedCount = int(res.DeletedCount)
+	}
+	failed := make(map[int]bool)
+	if err != nil {
+		var bwe *db.BulkWriteError
+		if bwe, err = bulkWriteError(err); bwe == nil {
+			return result, err
+		}
+		for _, we := range bwe.Errors {
+			failed[we.Index] = true
+		}
+		if ordered {
+			// 有序写入在首个失败处停止，之后的新增操作均未执行
+			for i := range ids {
+				if i > bwe.Errors[0].Index {
+					failed[i] = true
+				}
+			}
+		}
+	}
+	for i, id := range ids {
+		if !failed[i] {
+			result.Inserted[i] = &insertOneResult{result: &mongo.InsertOneResult{InsertedID: id}}
+		}
+	}
+	return result, err
+}
+
+// writeModels 返回各操作对应的写入模型及新增记录的主键，以操作下标为键
+func (c *mongoCollection) writeModels(ops []db.WriteOp) ([]mongo.WriteModel, map[int]interface{}, error) {
+	var (
+		models = make([]mongo.WriteModel, 0, len(ops))
+		ids    = make(map[int]interface{})
+	)
+	for i, op := range ops {
+		switch op.Action {
+		case db.ActionInsertOne:
+			doc := c.beforeInsert(op.Doc)[0]
+			if d, ok := doc.(bson.D); ok {
+				id, has := lookupID(d)
+				if !has {
+					id = primitive.NewObjectID()
+					doc = append(bson.D{{Key: "_id", Value: id}}, d...)
+				}
+				ids[i] = id
+			}
+			models = append(models, mongo.NewInsertOneModel().SetDocument(doc))
+		case db.ActionUpdateOne, db.ActionUpdateMany:
+			r := c.writeResult(op)
+			doc := r.beforeUpdate(op.Doc)
+			if op.Action == db.ActionUpdateOne {
+				models = append(models, mongo.NewUpdateOneModel().SetFilter(r.filter).SetUpdate(doc))
+			} else {
+				models = append(models, mongo.NewUpdateManyModel().SetFilter(r.filter).SetUpdate(doc))
+			}
+		case db.ActionDeleteOne:
+			models = append(models, mongo.NewDeleteOneModel().SetFilter(c.writeResult(op).filter))
+		case db.ActionDeleteMany:
+			models = append(models, mongo.NewDeleteManyModel().SetFilter(c.writeResult(op).filter))
+		default:
+			return nil, nil, db.Errorf(`unsupported write operation: %s`, op.Action)
+		}
+	}
+	return models, ids, nil
+}
+
+// writeResult 返回已转换查询条件的查询结果
+func (c *mongoCollection) writeResult(op db.WriteOp) *mongoResult {
+	var conditions []interface{}
+	for _, item := range op.Conditions {
+		if item != nil && len(item.Conditions()) > 0 {
+			conditions = append(conditions, item)
+		}
+	}
+	r := &mongoResult{mc: c, conditions: conditions, ctx: c.ctx}
+	return r.beforeQuery()
+}
+
+func lookupID(doc bson.D) (interface{}, bool) {
+	for _, e := range doc {
+		if e.Key == "_id" {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,55 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/iamdanielyin/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestWriteModels(t *testing.T) {
+	c := &mongoCollection{meta: db.Metadata{Name: "User"}}
+	models, ids, err := c.writeModels([]db.WriteOp{
+		db.InsertOp(map[string]interface{}{"_id": "u1", "Name": "foo"}),
+		db.InsertOp(map[string]interface{}{"Name": "bar"}),
+		db.UpdateOneOp(db.Update().Inc("Age", 1), db.Cond{"Name": "foo"}),
+		db.DeleteManyOp(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 4 || len(ids) != 2 || ids[0] != "u1" {
+		t.Fatalf("writeModels() = %v, %v", models, ids)
+	}
+	// 未指定主键时预先生成，保证返回的主键与写入的一致
+	doc := models[1].(*mongo.InsertOneModel).Document.(bson.D)
+	if id, has := lookupID(doc); !has || id != ids[1] {
+		t.Errorf("generated id = %v, want %v", id, ids[1])
+	}
+	if _, _, err := c.writeModels([]db.WriteOp{{Action: db.ActionUpsert}}); err == nil {
+		t.Error("writeModels() should reject unsupported operations")
+	}
+}
+
+func TestBulkWriteError(t *testing.T) {
+	err := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+		{WriteError: mongo.WriteError{Index: 3, Code: 11000, Message: "duplicate key"}},
+		{WriteError: mongo.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}},
+	}}
+	bwe, got := bulkWriteError(err)
+	if bwe == nil || len(bwe.Errors) != 2 || bwe.Errors[0].Index != 1 || bwe.Err(3) == nil {
+		t.Fatalf("bulkWriteError() = %v", got)
+	}
+	if !errors.Is(bwe.Err(1), db.ErrDuplicateKey) {
+		t.Errorf("Err(1) = %v, want ErrDuplicateKey", bwe.Err(1))
+	}
+	var target *db.BulkWriteError
+	if !errors.As(got, &target) {
+		t.Errorf("bulkWriteError() = %T, want *db.BulkWriteError", got)
+	}
+	if bwe, _ := bulkWriteError(mongo.BulkWriteException{WriteConcernError: &mongo.WriteConcernError{Message: "timeout"}}); bwe != nil {
+		t.Errorf("write concern error should not be mapped to operations: %v", bwe)
+	}
+}
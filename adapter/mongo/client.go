@@ -19,23 +19,22 @@ type mongoClient struct {
 	logger  db.Logger
 }
 
-func (c *mongoClient) Raw(s string, i ...interface{}) error {
-	//TODO implement me
-	panic("implement me")
+func (c *mongoClient) Raw(s string, i ...interface{}) db.RawScript {
+	return &mongoRaw{client: c, raw: s, values: i}
 }
 
 func (c *mongoClient) StartTransaction() (db.Tx, error) {
 	opts := options.Session().SetDefaultReadConcern(readconcern.Majority())
 	sess, err := c.client.StartSession(opts)
 	if err != nil {
-		return nil, db.Errorf(`%v`, err)
+		return nil, wrapError(err)
 	}
 	txnOpts := options.Transaction().SetReadPreference(readpref.PrimaryPreferred())
 	if err := sess.StartTransaction(txnOpts); err != nil {
-		return nil, db.Errorf(`%v`, err)
+		return nil, wrapError(err)
 	}
 	mt := &mongoTx{
-		ctx:       context.Background(),
+		ctx:       mongo.NewSessionContext(context.Background(), sess),
 		client:    c,
 		mongoSess: sess,
 	}
@@ -46,7 +45,7 @@ func (c *mongoClient) WithTransaction(fn func(db.Tx) error) error {
 	opts := options.Session().SetDefaultReadConcern(readconcern.Majority())
 	sess, err := c.client.StartSession(opts)
 	if err != nil {
-		return db.Errorf(`%v`, err)
+		return wrapError(err)
 	}
 	defer sess.EndSession(context.Background())
 	txnOpts := options.Transaction().SetReadPreference(readpref.PrimaryPreferred())
@@ -59,7 +58,23 @@ func (c *mongoClient) WithTransaction(fn func(db.Tx) error) error {
 		err := fn(mt)
 		return nil, err
 	}, txnOpts)
-	return err
+	return labelError(err)
+}
+
+// timeout 返回数据源设置的默认超时，未设置时返回d
+func (c *mongoClient) timeout(d time.Duration) time.Duration {
+	if c.source.Timeout > 0 {
+		return c.source.Timeout
+	}
+	return d
+}
+
+// withTimeout 上下文未设置截止时间时附加超时，已设置时保持不变
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
 }
 
 func (c *mongoClient) Name() string {
@@ -8,6 +8,13 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"reflect"
+	"time"
+)
+
+const (
+	// 调用方的上下文未设置截止时间且数据源未设置Timeout时，查询及写入操作的默认超时
+	defaultQueryTimeout = 1 * time.Minute
+	defaultWriteTimeout = 30 * time.Minute
 )
 
 type mongoCollection struct {
@@ -16,6 +23,9 @@ type mongoCollection struct {
 	meta   db.Metadata
 	db     *mongo.Database
 	coll   *mongo.Collection
+	ctx    context.Context
+	// 事务中的集合会将所有操作绑定到该会话
+	mongoSess mongo.Session
 }
 
 func (c *mongoCollection) Name() string {
@@ -30,6 +40,27 @@ func (c *mongoCollection) Session() *db.Connection {
 	return c.sess
 }
 
+func (c *mongoCollection) WithContext(ctx context.Context) db.Collection {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// context 返回当前绑定的上下文，未设置截止时间时附加默认超时，使用后需调用cancel
+func (c *mongoCollection) context(d time.Duration) (context.Context, context.CancelFunc) {
+	return withTimeout(c.sessionContext(c.ctx), c.client.timeout(d))
+}
+
+func (c *mongoCollection) sessionContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if c.mongoSess != nil {
+		ctx = mongo.NewSessionContext(ctx, c.mongoSess)
+	}
+	return ctx
+}
+
 func (c *mongoCollection) Raw(raw string, values ...interface{}) error {
 	// TODO 本地脚本实现
 	return nil
@@ -37,9 +68,11 @@ func (c *mongoCollection) Raw(raw string, values ...interface{}) error {
 
 func (c *mongoCollection) InsertOne(v interface{}, fns ...func(*db.InsertOptions)) (db.InsertOneResult, error) {
 	docs := c.beforeInsert(v)
-	res, err := c.coll.InsertOne(context.Background(), docs[0])
+	ctx, cancel := c.context(defaultWriteTimeout)
+	defer cancel()
+	res, err := c.coll.InsertOne(ctx, docs[0])
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	result := &insertOneResult{result: res}
 	return result, nil
@@ -47,9 +80,11 @@ func (c *mongoCollection) InsertOne(v interface{}, fns ...func(*db.InsertOptions
 
 func (c *mongoCollection) InsertMany(v interface{}, fns ...func(*db.InsertOptions)) (db.InsertManyResult, error) {
 	docs := c.beforeInsert(v)
-	res, err := c.coll.InsertMany(context.Background(), docs)
+	ctx, cancel := c.context(defaultWriteTimeout)
+	defer cancel()
+	res, err := c.coll.InsertMany(ctx, docs)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	result := &insertManyResult{result: res}
 	return result, nil
@@ -68,6 +103,9 @@ func (c *mongoCollection) beforeInsert(i interface{}) (docs []interface{}) {
 			}
 			key := field.Name()
 			if f, has := meta.FieldByName(key); has {
+				if f.Relationship.Type != "" {
+					continue
+				}
 				key = f.MustNativeName()
 			}
 			doc = append(doc, bson.E{Key: key, Value: field.Value()})
@@ -79,10 +117,14 @@ func (c *mongoCollection) beforeInsert(i interface{}) (docs []interface{}) {
 			key := k.Interface().(string)
 			val := reflectValue.MapIndex(k).Interface()
 			if f, has := meta.FieldByName(key); has {
+				if f.Relationship.Type != "" {
+					continue
+				}
 				key = f.MustNativeName()
 			}
 			doc = append(doc, bson.E{Key: key, Value: val})
 		}
+		docs = []interface{}{doc}
 	case reflect.Slice, reflect.Array:
 		for i := 0; i < reflectValue.Len(); i++ {
 			doc := reflectValue.Index(i).Interface()
@@ -96,7 +138,7 @@ func (c *mongoCollection) beforeInsert(i interface{}) (docs []interface{}) {
 }
 
 func (c *mongoCollection) Find(i ...interface{}) db.Result {
-	return &mongoResult{mc: c, conditions: i}
+	return &mongoResult{mc: c, conditions: i, ctx: c.ctx}
 }
 
 type insertOneResult struct {
@@ -115,6 +157,23 @@ func (i *insertOneResult) IntID() (v int) {
 	return
 }
 
+type upsertResult struct {
+	id       interface{}
+	inserted bool
+}
+
+func (u *upsertResult) StringID() string {
+	return objectIdToHex(u.id)
+}
+
+func (u *upsertResult) IntID() int {
+	return toInt(u.id)
+}
+
+func (u *upsertResult) Inserted() bool {
+	return u.inserted
+}
+
 type insertManyResult struct {
 	result *mongo.InsertManyResult
 }
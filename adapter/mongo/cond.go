@@ -5,6 +5,7 @@ import (
 	"github.com/iamdanielyin/db"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"regexp"
 	"strings"
 )
 
@@ -59,11 +60,11 @@ func parseCondition(meta db.Metadata, item *db.ConditionEntry) *bson.E {
 	case db.OperatorNotEq:
 		return &bson.E{Key: key, Value: bson.D{{Key: "$ne", Value: item.Value}}}
 	case db.OperatorPrefix:
-		return &bson.E{Key: key, Value: primitive.Regex{Pattern: fmt.Sprintf("^%v", item.Value), Options: "gim"}}
+		return &bson.E{Key: key, Value: primitive.Regex{Pattern: "^" + quoteValue(item.Value), Options: "i"}}
 	case db.OperatorSuffix:
-		return &bson.E{Key: key, Value: primitive.Regex{Pattern: fmt.Sprintf("%v$", item.Value), Options: "gim"}}
+		return &bson.E{Key: key, Value: primitive.Regex{Pattern: quoteValue(item.Value) + "$", Options: "i"}}
 	case db.OperatorContains:
-		return &bson.E{Key: key, Value: primitive.Regex{Pattern: fmt.Sprintf("%v", item.Value), Options: "gim"}}
+		return &bson.E{Key: key, Value: primitive.Regex{Pattern: quoteValue(item.Value), Options: "i"}}
 	case db.OperatorGt:
 		return &bson.E{Key: key, Value: bson.D{{Key: "$gt", Value: item.Value}}}
 	case db.OperatorGte:
@@ -74,13 +75,13 @@ func parseCondition(meta db.Metadata, item *db.ConditionEntry) *bson.E {
 		return &bson.E{Key: key, Value: bson.D{{Key: "$lte", Value: item.Value}}}
 	case db.OperatorRegExp:
 		var (
-			s       = item.Value.(string)
+			s       = fmt.Sprintf("%v", item.Value)
 			pattern string
 			options string
 		)
 		if lastIdx := strings.LastIndex(s, "/"); strings.HasPrefix(s, "/") && lastIdx > 0 {
-			pattern = s[1 : lastIdx+1]
-			options = s[lastIdx+1 : len(s)-1]
+			pattern = s[1:lastIdx]
+			options = s[lastIdx+1:]
 		} else {
 			pattern = s
 		}
@@ -95,3 +96,8 @@ func parseCondition(meta db.Metadata, item *db.ConditionEntry) *bson.E {
 	}
 	return nil
 }
+
+// quoteValue 字符串运算符按字面值匹配，不区分大小写
+func quoteValue(v interface{}) string {
+	return regexp.QuoteMeta(fmt.Sprintf("%v", v))
+}
@@ -18,17 +18,32 @@ func TestQueryFilter(t *testing.T) {
 		{
 			name: `db.Cond{"EmailAddress *=": "foo"}`,
 			args: db.Cond{"EmailAddress *=": "foo"},
-			want: `[{EmailAddress {"pattern": "^foo", "options": "gim"}}]`,
+			want: `[{EmailAddress {"pattern": "^foo", "options": "i"}}]`,
 		},
 		{
 			name: `db.Cond{"EmailAddress =*": "foo"}`,
 			args: db.Cond{"EmailAddress =*": "foo"},
-			want: `[{EmailAddress {"pattern": "foo$", "options": "gim"}}]`,
+			want: `[{EmailAddress {"pattern": "foo$", "options": "i"}}]`,
 		},
 		{
 			name: `db.Cond{"EmailAddress *": "foo"}`,
 			args: db.Cond{"EmailAddress *": "foo"},
-			want: `[{EmailAddress {"pattern": "foo", "options": "gim"}}]`,
+			want: `[{EmailAddress {"pattern": "foo", "options": "i"}}]`,
+		},
+		{
+			name: `db.Cond{"EmailAddress *": "foo.bar"}`,
+			args: db.Cond{"EmailAddress *": "foo.bar"},
+			want: `[{EmailAddress {"pattern": "foo\.bar", "options": "i"}}]`,
+		},
+		{
+			name: `db.Cond{"Username ~=": "^foo"}`,
+			args: db.Cond{"Username ~=": "^foo"},
+			want: `[{Username {"pattern": "^foo", "options": ""}}]`,
+		},
+		{
+			name: `db.Cond{"Username ~=": "/^foo/im"}`,
+			args: db.Cond{"Username ~=": "/^foo/im"},
+			want: `[{Username {"pattern": "^foo", "options": "im"}}]`,
 		},
 		// number
 		{
@@ -44,38 +59,38 @@ func TestQueryFilter(t *testing.T) {
 		{
 			name: `db.Cond{"Status !=": 1}`,
 			args: db.Cond{"Status !=": 1},
-			want: `[{Status {$ne 1}}]`,
+			want: `[{Status [{$ne 1}]}]`,
 		},
 		{
 			name: `db.Cond{"Status >": 0}`,
 			args: db.Cond{"Status >": 0},
-			want: `[{Status {$gt 0}}]`,
+			want: `[{Status [{$gt 0}]}]`,
 		},
 		{
 			name: `db.Cond{"Status >=": 1}`,
 			args: db.Cond{"Status >=": 1},
-			want: `[{Status {$gte 1}}]`,
+			want: `[{Status [{$gte 1}]}]`,
 		},
 		{
 			name: `db.Cond{"Status <": 0}`,
 			args: db.Cond{"Status <": 0},
-			want: `[{Status {$lt 0}}]`,
+			want: `[{Status [{$lt 0}]}]`,
 		},
 		{
 			name: `db.Cond{"Status <=": 0}`,
 			args: db.Cond{"Status <=": 0},
-			want: `[{Status {$lte 0}}]`,
+			want: `[{Status [{$lte 0}]}]`,
 		},
 		// range
 		{
 			name: `db.Cond{"Status $in": []int{1, -1, -2}}`,
 			args: db.Cond{"Status $in": []int{1, -1, -2}},
-			want: `[{Status {$in [1 -1 -2]}}]`,
+			want: `[{Status [{$in [1 -1 -2]}]}]`,
 		},
 		{
 			name: `db.Cond{"Status $nin": []int{-1, -2}}`,
 			args: db.Cond{"Status $nin": []int{-1, -2}},
-			want: `[{Status {$nin [-1 -2]}}]`,
+			want: `[{Status [{$nin [-1 -2]}]}]`,
 		},
 		{
 			name: `db.And(db.Cond{"CreatedAt >=": 1633536000}, db.Cond{"CreatedAt <=": 1633622399})`,
@@ -83,7 +98,7 @@ func TestQueryFilter(t *testing.T) {
 				db.Cond{"CreatedAt >=": 1633536000},
 				db.Cond{"CreatedAt <=": 1633622399},
 			),
-			want: `[{$and [[{CreatedAt {$gte 1633536000}}] [{CreatedAt {$lte 1633622399}}]]}]`,
+			want: `[{$and [[{CreatedAt [{$gte 1633536000}]}] [{CreatedAt [{$lte 1633622399}]}]]}]`,
 		},
 		{
 			name: `db.And(db.Or(db.Cond{"Username": "foo"}, db.Cond{"Username": "bar"}), db.Cond{"Status": 1})`,
@@ -100,12 +115,12 @@ func TestQueryFilter(t *testing.T) {
 		{
 			name: `db.Cond{"PhoneNumber $exists": true}`,
 			args: db.Cond{"PhoneNumber $exists": true},
-			want: `[{PhoneNumber {$exists true}}]`,
+			want: `[{PhoneNumber [{$exists true}]}]`,
 		},
 		{
 			name: `db.Cond{"PhoneNumber $exists": false}`,
 			args: db.Cond{"PhoneNumber $exists": false},
-			want: `[{PhoneNumber {$exists false}}]`,
+			want: `[{PhoneNumber [{$exists false}]}]`,
 		},
 		// logic
 		{
@@ -125,7 +140,7 @@ func TestQueryFilter(t *testing.T) {
 				),
 				db.Cond{"EmailAddress $exists": true},
 			),
-			want: `[{$or [[{$and [[{CountryCode 86}] [{PhoneNumber 13800138000}]]}] [{EmailAddress {$exists true}}]]}]`,
+			want: `[{$or [[{$and [[{CountryCode 86}] [{PhoneNumber 13800138000}]]}] [{EmailAddress [{$exists true}]}]]}]`,
 		},
 	}
 	for _, tt := range tests {
@@ -0,0 +1,67 @@
+package mongo
+
+import (
+	"errors"
+	"github.com/iamdanielyin/db"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sort"
+)
+
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	wrapped := db.Errorf(`%w`, err)
+	if isDuplicateKey(err) {
+		wrapped = &db.DuplicateKeyError{Err: wrapped}
+	}
+	return labelError(wrapped)
+}
+
+// isDuplicateKey 批量写入中单个操作的WriteError未实现ServerError，需单独判断错误码
+func isDuplicateKey(err error) bool {
+	if mongo.IsDuplicateKeyError(err) {
+		return true
+	}
+	var we mongo.WriteError
+	return errors.As(err, &we) && (we.Code == 11000 || we.Code == 11001 || we.Code == 12582)
+}
+
+// labelError 保留服务端返回的错误标签，便于调用方区分可重试的错误
+func labelError(err error) error {
+	var (
+		labels []string
+		te     *db.TransactionError
+		ce     mongo.CommandError
+		we     mongo.WriteException
+		bwe    mongo.BulkWriteException
+	)
+	switch {
+	case err == nil || errors.As(err, &te):
+		return err
+	case errors.As(err, &ce):
+		labels = ce.Labels
+	case errors.As(err, &we):
+		labels = we.Labels
+	case errors.As(err, &bwe):
+		labels = bwe.Labels
+	}
+	if len(labels) == 0 {
+		return err
+	}
+	return &db.TransactionError{Labels: labels, Err: err}
+}
+
+// bulkWriteError 将服务端返回的写入错误转换为各操作的错误，写关注错误等无法对应到具体操作时原样返回
+func bulkWriteError(err error) (*db.BulkWriteError, error) {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || len(bwe.WriteErrors) == 0 {
+		return nil, wrapError(err)
+	}
+	result := &db.BulkWriteError{}
+	for _, we := range bwe.WriteErrors {
+		result.Errors = append(result.Errors, db.WriteError{Index: we.Index, Err: wrapError(we.WriteError)})
+	}
+	sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Index < result.Errors[j].Index })
+	return result, result
+}
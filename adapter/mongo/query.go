@@ -20,8 +20,23 @@ type mongoResult struct {
 	orderBys   []string
 	pageNum    uint
 	pageSize   uint
+	after      string
+	before     string
 	unscoped   bool
+	groupBys   []string
+	aggs       []db.Aggregation
+	havings    []interface{}
 	filter     bson.D
+	ctx        context.Context
+}
+
+func (r *mongoResult) WithContext(ctx context.Context) db.Result {
+	r.ctx = ctx
+	return r
+}
+
+func (r *mongoResult) context(d time.Duration) (context.Context, context.CancelFunc) {
+	return withTimeout(r.mc.sessionContext(r.ctx), r.mc.client.timeout(d))
 }
 
 func (r *mongoResult) And(i ...db.Conditional) db.Result {
@@ -39,50 +54,79 @@ func (r *mongoResult) Project(p ...string) db.Result {
 	return r
 }
 
-func (r *mongoResult) One(dst interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+func (r *mongoResult) One(dst interface{}, fns ...func(*db.QueryOptions)) error {
+	ctx, cancel := r.context(defaultQueryTimeout)
+	defer cancel()
+	if r.grouped() {
+		cur, err := r.aggregate(ctx, 1)
+		if err != nil {
+			return err
+		}
+		defer cur.Close(ctx)
+		if !cur.Next(ctx) {
+			if err := cur.Err(); err != nil {
+				return wrapError(err)
+			}
+			return db.NotFound(fns...)
+		}
+		return wrapError(cur.Decode(dst))
+	}
 	err := r.beforeQuery().mc.coll.FindOne(ctx,
 		r.filter,
 		r.buildFindOneOptions(),
 	).Decode(dst)
-	cancel()
 
-	if err != nil && err != mongo.ErrNoDocuments {
-		return db.Errorf(`%v`, err)
+	if err == mongo.ErrNoDocuments {
+		return db.NotFound(fns...)
 	}
-	return nil
+	return wrapError(err)
 }
 
 func (r *mongoResult) All(dst interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	ctx, cancel := r.context(defaultQueryTimeout)
+	defer cancel()
+	if r.grouped() {
+		cur, err := r.aggregate(ctx, 0)
+		if err != nil {
+			return err
+		}
+		return wrapError(cur.All(ctx, dst))
+	}
 	cur, err := r.beforeQuery().mc.coll.Find(ctx,
 		r.filter,
 		r.buildFindOptions(),
 	)
-	cancel()
 	if err != nil && err != mongo.ErrNoDocuments {
-		return db.Errorf(`%v`, err)
+		return wrapError(err)
 	}
-	ctx, cancel = context.WithTimeout(context.Background(), 1*time.Minute)
-	err = cur.All(ctx, dst)
-	cancel()
-	if err != nil {
-		return db.Errorf(`%v`, err)
+	if err = cur.All(ctx, dst); err != nil {
+		return wrapError(err)
 	}
 	return nil
 }
 
+// Cursor 默认超时仅作用于查询及每次读取，不限制游标的整个生命周期
 func (r *mongoResult) Cursor() (db.Cursor, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	ctx, cancel := r.context(defaultQueryTimeout)
+	defer cancel()
+	next := &mongoCursor{ctx: r.mc.sessionContext(r.ctx), timeout: r.mc.client.timeout(defaultQueryTimeout)}
+	if r.grouped() {
+		cur, err := r.aggregate(ctx, 0)
+		if err != nil {
+			return nil, err
+		}
+		next.cur = cur
+		return next, nil
+	}
 	cur, err := r.beforeQuery().mc.coll.Find(ctx,
 		r.filter,
 		r.buildFindOptions(),
 	)
-	cancel()
 	if err != nil && err != mongo.ErrNoDocuments {
-		return nil, db.Errorf(`%v`, err)
+		return nil, wrapError(err)
 	}
-	return &mongoCursor{result: r, cur: cur}, nil
+	next.cur = cur
+	return next, nil
 }
 
 func (r *mongoResult) OrderBy(s ...string) db.Result {
@@ -91,15 +135,59 @@ func (r *mongoResult) OrderBy(s ...string) db.Result {
 }
 
 func (r *mongoResult) Count() (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	ctx, cancel := r.context(defaultWriteTimeout)
 	defer cancel()
+	if r.grouped() {
+		pipeline, err := r.groupPipeline()
+		if err != nil {
+			return 0, err
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$count", Value: "count"}})
+		cur, err := r.mc.coll.Aggregate(ctx, pipeline)
+		if err != nil {
+			return 0, wrapError(err)
+		}
+		var docs []struct {
+			Count int `bson:"count"`
+		}
+		if err := cur.All(ctx, &docs); err != nil {
+			return 0, wrapError(err)
+		}
+		if len(docs) == 0 {
+			return 0, nil
+		}
+		return docs[0].Count, nil
+	}
 	val, err := r.beforeQuery().mc.coll.CountDocuments(ctx, r.filter)
 	if err != nil {
-		return 0, db.Errorf(`%v`, err)
+		return 0, wrapError(err)
 	}
 	return int(val), nil
 }
 
+func (r *mongoResult) Distinct(field string, dst interface{}) error {
+	ctx, cancel := r.context(defaultQueryTimeout)
+	defer cancel()
+	key := r.mc.meta.MustFieldNativeName(field)
+	filter := bson.D{{Key: "$and", Value: bson.A{
+		r.beforeQuery().filter,
+		bson.D{{Key: key, Value: bson.D{{Key: "$ne", Value: nil}}}},
+	}}}
+	values, err := r.mc.coll.Distinct(ctx, key, filter)
+	if err != nil {
+		return wrapError(err)
+	}
+	// 借助bson解码将取值列表转换为目标类型
+	data, err := bson.Marshal(bson.D{{Key: "values", Value: values}})
+	if err != nil {
+		return wrapError(err)
+	}
+	if err := bson.Raw(data).Lookup("values").Unmarshal(dst); err != nil {
+		return wrapError(err)
+	}
+	return nil
+}
+
 func (r *mongoResult) Preload(path string, fns ...func(*db.PreloadOptions)) db.Result {
 	return r
 }
@@ -114,6 +202,30 @@ func (r *mongoResult) Page(u uint) db.Result {
 	return r
 }
 
+func (r *mongoResult) After(token string) db.Result {
+	r.after = token
+	return r
+}
+
+func (r *mongoResult) Before(token string) db.Result {
+	r.before = token
+	return r
+}
+
+// Scroll 键集分页，忽略Page设置的页码
+func (r *mongoResult) Scroll(dst interface{}) (db.PageTokens, error) {
+	if r.grouped() {
+		return db.PageTokens{}, db.Errorf(`keyset pagination is not supported by grouped queries`)
+	}
+	return db.KeysetScroll(&mongoResult{mc: r.mc, conditions: r.conditions, ctx: r.ctx}, r.mc.meta, db.Keyset{
+		OrderBys:   r.orderBys,
+		Projection: r.projection,
+		Size:       r.pageSize,
+		After:      r.after,
+		Before:     r.before,
+	}, dst)
+}
+
 func (r *mongoResult) TotalRecords() (int, error) {
 	return r.Count()
 }
@@ -124,19 +236,36 @@ func (r *mongoResult) TotalPages() (int, error) {
 	}
 	totalRecords, err := r.TotalRecords()
 	if err != nil {
-		return 0, db.Errorf(`%v`, err)
+		return 0, wrapError(err)
 	}
 	totalPages := int(math.Ceil(float64(totalRecords) / float64(r.pageSize)))
 	return totalPages, nil
 }
 
+func (r *mongoResult) GroupBy(fields ...string) db.Result {
+	r.groupBys = append(r.groupBys, fields...)
+	return r
+}
+
+func (r *mongoResult) Aggregate(aggs ...db.Aggregation) db.Result {
+	r.aggs = append(r.aggs, aggs...)
+	return r
+}
+
+func (r *mongoResult) Having(i ...db.Conditional) db.Result {
+	for _, item := range i {
+		r.havings = append(r.havings, item)
+	}
+	return r
+}
+
 func (r *mongoResult) Unscoped() db.Result {
 	r.unscoped = true
 	return r
 }
 
 func (r *mongoResult) UpdateOne(i interface{}, fns ...func(*db.UpdateOptions)) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	ctx, cancel := r.context(defaultWriteTimeout)
 	defer cancel()
 	doc := r.beforeUpdate(i)
 	result, err := r.beforeQuery().mc.coll.UpdateOne(ctx,
@@ -144,13 +273,13 @@ func (r *mongoResult) UpdateOne(i interface{}, fns ...func(*db.UpdateOptions)) (
 		doc,
 	)
 	if err != nil {
-		return 0, db.Errorf(`%v`, err)
+		return 0, wrapError(err)
 	}
 	return int(result.MatchedCount), nil
 }
 
 func (r *mongoResult) UpdateMany(i interface{}, fns ...func(*db.UpdateOptions)) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	ctx, cancel := r.context(defaultWriteTimeout)
 	defer cancel()
 	doc := r.beforeUpdate(i)
 	result, err := r.beforeQuery().mc.coll.UpdateMany(ctx,
@@ -158,29 +287,87 @@ func (r *mongoResult) UpdateMany(i interface{}, fns ...func(*db.UpdateOptions))
 		doc,
 	)
 	if err != nil {
-		return 0, db.Errorf(`%v`, err)
+		return 0, wrapError(err)
 	}
 	return int(result.MatchedCount), nil
 }
 
+// Upsert 新增的记录会包含查询条件中的等值字段
+func (r *mongoResult) Upsert(i interface{}) (db.UpsertResult, error) {
+	ctx, cancel := r.context(defaultWriteTimeout)
+	defer cancel()
+	doc := r.beforeUpdate(i)
+	result, err := r.beforeQuery().mc.coll.UpdateOne(ctx,
+		r.filter,
+		doc,
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return &upsertResult{id: result.UpsertedID, inserted: result.UpsertedCount > 0}, nil
+}
+
+func (r *mongoResult) FindOneAndUpdate(i interface{}, dst interface{}, returnNew bool, fns ...func(*db.QueryOptions)) error {
+	ctx, cancel := r.context(defaultWriteTimeout)
+	defer cancel()
+	var (
+		doc  = r.beforeUpdate(i)
+		opts = options.FindOneAndUpdate()
+	)
+	findOpts := r.buildFindOptions()
+	if findOpts.Sort != nil {
+		opts.SetSort(findOpts.Sort)
+	}
+	if findOpts.Projection != nil {
+		opts.SetProjection(findOpts.Projection)
+	}
+	if returnNew {
+		opts.SetReturnDocument(options.After)
+	}
+	err := r.beforeQuery().mc.coll.FindOneAndUpdate(ctx, r.filter, doc, opts).Decode(dst)
+	if err == mongo.ErrNoDocuments {
+		return db.NotFound(fns...)
+	}
+	return wrapError(err)
+}
+
+func (r *mongoResult) FindOneAndDelete(dst interface{}, fns ...func(*db.QueryOptions)) error {
+	ctx, cancel := r.context(defaultWriteTimeout)
+	defer cancel()
+	opts := options.FindOneAndDelete()
+	findOpts := r.buildFindOptions()
+	if findOpts.Sort != nil {
+		opts.SetSort(findOpts.Sort)
+	}
+	if findOpts.Projection != nil {
+		opts.SetProjection(findOpts.Projection)
+	}
+	err := r.beforeQuery().mc.coll.FindOneAndDelete(ctx, r.filter, opts).Decode(dst)
+	if err == mongo.ErrNoDocuments {
+		return db.NotFound(fns...)
+	}
+	return wrapError(err)
+}
+
 func (r *mongoResult) DeleteOne(fns ...func(*db.DeleteOptions)) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	ctx, cancel := r.context(defaultWriteTimeout)
 	defer cancel()
 	result, err := r.beforeQuery().mc.coll.DeleteOne(ctx, r.filter)
 	if err != nil {
-		return 0, db.Errorf(`%v`, err)
+		return 0, wrapError(err)
 	}
 	return int(result.DeletedCount), nil
 }
 
 func (r *mongoResult) DeleteMany(fns ...func(*db.DeleteOptions)) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	ctx, cancel := r.context(defaultWriteTimeout)
 	defer cancel()
 	result, err := r.beforeQuery().mc.coll.DeleteMany(ctx,
 		r.filter,
 	)
 	if err != nil {
-		return 0, db.Errorf(`%v`, err)
+		return 0, wrapError(err)
 	}
 	return int(result.DeletedCount), nil
 }
@@ -195,8 +382,100 @@ func (r *mongoResult) beforeQuery() *mongoResult {
 	return r
 }
 
+func (r *mongoResult) grouped() bool {
+	return len(r.groupBys) > 0 || len(r.aggs) > 0
+}
+
+// groupPipeline 生成分组聚合管道，分组结果已展开为以原始名称为键的文档
+func (r *mongoResult) groupPipeline() (mongo.Pipeline, error) {
+	meta := r.mc.meta
+	groupMeta, err := db.GroupMetadata(meta, r.groupBys, r.aggs)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		id      bson.D
+		group   bson.D
+		project = bson.D{{Key: "_id", Value: 0}}
+	)
+	for _, name := range r.groupBys {
+		key := meta.MustFieldNativeName(name)
+		id = append(id, bson.E{Key: key, Value: "$" + key})
+		if key == "_id" {
+			project[0].Value = "$_id._id"
+			continue
+		}
+		project = append(project, bson.E{Key: key, Value: "$_id." + key})
+	}
+	if id == nil {
+		group = bson.D{{Key: "_id", Value: nil}}
+	} else {
+		group = bson.D{{Key: "_id", Value: id}}
+	}
+	for _, agg := range r.aggs {
+		var (
+			alias = groupMeta.MustFieldNativeName(agg.Alias)
+			field = "$" + meta.MustFieldNativeName(agg.Field)
+			value interface{}
+		)
+		switch agg.Func {
+		case db.AggregateCount:
+			value = bson.D{{Key: "$sum", Value: 1}}
+		case db.AggregateCountDistinct:
+			value = bson.D{{Key: "$addToSet", Value: field}}
+			field = "$" + alias
+		default:
+			value = bson.D{{Key: "$" + strings.ToLower(agg.Func), Value: field}}
+			field = "$" + alias
+		}
+		group = append(group, bson.E{Key: alias, Value: value})
+		if agg.Func == db.AggregateCountDistinct {
+			project = append(project, bson.E{Key: alias, Value: bson.D{{Key: "$size", Value: field}}})
+		} else {
+			project = append(project, bson.E{Key: alias, Value: 1})
+		}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: r.beforeQuery().filter}},
+		{{Key: "$group", Value: group}},
+		{{Key: "$project", Value: project}},
+	}
+	if having := QueryFilter(groupMeta, r.havings...); len(having) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: having}})
+	}
+	return pipeline, nil
+}
+
+func (r *mongoResult) aggregate(ctx context.Context, limit int64) (*mongo.Cursor, error) {
+	pipeline, err := r.groupPipeline()
+	if err != nil {
+		return nil, err
+	}
+	groupMeta, _ := db.GroupMetadata(r.mc.meta, r.groupBys, r.aggs)
+	if sort := sortDoc(groupMeta, r.orderBys); len(sort) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sort}})
+	}
+	if r.pageSize > 0 && r.pageNum > 1 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: int64((r.pageNum - 1) * r.pageSize)}})
+	}
+	if limit == 0 && r.pageSize > 0 {
+		limit = int64(r.pageSize)
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+	cur, err := r.mc.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return cur, nil
+}
+
 func (r *mongoResult) beforeUpdate(i interface{}) (result interface{}) {
 	meta := r.mc.meta
+	if u, ok := i.(*db.Updater); ok {
+		return updaterDoc(meta, u)
+	}
 	reflectValue := reflect.Indirect(reflect.ValueOf(i))
 	switch reflectValue.Kind() {
 	case reflect.Struct:
@@ -208,6 +487,9 @@ func (r *mongoResult) beforeUpdate(i interface{}) (result interface{}) {
 			}
 			key := field.Name()
 			if f, has := meta.FieldByName(key); has {
+				if f.Relationship.Type != "" {
+					continue
+				}
 				key = f.MustNativeName()
 			}
 			doc = append(doc, bson.E{Key: key, Value: field.Value()})
@@ -219,6 +501,9 @@ func (r *mongoResult) beforeUpdate(i interface{}) (result interface{}) {
 			key := k.Interface().(string)
 			val := reflectValue.MapIndex(k).Interface()
 			if f, has := meta.FieldByName(key); has {
+				if f.Relationship.Type != "" {
+					continue
+				}
 				key = f.MustNativeName()
 			}
 			doc = append(doc, bson.E{Key: key, Value: val})
@@ -230,6 +515,28 @@ func (r *mongoResult) beforeUpdate(i interface{}) (result interface{}) {
 	return
 }
 
+// updaterDoc 将修改内容按操作符分组，Unset的值固定为空字符串
+func updaterDoc(meta db.Metadata, u *db.Updater) bson.D {
+	var (
+		result bson.D
+		index  = make(map[string]int)
+	)
+	for _, item := range u.NativeEntries(meta) {
+		value := item.Value
+		if item.Operator == db.UpdateOperatorUnset {
+			value = ""
+		}
+		idx, has := index[item.Operator]
+		if !has {
+			idx = len(result)
+			index[item.Operator] = idx
+			result = append(result, bson.E{Key: item.Operator, Value: bson.D{}})
+		}
+		result[idx].Value = append(result[idx].Value.(bson.D), bson.E{Key: item.Key, Value: value})
+	}
+	return result
+}
+
 func (r *mongoResult) buildFindOptions() *options.FindOptions {
 	opts := options.Find()
 	if r.pageSize > 0 {
@@ -239,25 +546,8 @@ func (r *mongoResult) buildFindOptions() *options.FindOptions {
 		}
 	}
 	meta := r.mc.meta
-	if len(r.orderBys) > 0 {
-		var sort bson.D
-		for _, item := range r.orderBys {
-			var (
-				key   = item
-				value = 1
-			)
-			if strings.HasPrefix(item, "-") {
-				key = item[1:]
-				value = -1
-			}
-			if f, has := meta.FieldByName(key); has {
-				key = f.MustNativeName()
-			}
-			sort = append(sort, bson.E{Key: key, Value: value})
-		}
-		if len(sort) > 0 {
-			opts.SetSort(sort)
-		}
+	if sort := sortDoc(meta, r.orderBys); len(sort) > 0 {
+		opts.SetSort(sort)
 	}
 	if len(r.projection) > 0 {
 		var projection bson.D
@@ -271,6 +561,9 @@ func (r *mongoResult) buildFindOptions() *options.FindOptions {
 				value = 0
 			}
 			if f, has := meta.FieldByName(key); has {
+				if f.Relationship.Type != "" {
+					continue
+				}
 				key = f.MustNativeName()
 			}
 			projection = append(projection, bson.E{Key: key, Value: value})
@@ -282,6 +575,27 @@ func (r *mongoResult) buildFindOptions() *options.FindOptions {
 	return opts
 }
 
+func sortDoc(meta db.Metadata, orderBys []string) (sort bson.D) {
+	for _, item := range orderBys {
+		var (
+			key   = item
+			value = 1
+		)
+		if strings.HasPrefix(item, "-") {
+			key = item[1:]
+			value = -1
+		}
+		if f, has := meta.FieldByName(key); has {
+			if f.Relationship.Type != "" {
+				continue
+			}
+			key = f.MustNativeName()
+		}
+		sort = append(sort, bson.E{Key: key, Value: value})
+	}
+	return
+}
+
 func (r *mongoResult) buildFindOneOptions() *options.FindOneOptions {
 	findOpts := r.buildFindOptions()
 	return &options.FindOneOptions{
@@ -292,8 +606,9 @@ func (r *mongoResult) buildFindOneOptions() *options.FindOneOptions {
 }
 
 type mongoCursor struct {
-	result          *mongoResult
 	cur             *mongo.Cursor
+	ctx             context.Context
+	timeout         time.Duration // 每次读取及关闭的默认超时
 	unprocessedNext bool
 	lastNextValue   bool
 }
@@ -302,7 +617,7 @@ func (c *mongoCursor) HasNext() bool {
 	if c.unprocessedNext {
 		return c.lastNextValue
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	ctx, cancel := withTimeout(c.ctx, c.timeout)
 	defer cancel()
 	c.unprocessedNext = true
 	c.lastNextValue = c.cur.Next(ctx)
@@ -310,18 +625,18 @@ func (c *mongoCursor) HasNext() bool {
 }
 
 func (c *mongoCursor) Next(dst interface{}) error {
-	c.unprocessedNext = true
+	c.unprocessedNext = false
 	if err := c.cur.Decode(dst); err != nil {
-		return db.Errorf(`%v`, err)
+		return wrapError(err)
 	}
 	return nil
 }
 
 func (c *mongoCursor) Close() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	ctx, cancel := withTimeout(c.ctx, c.timeout)
 	defer cancel()
 	if err := c.cur.Close(ctx); err != nil {
-		return db.Errorf(`%v`, err)
+		return wrapError(err)
 	}
 	return nil
 }
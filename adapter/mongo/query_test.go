@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/iamdanielyin/db"
+)
+
+func TestUpdaterDoc(t *testing.T) {
+	meta := db.Metadata{Name: "User", Properties: db.Fields{
+		"LoginCount": {Name: "LoginCount", Type: db.Int, NativeName: "login_count"},
+	}}
+	u := db.Update().Set("Username", "foo").Inc("LoginCount", 1).Push("Tags", "a").Set("Status", 1).Unset("Remark")
+	want := `[{$set [{username foo} {status 1}]} {$inc [{login_count 1}]} {$push [{tags a}]} {$unset [{remark }]}]`
+	if got := fmt.Sprintf("%v", updaterDoc(meta, u)); got != want {
+		t.Errorf("updaterDoc() = %v, want %v", got, want)
+	}
+}
+
+func TestContextTimeout(t *testing.T) {
+	client := &mongoClient{}
+	r := &mongoResult{mc: &mongoCollection{client: client}}
+	deadline := func(d time.Duration) time.Duration {
+		t.Helper()
+		ctx, cancel := r.context(d)
+		defer cancel()
+		dl, ok := ctx.Deadline()
+		if !ok {
+			t.Fatal("context() should set a default deadline")
+		}
+		return time.Until(dl)
+	}
+	if left := deadline(defaultQueryTimeout); left <= 0 || left > defaultQueryTimeout {
+		t.Errorf("default query timeout = %v, want up to %v", left, defaultQueryTimeout)
+	}
+	// 数据源设置的超时优先于默认值
+	client.source.Timeout = 5 * time.Second
+	if left := deadline(defaultWriteTimeout); left <= 0 || left > 5*time.Second {
+		t.Errorf("data source timeout = %v, want up to 5s", left)
+	}
+	// 调用方已设置截止时间时保持不变
+	parent, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	want, _ := parent.Deadline()
+	r.WithContext(parent)
+	ctx, cancelCtx := r.context(defaultQueryTimeout)
+	defer cancelCtx()
+	if got, ok := ctx.Deadline(); !ok || !got.Equal(want) {
+		t.Errorf("context() deadline = %v, want %v", got, want)
+	}
+}
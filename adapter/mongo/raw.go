@@ -0,0 +1,284 @@
+package mongo
+
+import (
+	"context"
+	"github.com/iamdanielyin/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+const (
+	rawActionFind      = "find"
+	rawActionAggregate = "aggregate"
+	rawActionUpdate    = "update"
+	rawActionDelete    = "delete"
+	rawActionCommand   = "command"
+)
+
+// rawScript 脚本格式，collection为元数据名称（未注册时视为集合名称），options随action而定：
+// find、delete为查询条件，aggregate为管道数组，update为包含filter和update的文档，command为命令文档
+type rawScript struct {
+	Collection string        `bson:"collection"`
+	Action     string        `bson:"action"`
+	Options    bson.RawValue `bson:"options"`
+}
+
+type mongoRaw struct {
+	client *mongoClient
+	raw    string
+	values []interface{}
+	ctx    context.Context
+}
+
+func (r *mongoRaw) WithContext(ctx context.Context) db.RawScript {
+	r.ctx = ctx
+	return r
+}
+
+func (r *mongoRaw) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// contextTimeout 返回附加默认超时的上下文，使用后需调用cancel
+func (r *mongoRaw) contextTimeout(d time.Duration) (context.Context, context.CancelFunc) {
+	return withTimeout(r.context(), r.client.timeout(d))
+}
+
+func (r *mongoRaw) parse() (*rawScript, error) {
+	if len(r.values) > 0 {
+		return nil, db.Errorf(`raw scripts of the %s adapter do not accept parameters`, Adapter)
+	}
+	var script rawScript
+	if err := bson.UnmarshalExtJSON([]byte(r.raw), false, &script); err != nil {
+		return nil, db.Errorf(`invalid raw script: %v`, err)
+	}
+	if script.Action != rawActionCommand && script.Collection == "" {
+		return nil, db.Errorf(`missing collection in raw script`)
+	}
+	return &script, nil
+}
+
+func (r *mongoRaw) database() *mongo.Database {
+	return r.client.client.Database(r.client.cs.Database)
+}
+
+func (r *mongoRaw) collection(script *rawScript) *mongo.Collection {
+	name := script.Collection
+	if meta, err := db.LookupMetadata(name); err == nil {
+		name = meta.MustNativeName()
+	}
+	return r.database().Collection(name)
+}
+
+func (r *mongoRaw) Query() (db.QueryResult, error) {
+	script, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	switch script.Action {
+	case rawActionFind, rawActionAggregate, rawActionCommand:
+		return &mongoRawResult{raw: r, script: script}, nil
+	}
+	return nil, db.Errorf(`unsupported query action: %s`, script.Action)
+}
+
+func (r *mongoRaw) Exec() (db.ExecResult, error) {
+	script, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := r.contextTimeout(defaultWriteTimeout)
+	defer cancel()
+	switch script.Action {
+	case rawActionUpdate:
+		var opts struct {
+			Filter bson.D        `bson:"filter"`
+			Update bson.RawValue `bson:"update"`
+		}
+		if err := unmarshalOptions(script, &opts); err != nil {
+			return nil, err
+		}
+		var update interface{}
+		if opts.Update.Type == bsontype.Array {
+			var pipeline bson.A
+			err = opts.Update.Unmarshal(&pipeline)
+			update = pipeline
+		} else {
+			var doc bson.D
+			err = opts.Update.Unmarshal(&doc)
+			update = doc
+		}
+		if err != nil {
+			return nil, db.Errorf(`invalid update in raw script: %v`, err)
+		}
+		res, err := r.collection(script).UpdateMany(ctx, filterOrEmpty(opts.Filter), update)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		return &execResult{ok: true, recordsAffected: int(res.MatchedCount)}, nil
+	case rawActionDelete:
+		var filter bson.D
+		if err := unmarshalOptions(script, &filter); err != nil {
+			return nil, err
+		}
+		res, err := r.collection(script).DeleteMany(ctx, filterOrEmpty(filter))
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		return &execResult{ok: true, recordsAffected: int(res.DeletedCount)}, nil
+	case rawActionCommand:
+		var cmd bson.D
+		if err := unmarshalOptions(script, &cmd); err != nil {
+			return nil, err
+		}
+		var reply bson.M
+		if err := r.database().RunCommand(ctx, cmd).Decode(&reply); err != nil {
+			return nil, wrapError(err)
+		}
+		return &execResult{ok: toInt(reply["ok"]) == 1, recordsAffected: toInt(reply["n"])}, nil
+	}
+	return nil, db.Errorf(`unsupported exec action: %s`, script.Action)
+}
+
+// unmarshalOptions 未指定options时保持零值
+func unmarshalOptions(script *rawScript, v interface{}) error {
+	if script.Options.Type == 0 {
+		return nil
+	}
+	if err := script.Options.Unmarshal(v); err != nil {
+		return db.Errorf(`invalid options in raw script: %v`, err)
+	}
+	return nil
+}
+
+func filterOrEmpty(filter bson.D) bson.D {
+	if filter == nil {
+		return bson.D{}
+	}
+	return filter
+}
+
+type mongoRawResult struct {
+	raw    *mongoRaw
+	script *rawScript
+}
+
+func (r *mongoRawResult) One(dst interface{}, fns ...func(*db.QueryOptions)) error {
+	ctx, cancel := r.raw.contextTimeout(defaultQueryTimeout)
+	defer cancel()
+	switch r.script.Action {
+	case rawActionFind:
+		var filter bson.D
+		if err := unmarshalOptions(r.script, &filter); err != nil {
+			return err
+		}
+		err := r.raw.collection(r.script).FindOne(ctx, filterOrEmpty(filter)).Decode(dst)
+		if err == mongo.ErrNoDocuments {
+			return db.NotFound(fns...)
+		}
+		return wrapError(err)
+	case rawActionCommand:
+		var cmd bson.D
+		if err := unmarshalOptions(r.script, &cmd); err != nil {
+			return err
+		}
+		if err := r.raw.database().RunCommand(ctx, cmd).Decode(dst); err != nil {
+			return wrapError(err)
+		}
+		return nil
+	}
+	cur, err := r.cursor(ctx)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	if cur.Next(ctx) {
+		if err := cur.Decode(dst); err != nil {
+			return wrapError(err)
+		}
+	}
+	return wrapError(cur.Err())
+}
+
+func (r *mongoRawResult) All(dst interface{}) error {
+	ctx, cancel := r.raw.contextTimeout(defaultQueryTimeout)
+	defer cancel()
+	cur, err := r.cursor(ctx)
+	if err != nil {
+		return err
+	}
+	if err := cur.All(ctx, dst); err != nil {
+		return wrapError(err)
+	}
+	return nil
+}
+
+func (r *mongoRawResult) Cursor() (db.Cursor, error) {
+	ctx, cancel := r.raw.contextTimeout(defaultQueryTimeout)
+	defer cancel()
+	cur, err := r.cursor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &mongoCursor{cur: cur, ctx: r.raw.context(), timeout: r.raw.client.timeout(defaultQueryTimeout)}, nil
+}
+
+// cursor 命令需返回游标，如find、aggregate、listCollections等
+func (r *mongoRawResult) cursor(ctx context.Context) (cur *mongo.Cursor, err error) {
+	switch r.script.Action {
+	case rawActionFind:
+		var filter bson.D
+		if err := unmarshalOptions(r.script, &filter); err != nil {
+			return nil, err
+		}
+		cur, err = r.raw.collection(r.script).Find(ctx, filterOrEmpty(filter))
+	case rawActionAggregate:
+		pipeline := bson.A{}
+		if err := unmarshalOptions(r.script, &pipeline); err != nil {
+			return nil, err
+		}
+		cur, err = r.raw.collection(r.script).Aggregate(ctx, pipeline)
+	case rawActionCommand:
+		var cmd bson.D
+		if err := unmarshalOptions(r.script, &cmd); err != nil {
+			return nil, err
+		}
+		cur, err = r.raw.database().RunCommandCursor(ctx, cmd)
+	}
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return cur, nil
+}
+
+type execResult struct {
+	ok              bool
+	recordsAffected int
+}
+
+func (r *execResult) OK() bool {
+	return r.ok
+}
+
+func (r *execResult) RecordsAffected() int {
+	return r.recordsAffected
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"testing"
+)
+
+func TestRawParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		values  []interface{}
+		action  string
+		wantErr bool
+	}{
+		{
+			name:   "aggregate",
+			raw:    `{"collection": "User", "action": "aggregate", "options": [{"$match": {"status": 1}}, {"$group": {"_id": "$status", "n": {"$sum": 1}}}]}`,
+			action: rawActionAggregate,
+		},
+		{
+			name:   "command without collection",
+			raw:    `{"action": "command", "options": {"ping": 1}}`,
+			action: rawActionCommand,
+		},
+		{
+			name:    "missing collection",
+			raw:     `{"action": "find", "options": {}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			raw:     `{"collection": `,
+			wantErr: true,
+		},
+		{
+			name:    "parameters",
+			raw:     `{"collection": "User", "action": "find"}`,
+			values:  []interface{}{1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &mongoRaw{raw: tt.raw, values: tt.values}
+			script, err := r.parse()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && script.Action != tt.action {
+				t.Errorf("parse() action = %s, want %s", script.Action, tt.action)
+			}
+		})
+	}
+
+	r := &mongoRaw{raw: `{"collection": "User", "action": "update"}`}
+	if _, err := r.Query(); err == nil {
+		t.Error("Query() with update action should fail")
+	}
+}
@@ -17,21 +17,33 @@ func (mt *mongoTx) Model(name string) db.Collection {
 	if err != nil {
 		panic(err)
 	}
-	return mt.client.Model(meta)
+	coll := mt.client.Model(meta).(*mongoCollection)
+	coll.ctx = mt.ctx
+	coll.mongoSess = mt.mongoSess
+	return coll
+}
+
+func (mt *mongoTx) Context() context.Context {
+	return mt.ctx
 }
 
 func (mt *mongoTx) Commit() error {
-	defer mt.close()
 	if err := mt.mongoSess.CommitTransaction(mt.ctx); err != nil {
-		return db.Errorf(`%v`, err)
+		err = wrapError(err)
+		// 提交结果未知时保留会话以便重试提交，由Rollback负责释放
+		if !db.HasErrorLabel(err, db.LabelUnknownTransactionCommitResult) {
+			mt.close()
+		}
+		return err
 	}
+	mt.close()
 	return nil
 }
 
 func (mt *mongoTx) Rollback() error {
 	defer mt.close()
 	if err := mt.mongoSess.AbortTransaction(mt.ctx); err != nil {
-		return db.Errorf(`%v`, err)
+		return wrapError(err)
 	}
 	return nil
 }
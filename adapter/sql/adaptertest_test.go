@@ -0,0 +1,35 @@
+package sql_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/iamdanielyin/db"
+	"github.com/iamdanielyin/db/adapter/adaptertest"
+)
+
+func TestSQLiteAdapter(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) *db.Connection {
+		sess, err := db.Connect(db.DataSource{
+			Name:    t.Name(),
+			Adapter: "sqlite",
+			URI:     filepath.Join(t.TempDir(), "test.db"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_ = db.Disconnect(t.Name())
+		})
+		if _, err := sess.Raw(`CREATE TABLE item (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT,
+			status INTEGER,
+			score REAL,
+			remark TEXT
+		)`).Exec(); err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	})
+}
@@ -0,0 +1,112 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/iamdanielyin/db"
+)
+
+// executor *sql.DB与*sql.Tx的公共方法
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+type sqlClient struct {
+	adapter *sqlAdapter
+	source  db.DataSource
+	db      *sql.DB
+	logger  db.Logger
+}
+
+func (c *sqlClient) Raw(s string, values ...interface{}) db.RawScript {
+	return &sqlRaw{client: c, exec: c.db, query: s, args: values}
+}
+
+func (c *sqlClient) StartTransaction() (db.Tx, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, db.Errorf(`%w`, err)
+	}
+	return &sqlTx{client: c, tx: tx, ctx: context.Background()}, nil
+}
+
+func (c *sqlClient) WithTransaction(fn func(db.Tx) error) error {
+	tx, err := c.StartTransaction()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return db.Errorf(`%w; %v`, err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func (c *sqlClient) Name() string {
+	return c.source.Name
+}
+
+func (c *sqlClient) Logger() db.Logger {
+	return c.logger
+}
+
+func (c *sqlClient) Source() db.DataSource {
+	return c.source
+}
+
+func (c *sqlClient) Model(metadata db.Metadata) db.Collection {
+	return &sqlCollection{
+		client: c,
+		sess:   metadata.Session(),
+		meta:   metadata,
+		exec:   c.db,
+	}
+}
+
+func (c *sqlClient) Disconnect(context.Context) error {
+	return c.db.Close()
+}
+
+func (c *sqlClient) dialect() *Dialect {
+	return c.adapter.dialect
+}
+
+func (c *sqlClient) exec(ctx context.Context, e executor, query string, args ...interface{}) (sql.Result, error) {
+	c.logger.INFO("[db] %s %v\n", query, args)
+	res, err := e.ExecContext(ctx, query, args...)
+	if err != nil {
+		c.logger.ERROR("[db] %s: %v\n", query, err)
+		return nil, c.wrapError(err)
+	}
+	return res, nil
+}
+
+func (c *sqlClient) query(ctx context.Context, e executor, query string, args ...interface{}) (*sql.Rows, error) {
+	c.logger.INFO("[db] %s %v\n", query, args)
+	rows, err := e.QueryContext(ctx, query, args...)
+	if err != nil {
+		c.logger.ERROR("[db] %s: %v\n", query, err)
+		return nil, c.wrapError(err)
+	}
+	return rows, nil
+}
+
+// wrapError 保留驱动返回的原始错误，并按方言转换为db包中的错误类型
+func (c *sqlClient) wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	wrapped := db.Errorf(`%w`, err)
+	if classify := c.dialect().ClassifyError; classify != nil {
+		switch classify(err) {
+		case db.ErrDuplicateKey:
+			return &db.DuplicateKeyError{Err: wrapped}
+		case db.ErrTxAborted:
+			return &db.TransactionError{Labels: []string{db.LabelTransientTransactionError}, Err: wrapped}
+		}
+	}
+	return wrapped
+}
@@ -0,0 +1,237 @@
+package sql_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/iamdanielyin/db"
+	_ "github.com/iamdanielyin/db/adapter/sql/sqlite"
+)
+
+type SQLUser struct {
+	ID       int
+	Username string
+	Nickname string
+	Age      int
+	Active   bool
+	Tags     []string
+}
+
+func connectSQLite(t *testing.T) *db.Connection {
+	sess, err := db.Connect(db.DataSource{
+		Name:    t.Name(),
+		Adapter: "sqlite",
+		URI:     filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Disconnect(t.Name())
+		db.UnregisterMetadata("SQLUser")
+	})
+	if _, err := sess.Raw(`CREATE TABLE sql_user (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT,
+		nickname TEXT,
+		age INTEGER,
+		active BOOLEAN,
+		tags TEXT
+	)`).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.RegisterMetadata(&SQLUser{}); err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+func TestSQLiteCRUD(t *testing.T) {
+	connectSQLite(t)
+	model := db.Model("SQLUser")
+
+	res, err := model.InsertOne(&SQLUser{Username: "foo", Age: 20, Active: true, Tags: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IntID() != 1 {
+		t.Errorf("IntID() = %d, want 1", res.IntID())
+	}
+	many, err := model.InsertMany([]SQLUser{{Username: "bar", Age: 30}, {Username: "baz", Age: 40}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := many.IntIDs(); len(ids) != 2 || ids[1] != 3 {
+		t.Errorf("IntIDs() = %v, want [2 3]", ids)
+	}
+	if _, err := model.InsertOne(&SQLUser{ID: 1, Username: "dup"}); !errors.Is(err, db.ErrDuplicateKey) {
+		t.Errorf("InsertOne() with duplicate key error = %v, want ErrDuplicateKey", err)
+	}
+
+	var user SQLUser
+	if err := model.Find(db.Cond{"Username": "foo"}).One(&user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 1 || !user.Active || len(user.Tags) != 2 {
+		t.Errorf("One() = %+v", user)
+	}
+
+	var users []SQLUser
+	if err := model.Find(db.Cond{"Age >": 20}).OrderBy("-Age").All(&users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Username != "baz" {
+		t.Errorf("All() = %+v", users)
+	}
+
+	users = nil
+	if err := model.Find(db.Cond{"Username ~=": "/^BA/i"}).Project("Username").OrderBy("Username").Paginate(1).Page(2).All(&users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Username != "baz" || users[0].Age != 0 {
+		t.Errorf("All() with projection and pagination = %+v", users)
+	}
+	if n, _ := model.Find().Paginate(2).TotalPages(); n != 2 {
+		t.Errorf("TotalPages() = %d, want 2", n)
+	}
+
+	if n, err := model.Find(db.Cond{"Age >=": 30}).UpdateOne(map[string]interface{}{"Nickname": "Bar"}); err != nil || n != 1 {
+		t.Fatalf("UpdateOne() = %d, %v", n, err)
+	}
+	if n, err := model.Find(db.Cond{"Age >=": 30}).UpdateMany(&SQLUser{Active: true}); err != nil || n != 2 {
+		t.Fatalf("UpdateMany() = %d, %v", n, err)
+	}
+	if n, _ := model.Find(db.Cond{"Active": true, "Nickname $exists": true}).Count(); n != 1 {
+		t.Errorf("Count() = %d, want 1", n)
+	}
+
+	if n, err := model.Find(db.Cond{"Username $in": []string{"foo", "bar"}}).DeleteOne(); err != nil || n != 1 {
+		t.Fatalf("DeleteOne() = %d, %v", n, err)
+	}
+	if n, err := model.Find().DeleteMany(); err != nil || n != 2 {
+		t.Fatalf("DeleteMany() = %d, %v", n, err)
+	}
+}
+
+func TestSQLiteCursor(t *testing.T) {
+	connectSQLite(t)
+	model := db.Model("SQLUser")
+	if _, err := model.InsertMany([]SQLUser{{Username: "a"}, {Username: "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	cur, err := model.Find().OrderBy("ID").Cursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+	var names []string
+	for cur.HasNext() && cur.HasNext() {
+		var user SQLUser
+		if err := cur.Next(&user); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, user.Username)
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("cursor = %v", names)
+	}
+}
+
+func TestSQLiteTransaction(t *testing.T) {
+	sess := connectSQLite(t)
+	err := sess.WithTransaction(func(tx db.Tx) error {
+		if _, err := tx.Model("SQLUser").InsertOne(&SQLUser{Username: "outer"}); err != nil {
+			return err
+		}
+		// 嵌套事务回滚到保存点，不影响外层事务
+		_ = sess.WithTransactionContext(tx.Context(), func(tx db.Tx) error {
+			if _, err := tx.Model("SQLUser").InsertOne(&SQLUser{Username: "inner"}); err != nil {
+				return err
+			}
+			return errors.New("rollback inner")
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users []SQLUser
+	if err := db.Model("SQLUser").Find().All(&users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Username != "outer" {
+		t.Errorf("users = %+v", users)
+	}
+
+	_ = sess.WithTransaction(func(tx db.Tx) error {
+		if _, err := tx.Model("SQLUser").InsertOne(&SQLUser{Username: "discarded"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if n, _ := db.Model("SQLUser").Find().Count(); n != 1 {
+		t.Errorf("Count() = %d, want 1", n)
+	}
+}
+
+func TestSQLiteRaw(t *testing.T) {
+	sess := connectSQLite(t)
+
+	res, err := db.Raw(t.Name(), `INSERT INTO sql_user (username, age) VALUES (?, ?), (?, ?)`, "foo", 20, "bar", 30).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.OK() || res.RecordsAffected() != 2 {
+		t.Errorf("Exec() = %v, %d, want true, 2", res.OK(), res.RecordsAffected())
+	}
+
+	q, err := sess.Raw(`SELECT username, age FROM sql_user WHERE age >= ? ORDER BY age DESC`, 20).Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var user SQLUser
+	if err := q.One(&user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "bar" || user.Age != 30 {
+		t.Errorf("One() = %+v", user)
+	}
+	empty, err := sess.Raw(`SELECT username, age FROM sql_user WHERE age > ?`, 100).Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := empty.One(&user); err != nil || user.Username != "bar" {
+		t.Errorf("One() without rows = %+v, %v", user, err)
+	}
+	if err := empty.One(&user, db.WithQueryOptionReturnNotFound(true)); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("One(ReturnNotFound) without rows = %v, want ErrNotFound", err)
+	}
+	var rows []map[string]interface{}
+	if err := q.All(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1]["username"] != "foo" {
+		t.Errorf("All() = %v", rows)
+	}
+	cur, err := q.Cursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+	var names []string
+	for cur.HasNext() {
+		var u SQLUser
+		if err := cur.Next(&u); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, u.Username)
+	}
+	if len(names) != 2 || names[0] != "bar" {
+		t.Errorf("Cursor() = %v", names)
+	}
+
+	if _, err := db.Raw("missing", `SELECT 1`).Query(); err == nil {
+		t.Error("Raw() on a missing session should fail")
+	}
+}
@@ -0,0 +1,365 @@
+package sql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"github.com/iamdanielyin/db"
+	"github.com/iamdanielyin/structs"
+	"github.com/iancoleman/strcase"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// record 以字段原始名称（即列名）为键的一行数据
+type record map[string]interface{}
+
+// columns 返回排序后的列名，保证生成的语句稳定
+func (r record) columns() []string {
+	cols := make([]string, 0, len(r))
+	for k := range r {
+		cols = append(cols, k)
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func nativeKey(meta db.Metadata, key string) string {
+	if f, has := meta.FieldByName(key); has {
+		return f.MustNativeName()
+	}
+	return key
+}
+
+// primaryKey 返回主键的列名
+func primaryKey(meta db.Metadata) string {
+	return nativeKey(meta, meta.PrimaryFieldName())
+}
+
+// isRelationship 引用字段由上层回调单独写入，不对应数据表的列
+func isRelationship(meta db.Metadata, name string) bool {
+	if f, has := meta.FieldByName(name); has {
+		return f.Relationship.Type != ""
+	}
+	return false
+}
+
+// encode 将结构体或map转换为待写入的行，零值字段将被忽略
+func encode(meta db.Metadata, i interface{}) (records []record, err error) {
+	reflectValue := reflect.Indirect(reflect.ValueOf(i))
+	switch reflectValue.Kind() {
+	case reflect.Struct:
+		r := make(record)
+		for _, field := range structs.New(i).Fields() {
+			if !field.IsExported() || field.IsZero() || isRelationship(meta, field.Name()) {
+				continue
+			}
+			if r[nativeKey(meta, field.Name())], err = encodeValue(field.Value()); err != nil {
+				return nil, err
+			}
+		}
+		records = []record{r}
+	case reflect.Map:
+		r := make(record)
+		for _, k := range reflectValue.MapKeys() {
+			key, ok := k.Interface().(string)
+			if !ok {
+				return nil, db.Errorf(`unsupported document key: %v`, k.Interface())
+			}
+			if isRelationship(meta, key) {
+				continue
+			}
+			if r[nativeKey(meta, key)], err = encodeValue(reflectValue.MapIndex(k).Interface()); err != nil {
+				return nil, err
+			}
+		}
+		records = []record{r}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < reflectValue.Len(); i++ {
+			res, err := encode(meta, reflectValue.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, res...)
+		}
+	default:
+		return nil, db.Errorf(`unsupported document type: %T`, i)
+	}
+	return
+}
+
+// insertRecord 将修改内容转换为新增的行，Inc的值作为初始值
+func insertRecord(meta db.Metadata, i interface{}) (record, error) {
+	u, ok := i.(*db.Updater)
+	if !ok {
+		records, err := encode(meta, i)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) != 1 {
+			return nil, db.Errorf(`expected one document, got %d`, len(records))
+		}
+		return records[0], nil
+	}
+	r := make(record)
+	for _, item := range u.NativeEntries(meta) {
+		switch item.Operator {
+		case db.UpdateOperatorSet, db.UpdateOperatorSetOnInsert, db.UpdateOperatorInc:
+			v, err := encodeValue(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			r[item.Key] = v
+		case db.UpdateOperatorUnset:
+			delete(r, item.Key)
+		default:
+			return nil, db.Errorf(`unsupported update operator: %s`, item.Operator)
+		}
+	}
+	return r, nil
+}
+
+// encodeValue 对象和数组类型的值以JSON格式存储
+func encodeValue(v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case nil, driver.Valuer, time.Time, []byte:
+		return v, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(rv.Elem().Interface())
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		data, err := db.JSONMarshal(v)
+		if err != nil {
+			return nil, db.Errorf(`%w`, err)
+		}
+		return string(data), nil
+	}
+	return v, nil
+}
+
+func decodeAll(meta db.Metadata, records []record, dst interface{}) error {
+	reflectValue := reflect.ValueOf(dst)
+	if reflectValue.Kind() != reflect.Ptr || reflectValue.IsNil() {
+		return db.Errorf(`destination must be a non-nil pointer: %T`, dst)
+	}
+	sliceValue := reflect.Indirect(reflectValue)
+	if sliceValue.Kind() != reflect.Slice {
+		return db.Errorf(`destination must be a pointer to slice: %T`, dst)
+	}
+	result := reflect.MakeSlice(sliceValue.Type(), len(records), len(records))
+	for i, r := range records {
+		if err := decodeRecord(meta, r, result.Index(i)); err != nil {
+			return err
+		}
+	}
+	sliceValue.Set(result)
+	return nil
+}
+
+// decodeValues 将单个字段的取值列表赋给目标切片
+func decodeValues(values []interface{}, dst interface{}) error {
+	reflectValue := reflect.ValueOf(dst)
+	if reflectValue.Kind() != reflect.Ptr || reflectValue.IsNil() {
+		return db.Errorf(`destination must be a non-nil pointer: %T`, dst)
+	}
+	sliceValue := reflect.Indirect(reflectValue)
+	if sliceValue.Kind() != reflect.Slice {
+		return db.Errorf(`destination must be a pointer to slice: %T`, dst)
+	}
+	result := reflect.MakeSlice(sliceValue.Type(), len(values), len(values))
+	for i, v := range values {
+		if err := assign(result.Index(i), v); err != nil {
+			return err
+		}
+	}
+	sliceValue.Set(result)
+	return nil
+}
+
+func decodeOne(meta db.Metadata, r record, dst interface{}) error {
+	reflectValue := reflect.ValueOf(dst)
+	if reflectValue.Kind() != reflect.Ptr || reflectValue.IsNil() {
+		return db.Errorf(`destination must be a non-nil pointer: %T`, dst)
+	}
+	return decodeRecord(meta, r, reflectValue.Elem())
+}
+
+func decodeRecord(meta db.Metadata, r record, dst reflect.Value) error {
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return decodeRecord(meta, r, dst.Elem())
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(map[string]interface{}(r)))
+	case reflect.Map:
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		for k, v := range r {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := assign(elem, v); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+		}
+	case reflect.Struct:
+		t := dst.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			v, has := lookupStructField(meta, r, sf)
+			if !has {
+				continue
+			}
+			if err := assign(dst.Field(i), v); err != nil {
+				return db.Errorf(`decode field %s failed: %v`, sf.Name, err)
+			}
+		}
+	default:
+		return db.Errorf(`unsupported destination type: %s`, dst.Type())
+	}
+	return nil
+}
+
+func lookupStructField(meta db.Metadata, r record, sf reflect.StructField) (interface{}, bool) {
+	var keys []string
+	if tag, ok := sf.Tag.Lookup("json"); ok {
+		if tag = strings.Split(tag, ",")[0]; tag == "-" {
+			return nil, false
+		} else if tag != "" {
+			keys = append(keys, tag)
+		}
+	}
+	keys = append(keys, nativeKey(meta, sf.Name), sf.Name, strcase.ToSnake(sf.Name))
+	for _, key := range keys {
+		if v, has := r[key]; has {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// assign 将数据库返回的值赋给目标字段，兼容各驱动返回的不同类型
+func assign(dst reflect.Value, v interface{}) error {
+	if v == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.CanAddr() {
+		if s, ok := dst.Addr().Interface().(interface{ Scan(interface{}) error }); ok {
+			return s.Scan(v)
+		}
+	}
+	src := reflect.ValueOf(v)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if err := assign(elem.Elem(), v); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case reflect.Interface:
+		if src.Type().Implements(dst.Type()) {
+			dst.Set(src)
+			return nil
+		}
+	}
+	switch s := v.(type) {
+	case []byte:
+		return assign(dst, string(s))
+	case string:
+		if dst.Type() == timeType {
+			t, err := parseTime(s)
+			if err != nil {
+				return err
+			}
+			dst.Set(reflect.ValueOf(t))
+			return nil
+		}
+		switch dst.Kind() {
+		case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+			tmp := reflect.New(dst.Type())
+			if err := db.JSONParse(s, tmp.Interface()); err != nil {
+				return err
+			}
+			dst.Set(tmp.Elem())
+			return nil
+		case reflect.Bool:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return err
+			}
+			dst.SetBool(b)
+			return nil
+		}
+		if isNumberKind(dst.Kind()) {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return err
+			}
+			return assign(dst, f)
+		}
+	}
+	switch {
+	case isNumberKind(src.Kind()) && isNumberKind(dst.Kind()),
+		src.Kind() == reflect.String && dst.Kind() == reflect.String:
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	case isNumberKind(src.Kind()) && dst.Kind() == reflect.String:
+		// 自增主键等数值列可读取到字符串字段中
+		dst.SetString(fmt.Sprint(v))
+		return nil
+	case isNumberKind(src.Kind()) && dst.Kind() == reflect.Bool:
+		dst.SetBool(src.Convert(reflect.TypeOf(float64(0))).Float() != 0)
+		return nil
+	case src.Kind() == reflect.Bool && isNumberKind(dst.Kind()):
+		var n int64
+		if src.Bool() {
+			n = 1
+		}
+		dst.Set(reflect.ValueOf(n).Convert(dst.Type()))
+		return nil
+	}
+	return db.Errorf(`cannot assign %T to %s`, v, dst.Type())
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999", "2006-01-02"}
+)
+
+func parseTime(s string) (t time.Time, err error) {
+	for _, layout := range timeLayouts {
+		if t, err = time.Parse(layout, s); err == nil {
+			return
+		}
+	}
+	return t, db.Errorf(`cannot parse time: %s`, s)
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
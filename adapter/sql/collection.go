@@ -0,0 +1,213 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"github.com/iamdanielyin/db"
+	"strconv"
+	"strings"
+)
+
+type sqlCollection struct {
+	client *sqlClient
+	sess   *db.Connection
+	meta   db.Metadata
+	ctx    context.Context
+	// 事务中的集合会将所有操作绑定到该事务
+	exec executor
+}
+
+func (c *sqlCollection) Name() string {
+	return c.meta.Name
+}
+
+func (c *sqlCollection) Metadata() db.Metadata {
+	return c.meta
+}
+
+func (c *sqlCollection) Session() *db.Connection {
+	return c.sess
+}
+
+func (c *sqlCollection) WithContext(ctx context.Context) db.Collection {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+func (c *sqlCollection) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+func (c *sqlCollection) table() string {
+	return c.client.dialect().Quote(c.meta.MustNativeName())
+}
+
+func (c *sqlCollection) InsertOne(v interface{}, fns ...func(*db.InsertOptions)) (db.InsertOneResult, error) {
+	records, err := encode(c.meta, v)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) != 1 {
+		return nil, db.Errorf(`expected one document, got %d`, len(records))
+	}
+	id, err := c.insert(records[0])
+	if err != nil {
+		return nil, err
+	}
+	return &insertOneResult{id: id}, nil
+}
+
+func (c *sqlCollection) InsertMany(v interface{}, fns ...func(*db.InsertOptions)) (db.InsertManyResult, error) {
+	records, err := encode(c.meta, v)
+	if err != nil {
+		return nil, err
+	}
+	result := &insertManyResult{}
+	// 逐行插入以获取每条记录的主键
+	for _, r := range records {
+		id, err := c.insert(r)
+		if err != nil {
+			return nil, err
+		}
+		result.ids = append(result.ids, id)
+	}
+	return result, nil
+}
+
+// insert 插入一行并返回主键，未指定主键时使用数据库生成的值
+func (c *sqlCollection) insert(r record) (interface{}, error) {
+	var (
+		d    = c.client.dialect()
+		b    = &builder{dialect: d, meta: c.meta}
+		pk   = primaryKey(c.meta)
+		cols = r.columns()
+	)
+	quoted := make([]string, len(cols))
+	values := make([]string, len(cols))
+	for i, col := range cols {
+		quoted[i] = d.Quote(col)
+		values[i] = b.bind(r[col])
+	}
+	var query string
+	if len(cols) == 0 {
+		query = fmt.Sprintf("INSERT INTO %s %s", c.table(), d.DefaultValues)
+	} else {
+		query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", c.table(), strings.Join(quoted, ", "), strings.Join(values, ", "))
+	}
+	if id, has := r[pk]; has {
+		if _, err := c.client.exec(c.context(), c.exec, query, b.args...); err != nil {
+			return nil, err
+		}
+		return id, nil
+	}
+	if d.Returning {
+		rows, err := c.client.query(c.context(), c.exec, query+" RETURNING "+d.Quote(pk), b.args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var id interface{}
+		if rows.Next() {
+			if err := rows.Scan(&id); err != nil {
+				return nil, db.Errorf(`%w`, err)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, c.client.wrapError(err)
+		}
+		return id, nil
+	}
+	res, err := c.client.exec(c.context(), c.exec, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		// 部分驱动不支持返回自增主键
+		return nil, nil
+	}
+	return id, nil
+}
+
+// BulkWrite 依次执行各个操作
+func (c *sqlCollection) BulkWrite(ops []db.WriteOp, ordered bool) (db.BulkWriteResult, error) {
+	return db.SequentialBulkWrite(c, ops, ordered)
+}
+
+func (c *sqlCollection) Find(i ...interface{}) db.Result {
+	return &sqlResult{coll: c, conditions: i, ctx: c.ctx}
+}
+
+type insertOneResult struct {
+	id interface{}
+}
+
+func (i *insertOneResult) StringID() string {
+	return idToString(i.id)
+}
+
+func (i *insertOneResult) IntID() int {
+	return idToInt(i.id)
+}
+
+type upsertResult struct {
+	insertOneResult
+	inserted bool
+}
+
+func (u *upsertResult) Inserted() bool {
+	return u.inserted
+}
+
+type insertManyResult struct {
+	ids []interface{}
+}
+
+func (i *insertManyResult) StringIDs() (v []string) {
+	for _, id := range i.ids {
+		v = append(v, idToString(id))
+	}
+	return
+}
+
+func (i *insertManyResult) IntIDs() (v []int) {
+	for _, id := range i.ids {
+		v = append(v, idToInt(id))
+	}
+	return
+}
+
+func idToString(id interface{}) string {
+	switch v := id.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprintf("%v", id)
+}
+
+func idToInt(id interface{}) int {
+	switch v := id.(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint:
+		return int(v)
+	case uint32:
+		return int(v)
+	case uint64:
+		return int(v)
+	}
+	n, _ := strconv.Atoi(idToString(id))
+	return n
+}
@@ -0,0 +1,230 @@
+package sql
+
+import (
+	"fmt"
+	"github.com/iamdanielyin/db"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// QueryFilter 将查询条件转换为WHERE子句（不含WHERE关键字）及对应参数
+func QueryFilter(d *Dialect, meta db.Metadata, filters ...interface{}) (string, []interface{}) {
+	b := &builder{dialect: d, meta: meta}
+	return b.where(filters...), b.args
+}
+
+type builder struct {
+	dialect *Dialect
+	meta    db.Metadata
+	args    []interface{}
+}
+
+// bind 追加参数并返回对应的占位符
+func (b *builder) bind(v interface{}) string {
+	b.args = append(b.args, v)
+	return b.dialect.Placeholder(len(b.args))
+}
+
+func (b *builder) column(name string) string {
+	if f, has := b.meta.FieldByName(name); has {
+		name = f.MustNativeName()
+	}
+	return b.dialect.Quote(name)
+}
+
+// set 生成UPDATE语句中的赋值部分
+func (b *builder) set(i interface{}) ([]string, error) {
+	var (
+		d     = b.dialect
+		parts []string
+	)
+	if u, ok := i.(*db.Updater); ok {
+		for _, item := range u.NativeEntries(b.meta) {
+			col := d.Quote(item.Key)
+			switch item.Operator {
+			case db.UpdateOperatorSet:
+				v, err := encodeValue(item.Value)
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, col+" = "+b.bind(v))
+			case db.UpdateOperatorInc:
+				parts = append(parts, col+" = COALESCE("+col+", 0) + "+b.bind(item.Value))
+			case db.UpdateOperatorUnset:
+				parts = append(parts, col+" = NULL")
+			case db.UpdateOperatorSetOnInsert:
+				// 仅在Upsert新增记录时写入，见insertRecord
+				continue
+			default:
+				return nil, db.Errorf(`unsupported update operator: %s`, item.Operator)
+			}
+		}
+		return parts, nil
+	}
+	records, err := encode(b.meta, i)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) != 1 {
+		return nil, db.Errorf(`expected one document, got %d`, len(records))
+	}
+	for _, col := range records[0].columns() {
+		parts = append(parts, d.Quote(col)+" = "+b.bind(records[0][col]))
+	}
+	return parts, nil
+}
+
+func (b *builder) where(filters ...interface{}) string {
+	var parts []string
+	for _, filter := range filters {
+		var s string
+		switch v := filter.(type) {
+		case db.Cond:
+			s = b.cond(v)
+		case *db.Cond:
+			s = b.cond(*v)
+		case db.Union:
+			s = b.union(&v)
+		case *db.Union:
+			s = b.union(v)
+		}
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return joinConditions(parts, " AND ")
+}
+
+func (b *builder) cond(c db.Cond) string {
+	entries := c.Entries()
+	// 保证生成的语句稳定，便于数据库缓存执行计划
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Key != entries[j].Key {
+			return entries[i].Key < entries[j].Key
+		}
+		return entries[i].Operator < entries[j].Operator
+	})
+	var parts []string
+	for _, item := range entries {
+		if s := b.condition(&item); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return joinConditions(parts, " AND ")
+}
+
+func (b *builder) union(u *db.Union) string {
+	var parts []string
+	for _, each := range u.Conditions() {
+		if s := b.where(each); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if u.Operator() == db.OperatorOr {
+		return joinConditions(parts, " OR ")
+	}
+	return joinConditions(parts, " AND ")
+}
+
+func joinConditions(parts []string, sep string) string {
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+func (b *builder) condition(item *db.ConditionEntry) string {
+	col := b.column(item.Key)
+	switch item.Operator {
+	case db.OperatorEq:
+		if db.IsNil(item.Value) {
+			return col + " IS NULL"
+		}
+		return col + " = " + b.bind(item.Value)
+	case db.OperatorNotEq:
+		if db.IsNil(item.Value) {
+			return col + " IS NOT NULL"
+		}
+		return col + " <> " + b.bind(item.Value)
+	case db.OperatorPrefix:
+		return b.like(col, escapeLike(item.Value)+"%")
+	case db.OperatorSuffix:
+		return b.like(col, "%"+escapeLike(item.Value))
+	case db.OperatorContains:
+		return b.like(col, "%"+escapeLike(item.Value)+"%")
+	case db.OperatorGt:
+		return col + " > " + b.bind(item.Value)
+	case db.OperatorGte:
+		return col + " >= " + b.bind(item.Value)
+	case db.OperatorLt:
+		return col + " < " + b.bind(item.Value)
+	case db.OperatorLte:
+		return col + " <= " + b.bind(item.Value)
+	case db.OperatorRegExp:
+		pattern, flags := parseRegExp(item.Value)
+		op, value := b.dialect.RegExp(pattern, strings.Contains(flags, "i"))
+		return col + " " + op + " " + b.bind(value)
+	case db.OperatorIn, db.OperatorNotIn:
+		values := toSlice(item.Value)
+		if len(values) == 0 {
+			// 空集合：IN永远不成立，NOT IN永远成立
+			if item.Operator == db.OperatorIn {
+				return "1 = 0"
+			}
+			return "1 = 1"
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			placeholders[i] = b.bind(v)
+		}
+		op := " IN "
+		if item.Operator == db.OperatorNotIn {
+			op = " NOT IN "
+		}
+		return col + op + "(" + strings.Join(placeholders, ", ") + ")"
+	case db.OperatorExists:
+		// 关系型数据库中字段总是存在，以是否为NULL判断
+		if exists, _ := item.Value.(bool); exists {
+			return col + " IS NOT NULL"
+		}
+		return col + " IS NULL"
+	}
+	return ""
+}
+
+func (b *builder) like(col, pattern string) string {
+	return col + " " + b.dialect.Like + " " + b.bind(pattern) + " ESCAPE '!'"
+}
+
+// escapeLike 转义模糊匹配中的通配符，使用“!”作为转义字符以兼容MySQL对反斜杠的处理
+func escapeLike(v interface{}) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(fmt.Sprintf("%v", v))
+}
+
+// parseRegExp 解析“/pattern/flags”格式的正则表达式
+func parseRegExp(v interface{}) (pattern, flags string) {
+	s, _ := v.(string)
+	if lastIdx := strings.LastIndex(s, "/"); strings.HasPrefix(s, "/") && lastIdx > 0 {
+		return s[1:lastIdx], s[lastIdx+1:]
+	}
+	return s, ""
+}
+
+func toSlice(v interface{}) []interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		if v == nil {
+			return nil
+		}
+		return []interface{}{v}
+	}
+	values := make([]interface{}, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values
+}
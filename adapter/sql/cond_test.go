@@ -0,0 +1,116 @@
+package sql_test
+
+import (
+	"github.com/iamdanielyin/db"
+	"github.com/iamdanielyin/db/adapter/sql"
+	"reflect"
+	"testing"
+)
+
+func TestQueryFilter(t *testing.T) {
+	meta := db.Metadata{Name: "User", Properties: db.Fields{}}
+	for _, name := range []string{"Status", "Age", "DeletedAt", "UpdatedAt", "EmailAddress", "Username"} {
+		meta.Properties[name] = db.Field{Name: name, Type: db.String}
+	}
+	tests := []struct {
+		name     string
+		args     []interface{}
+		want     string
+		wantPg   string
+		wantArgs []interface{}
+		pgArgs   []interface{} // 为空时与wantArgs相同
+	}{
+		{
+			name:     "eq",
+			args:     []interface{}{db.Cond{"Status": 1}},
+			want:     `"status" = ?`,
+			wantPg:   `"status" = $1`,
+			wantArgs: []interface{}{1},
+		},
+		{
+			name:     "multiple entries",
+			args:     []interface{}{db.Cond{"Status !=": 1, "Age >": 18, "Age <=": 60}},
+			want:     `("age" <= ? AND "age" > ? AND "status" <> ?)`,
+			wantPg:   `("age" <= $1 AND "age" > $2 AND "status" <> $3)`,
+			wantArgs: []interface{}{60, 18, 1},
+		},
+		{
+			name:     "null",
+			args:     []interface{}{db.Cond{"DeletedAt": nil, "UpdatedAt !=": nil}},
+			want:     `("deleted_at" IS NULL AND "updated_at" IS NOT NULL)`,
+			wantPg:   `("deleted_at" IS NULL AND "updated_at" IS NOT NULL)`,
+			wantArgs: nil,
+		},
+		{
+			name:     "prefix",
+			args:     []interface{}{db.Cond{"EmailAddress *=": "foo_"}},
+			want:     `"email_address" LIKE ? ESCAPE '!'`,
+			wantPg:   `"email_address" ILIKE $1 ESCAPE '!'`,
+			wantArgs: []interface{}{"foo!_%"},
+		},
+		{
+			name:     "suffix",
+			args:     []interface{}{db.Cond{"EmailAddress =*": "100%"}},
+			want:     `"email_address" LIKE ? ESCAPE '!'`,
+			wantPg:   `"email_address" ILIKE $1 ESCAPE '!'`,
+			wantArgs: []interface{}{"%100!%"},
+		},
+		{
+			name:     "contains",
+			args:     []interface{}{db.Cond{"EmailAddress *": "foo"}},
+			want:     `"email_address" LIKE ? ESCAPE '!'`,
+			wantPg:   `"email_address" ILIKE $1 ESCAPE '!'`,
+			wantArgs: []interface{}{"%foo%"},
+		},
+		{
+			name:     "regexp",
+			args:     []interface{}{db.Cond{"Username ~=": "/^foo/i"}},
+			want:     `"username" REGEXP ?`,
+			wantPg:   `"username" ~* $1`,
+			wantArgs: []interface{}{"(?i)^foo"},
+			pgArgs:   []interface{}{"^foo"},
+		},
+		{
+			name:     "in",
+			args:     []interface{}{db.Cond{"Status $in": []int{1, 2}}},
+			want:     `"status" IN (?, ?)`,
+			wantPg:   `"status" IN ($1, $2)`,
+			wantArgs: []interface{}{1, 2},
+		},
+		{
+			name:     "empty in",
+			args:     []interface{}{db.Cond{"Status $in": []int{}, "Age $nin": []int{}}},
+			want:     `(1 = 1 AND 1 = 0)`,
+			wantPg:   `(1 = 1 AND 1 = 0)`,
+			wantArgs: nil,
+		},
+		{
+			name:     "exists",
+			args:     []interface{}{db.Cond{"Age $exists": true, "Status $exists": false}},
+			want:     `("age" IS NOT NULL AND "status" IS NULL)`,
+			wantPg:   `("age" IS NOT NULL AND "status" IS NULL)`,
+			wantArgs: nil,
+		},
+		{
+			name:     "union",
+			args:     []interface{}{db.Cond{"Status": 1}, db.NewUnion(db.OperatorOr, []db.Conditional{db.Cond{"Age <": 18}, db.Cond{"Age >": 60}})},
+			want:     `("status" = ? AND ("age" < ? OR "age" > ?))`,
+			wantPg:   `("status" = $1 AND ("age" < $2 OR "age" > $3))`,
+			wantArgs: []interface{}{1, 18, 60},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for d, want := range map[*sql.Dialect]string{sql.SQLite: tt.want, sql.PostgreSQL: tt.wantPg} {
+				wantArgs := tt.wantArgs
+				if d == sql.PostgreSQL && tt.pgArgs != nil {
+					wantArgs = tt.pgArgs
+				}
+				got, args := sql.QueryFilter(d, meta, tt.args...)
+				if got != want || !reflect.DeepEqual(args, wantArgs) {
+					t.Errorf("%s: QueryFilter() = %s %v, want %s %v", d.Name, got, args, want, wantArgs)
+				}
+			}
+		})
+	}
+}
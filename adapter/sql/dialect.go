@@ -0,0 +1,130 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"github.com/iamdanielyin/db"
+	"regexp"
+	"strings"
+)
+
+// Dialect 数据库方言，描述不同数据库在SQL语法上的差异
+type Dialect struct {
+	Name       string // 适配器名称，即DataSource.Adapter
+	DriverName string // database/sql的驱动名称
+	// Placeholder 返回第n个（从1开始）参数的占位符
+	Placeholder func(n int) string
+	// Quote 转义表名或字段名
+	Quote func(name string) string
+	// Like 不区分大小写的模糊匹配运算符
+	Like string
+	// RegExp 返回正则匹配的运算符及传入数据库的表达式
+	RegExp func(pattern string, ignoreCase bool) (string, string)
+	// Returning 插入时是否通过RETURNING子句返回主键
+	Returning bool
+	// DefaultValues 插入空记录时使用的子句
+	DefaultValues string
+	// ForUpdate 查询时锁定记录的子句，为空时不加锁
+	ForUpdate string
+	// ClassifyError 返回驱动错误对应的db.ErrDuplicateKey或db.ErrTxAborted，无法识别时返回nil
+	ClassifyError func(err error) error
+}
+
+var (
+	SQLite = &Dialect{
+		Name:          "sqlite",
+		DriverName:    "sqlite3",
+		Placeholder:   questionPlaceholder,
+		Quote:         quoteWith(`"`),
+		Like:          "LIKE",
+		RegExp:        inlineRegExp,
+		DefaultValues: "DEFAULT VALUES",
+		ClassifyError: classifySQLiteError,
+	}
+	PostgreSQL = &Dialect{
+		Name:        "postgres",
+		DriverName:  "postgres",
+		Placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+		Quote:       quoteWith(`"`),
+		Like:        "ILIKE",
+		RegExp: func(pattern string, ignoreCase bool) (string, string) {
+			if ignoreCase {
+				return "~*", pattern
+			}
+			return "~", pattern
+		},
+		Returning:     true,
+		DefaultValues: "DEFAULT VALUES",
+		ForUpdate:     "FOR UPDATE",
+		ClassifyError: classifyPostgreSQLError,
+	}
+	MySQL = &Dialect{
+		Name:          "mysql",
+		DriverName:    "mysql",
+		Placeholder:   questionPlaceholder,
+		Quote:         quoteWith("`"),
+		Like:          "LIKE",
+		RegExp:        inlineRegExp,
+		DefaultValues: "() VALUES ()",
+		ForUpdate:     "FOR UPDATE",
+		ClassifyError: classifyMySQLError,
+	}
+)
+
+// classifySQLiteError SQLite驱动未导出统一的错误类型，按错误信息识别
+func classifySQLiteError(err error) error {
+	if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return db.ErrDuplicateKey
+	}
+	return nil
+}
+
+// classifyPostgreSQLError 按SQLSTATE识别，lib/pq及pgx的错误类型均实现了SQLState方法
+func classifyPostgreSQLError(err error) error {
+	var se interface{ SQLState() string }
+	if !errors.As(err, &se) {
+		return nil
+	}
+	switch se.SQLState() {
+	case "23505":
+		return db.ErrDuplicateKey
+	case "40001", "40P01", "25P02":
+		return db.ErrTxAborted
+	}
+	return nil
+}
+
+var mysqlErrorNumber = regexp.MustCompile(`^Error (\d+)`)
+
+// classifyMySQLError 按错误信息中的错误码识别，避免依赖具体的驱动
+func classifyMySQLError(err error) error {
+	m := mysqlErrorNumber.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil
+	}
+	switch m[1] {
+	case "1062", "1586":
+		return db.ErrDuplicateKey
+	case "1213":
+		return db.ErrTxAborted
+	}
+	return nil
+}
+
+func questionPlaceholder(int) string {
+	return "?"
+}
+
+// inlineRegExp 通过内联标记实现不区分大小写的匹配
+func inlineRegExp(pattern string, ignoreCase bool) (string, string) {
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return "REGEXP", pattern
+}
+
+func quoteWith(q string) func(string) string {
+	return func(name string) string {
+		return q + strings.ReplaceAll(name, q, q+q) + q
+	}
+}
@@ -0,0 +1,34 @@
+package sql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/iamdanielyin/db"
+)
+
+type pgError struct{ code string }
+
+func (e *pgError) Error() string    { return "pq: error " + e.code }
+func (e *pgError) SQLState() string { return e.code }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		dialect *Dialect
+		err     error
+		want    error
+	}{
+		{SQLite, errors.New("UNIQUE constraint failed: user.id"), db.ErrDuplicateKey},
+		{SQLite, errors.New("no such table: user"), nil},
+		{PostgreSQL, &pgError{code: "23505"}, db.ErrDuplicateKey},
+		{PostgreSQL, &pgError{code: "40001"}, db.ErrTxAborted},
+		{PostgreSQL, &pgError{code: "42P01"}, nil},
+		{MySQL, errors.New("Error 1062: Duplicate entry '1' for key 'PRIMARY'"), db.ErrDuplicateKey},
+		{MySQL, errors.New("Error 1213 (40001): Deadlock found when trying to get lock"), db.ErrTxAborted},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.ClassifyError(tt.err); got != tt.want {
+			t.Errorf("%s.ClassifyError(%v) = %v, want %v", tt.dialect.Name, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,702 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/iamdanielyin/db"
+	"math"
+	"sort"
+	"strings"
+)
+
+type sqlResult struct {
+	coll       *sqlCollection
+	conditions []interface{}
+	projection []string
+	orderBys   []string
+	pageNum    uint
+	pageSize   uint
+	after      string
+	before     string
+	unscoped   bool
+	forUpdate  bool
+	groupBys   []string
+	aggs       []db.Aggregation
+	havings    []interface{}
+	ctx        context.Context
+}
+
+func (r *sqlResult) WithContext(ctx context.Context) db.Result {
+	r.ctx = ctx
+	return r
+}
+
+func (r *sqlResult) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+func (r *sqlResult) And(i ...db.Conditional) db.Result {
+	r.conditions = append(r.conditions, db.And(i...))
+	return r
+}
+
+func (r *sqlResult) Or(i ...db.Conditional) db.Result {
+	r.conditions = append(r.conditions, db.Or(i...))
+	return r
+}
+
+func (r *sqlResult) Project(p ...string) db.Result {
+	r.projection = p
+	return r
+}
+
+func (r *sqlResult) One(dst interface{}, fns ...func(*db.QueryOptions)) error {
+	records, meta, err := r.find(1)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return db.NotFound(fns...)
+	}
+	return decodeOne(meta, records[0], dst)
+}
+
+func (r *sqlResult) All(dst interface{}) error {
+	records, meta, err := r.find(0)
+	if err != nil {
+		return err
+	}
+	return decodeAll(meta, records, dst)
+}
+
+func (r *sqlResult) Cursor() (db.Cursor, error) {
+	query, args, meta, err := r.selectQuery(0)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.coll.client.query(r.context(), r.coll.exec, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return newCursor(meta, rows)
+}
+
+func (r *sqlResult) OrderBy(s ...string) db.Result {
+	r.orderBys = append(r.orderBys, s...)
+	return r
+}
+
+func (r *sqlResult) Count() (int, error) {
+	where, args := r.where(nil)
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", r.coll.table(), where)
+	if r.grouped() {
+		b := &builder{dialect: r.coll.client.dialect(), meta: r.coll.meta}
+		group, _, err := r.groupQuery(b)
+		if err != nil {
+			return 0, err
+		}
+		query, args = fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS %s", group, b.dialect.Quote("db_count")), b.args
+	}
+	rows, err := r.coll.client.query(r.context(), r.coll.exec, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	if rows.Next() {
+		if err := rows.Scan(&n); err != nil {
+			return 0, db.Errorf(`%w`, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, db.Errorf(`%w`, err)
+	}
+	return n, nil
+}
+
+func (r *sqlResult) Distinct(field string, dst interface{}) error {
+	var (
+		key    = nativeKey(r.coll.meta, field)
+		col    = r.coll.client.dialect().Quote(key)
+		filter = col + " IS NOT NULL"
+	)
+	b := &builder{dialect: r.coll.client.dialect(), meta: r.coll.meta}
+	if where := b.where(r.conditions...); where != "" {
+		filter = "(" + where + ") AND " + filter
+	}
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s ORDER BY %s", col, r.coll.table(), filter, col)
+	rows, err := r.coll.client.query(r.context(), r.coll.exec, query, b.args...)
+	if err != nil {
+		return err
+	}
+	records, err := scanRecords(rows, 0)
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, 0, len(records))
+	for _, rec := range records {
+		values = append(values, rec[key])
+	}
+	return decodeValues(values, dst)
+}
+
+func (r *sqlResult) Preload(path string, fns ...func(*db.PreloadOptions)) db.Result {
+	return r
+}
+
+func (r *sqlResult) Paginate(u uint) db.Result {
+	r.pageSize = u
+	return r
+}
+
+func (r *sqlResult) Page(u uint) db.Result {
+	r.pageNum = u
+	return r
+}
+
+func (r *sqlResult) After(token string) db.Result {
+	r.after = token
+	return r
+}
+
+func (r *sqlResult) Before(token string) db.Result {
+	r.before = token
+	return r
+}
+
+// Scroll 键集分页，忽略Page设置的页码
+func (r *sqlResult) Scroll(dst interface{}) (db.PageTokens, error) {
+	if r.grouped() {
+		return db.PageTokens{}, db.Errorf(`keyset pagination is not supported by grouped queries`)
+	}
+	return db.KeysetScroll(&sqlResult{coll: r.coll, conditions: r.conditions, ctx: r.ctx}, r.coll.meta, db.Keyset{
+		OrderBys:   r.orderBys,
+		Projection: r.projection,
+		Size:       r.pageSize,
+		After:      r.after,
+		Before:     r.before,
+	}, dst)
+}
+
+func (r *sqlResult) TotalRecords() (int, error) {
+	return r.Count()
+}
+
+func (r *sqlResult) TotalPages() (int, error) {
+	if r.pageSize == 0 {
+		return 1, nil
+	}
+	totalRecords, err := r.TotalRecords()
+	if err != nil {
+		return 0, err
+	}
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(r.pageSize)))
+	return totalPages, nil
+}
+
+func (r *sqlResult) GroupBy(fields ...string) db.Result {
+	r.groupBys = append(r.groupBys, fields...)
+	return r
+}
+
+func (r *sqlResult) Aggregate(aggs ...db.Aggregation) db.Result {
+	r.aggs = append(r.aggs, aggs...)
+	return r
+}
+
+func (r *sqlResult) Having(i ...db.Conditional) db.Result {
+	for _, item := range i {
+		r.havings = append(r.havings, item)
+	}
+	return r
+}
+
+func (r *sqlResult) Unscoped() db.Result {
+	r.unscoped = true
+	return r
+}
+
+// UpdateOne 在事务中锁定第一条匹配记录，再按原有条件及其主键更新，返回实际更新的记录数
+func (r *sqlResult) UpdateOne(i interface{}, fns ...func(*db.UpdateOptions)) (n int, err error) {
+	err = r.atomic(func(r *sqlResult) error {
+		id, found, err := r.firstID()
+		if err != nil || !found {
+			return err
+		}
+		n, err = r.update(i, r.byIDConditions(id)...)
+		return err
+	})
+	return
+}
+
+func (r *sqlResult) UpdateMany(i interface{}, fns ...func(*db.UpdateOptions)) (int, error) {
+	return r.update(i, r.conditions...)
+}
+
+func (r *sqlResult) Upsert(i interface{}) (db.UpsertResult, error) {
+	rec, err := insertRecord(r.coll.meta, i)
+	if err != nil {
+		return nil, err
+	}
+	var result *upsertResult
+	err = r.atomic(func(r *sqlResult) error {
+		id, found, err := r.firstID()
+		if err != nil {
+			return err
+		}
+		if found {
+			result = &upsertResult{insertOneResult: insertOneResult{id: id}}
+			_, err = r.update(i, db.Cond{primaryKey(r.coll.meta): id})
+			return err
+		}
+		// 等值条件作为新记录的初始值，文档中的值优先
+		for k, v := range db.EqualValues(r.conditions...) {
+			key := nativeKey(r.coll.meta, k)
+			if _, has := rec[key]; has {
+				continue
+			}
+			if rec[key], err = encodeValue(v); err != nil {
+				return err
+			}
+		}
+		if id, err = r.coll.insert(rec); err != nil {
+			return err
+		}
+		result = &upsertResult{insertOneResult: insertOneResult{id: id}, inserted: true}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *sqlResult) FindOneAndUpdate(i interface{}, dst interface{}, returnNew bool, fns ...func(*db.QueryOptions)) error {
+	return r.atomic(func(r *sqlResult) error {
+		id, found, err := r.firstID()
+		if err != nil {
+			return err
+		}
+		if !found {
+			return db.NotFound(fns...)
+		}
+		cond := db.Cond{primaryKey(r.coll.meta): id}
+		if !returnNew {
+			if err := r.byID(cond).One(dst); err != nil {
+				return err
+			}
+		}
+		if _, err := r.update(i, cond); err != nil {
+			return err
+		}
+		if returnNew {
+			return r.byID(cond).One(dst)
+		}
+		return nil
+	})
+}
+
+func (r *sqlResult) FindOneAndDelete(dst interface{}, fns ...func(*db.QueryOptions)) error {
+	return r.atomic(func(r *sqlResult) error {
+		id, found, err := r.firstID()
+		if err != nil {
+			return err
+		}
+		if !found {
+			return db.NotFound(fns...)
+		}
+		cond := db.Cond{primaryKey(r.coll.meta): id}
+		if err := r.byID(cond).One(dst); err != nil {
+			return err
+		}
+		_, err = r.delete(cond)
+		return err
+	})
+}
+
+// atomic 在事务中执行先查询后写入的操作并锁定查询到的记录，已处于事务中时直接执行
+func (r *sqlResult) atomic(fn func(*sqlResult) error) error {
+	rr := *r
+	rr.forUpdate = true
+	conn, ok := r.coll.exec.(*sql.DB)
+	if !ok {
+		return fn(&rr)
+	}
+	tx, err := conn.BeginTx(r.context(), nil)
+	if err != nil {
+		return db.Errorf(`%w`, err)
+	}
+	coll := *r.coll
+	coll.exec = tx
+	rr.coll = &coll
+	if err := fn(&rr); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return db.Errorf(`%w; %v`, err, rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return r.coll.client.wrapError(err)
+	}
+	return nil
+}
+
+// byID 返回按主键查询且保留投影的结果
+func (r *sqlResult) byID(cond db.Cond) *sqlResult {
+	return &sqlResult{coll: r.coll, conditions: []interface{}{cond}, projection: r.projection, ctx: r.ctx}
+}
+
+func (r *sqlResult) update(i interface{}, conditions ...interface{}) (int, error) {
+	b := &builder{dialect: r.coll.client.dialect(), meta: r.coll.meta}
+	parts, err := b.set(i)
+	if err != nil {
+		return 0, err
+	}
+	if len(parts) == 0 {
+		return (&sqlResult{coll: r.coll, conditions: conditions, ctx: r.ctx}).Count()
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s", r.coll.table(), strings.Join(parts, ", "))
+	if where := b.where(conditions...); where != "" {
+		query += " WHERE " + where
+	}
+	res, err := r.coll.client.exec(r.context(), r.coll.exec, query, b.args...)
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected(res)
+}
+
+// DeleteOne 在事务中锁定第一条匹配记录，再按原有条件及其主键删除，返回实际删除的记录数
+func (r *sqlResult) DeleteOne(fns ...func(*db.DeleteOptions)) (n int, err error) {
+	err = r.atomic(func(r *sqlResult) error {
+		id, found, err := r.firstID()
+		if err != nil || !found {
+			return err
+		}
+		n, err = r.delete(r.byIDConditions(id)...)
+		return err
+	})
+	return
+}
+
+// byIDConditions 返回原有条件加上主键条件，查询与写入之间记录被修改时不会误写
+func (r *sqlResult) byIDConditions(id interface{}) []interface{} {
+	conditions := make([]interface{}, 0, len(r.conditions)+1)
+	conditions = append(conditions, r.conditions...)
+	return append(conditions, db.Cond{primaryKey(r.coll.meta): id})
+}
+
+func (r *sqlResult) DeleteMany(fns ...func(*db.DeleteOptions)) (int, error) {
+	return r.delete(r.conditions...)
+}
+
+func (r *sqlResult) delete(conditions ...interface{}) (int, error) {
+	b := &builder{dialect: r.coll.client.dialect(), meta: r.coll.meta}
+	query := fmt.Sprintf("DELETE FROM %s", r.coll.table())
+	if where := b.where(conditions...); where != "" {
+		query += " WHERE " + where
+	}
+	res, err := r.coll.client.exec(r.context(), r.coll.exec, query, b.args...)
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected(res)
+}
+
+func rowsAffected(res sql.Result) (int, error) {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, db.Errorf(`%w`, err)
+	}
+	return int(n), nil
+}
+
+func (r *sqlResult) firstID() (id interface{}, found bool, err error) {
+	pk := primaryKey(r.coll.meta)
+	rr := *r
+	rr.projection = []string{pk}
+	rr.groupBys, rr.aggs, rr.havings = nil, nil, nil
+	records, _, err := rr.find(1)
+	if err != nil || len(records) == 0 {
+		return nil, false, err
+	}
+	return records[0][pk], true, nil
+}
+
+func (r *sqlResult) where(b *builder) (string, []interface{}) {
+	if b == nil {
+		b = &builder{dialect: r.coll.client.dialect(), meta: r.coll.meta}
+	}
+	if where := b.where(r.conditions...); where != "" {
+		return " WHERE " + where, b.args
+	}
+	return "", b.args
+}
+
+// selectQuery 生成查询语句并返回结果对应的元数据，limit大于0时覆盖分页条数
+func (r *sqlResult) selectQuery(limit uint) (string, []interface{}, db.Metadata, error) {
+	var (
+		b     = &builder{dialect: r.coll.client.dialect(), meta: r.coll.meta}
+		meta  = r.coll.meta
+		query string
+	)
+	if r.grouped() {
+		var err error
+		if query, meta, err = r.groupQuery(b); err != nil {
+			return "", nil, meta, err
+		}
+	} else {
+		where, _ := r.where(b)
+		query = fmt.Sprintf("SELECT %s FROM %s%s", r.selectColumns(), r.coll.table(), where)
+	}
+	if orderBy := r.orderClause(meta); orderBy != "" {
+		query += " ORDER BY " + orderBy
+	}
+	query += r.limitClause(limit)
+	if d := b.dialect; r.forUpdate && d.ForUpdate != "" {
+		query += " " + d.ForUpdate
+	}
+	return query, b.args, meta, nil
+}
+
+func (r *sqlResult) limitClause(limit uint) (clause string) {
+	var offset uint
+	if r.pageSize > 0 && r.pageNum > 0 {
+		offset = (r.pageNum - 1) * r.pageSize
+	}
+	if limit == 0 {
+		limit = r.pageSize
+	}
+	if limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if offset > 0 {
+		if limit == 0 {
+			clause += fmt.Sprintf(" LIMIT %d", math.MaxInt32)
+		}
+		clause += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return
+}
+
+func (r *sqlResult) grouped() bool {
+	return len(r.groupBys) > 0 || len(r.aggs) > 0
+}
+
+// groupQuery 分组查询以子查询的方式执行，使Having、OrderBy可以直接引用分组字段及别名
+func (r *sqlResult) groupQuery(b *builder) (string, db.Metadata, error) {
+	d := b.dialect
+	meta, err := db.GroupMetadata(r.coll.meta, r.groupBys, r.aggs)
+	if err != nil {
+		return "", meta, err
+	}
+	var cols, inner, groups []string
+	for _, name := range r.groupBys {
+		col := d.Quote(nativeKey(r.coll.meta, name))
+		cols = append(cols, col)
+		inner = append(inner, col)
+		groups = append(groups, col)
+	}
+	for _, agg := range r.aggs {
+		var (
+			field = d.Quote(nativeKey(r.coll.meta, agg.Field))
+			alias = d.Quote(nativeKey(meta, agg.Alias))
+			expr  string
+		)
+		switch agg.Func {
+		case db.AggregateCount:
+			expr = "COUNT(*)"
+		case db.AggregateCountDistinct:
+			expr = "COUNT(DISTINCT " + field + ")"
+		case db.AggregateSum:
+			expr = "COALESCE(SUM(" + field + "), 0)"
+		default:
+			expr = agg.Func + "(" + field + ")"
+		}
+		cols = append(cols, alias)
+		inner = append(inner, expr+" AS "+alias)
+	}
+	where, _ := r.where(b)
+	query := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(inner, ", "), r.coll.table(), where)
+	var outer []string
+	if len(groups) > 0 {
+		query += " GROUP BY " + strings.Join(groups, ", ")
+	} else {
+		// 未分组时聚合函数总会返回一行，没有记录时需排除
+		count := d.Quote("db_group_count")
+		query = strings.Replace(query, "SELECT ", "SELECT COUNT(*) AS "+count+", ", 1)
+		outer = append(outer, count+" > 0")
+	}
+	b.meta = meta
+	if having := b.where(r.havings...); having != "" {
+		outer = append(outer, having)
+	}
+	query = fmt.Sprintf("SELECT %s FROM (%s) AS %s", strings.Join(cols, ", "), query, d.Quote("db_group"))
+	if len(outer) > 0 {
+		query += " WHERE " + strings.Join(outer, " AND ")
+	}
+	return query, meta, nil
+}
+
+func (r *sqlResult) find(limit uint) ([]record, db.Metadata, error) {
+	query, args, meta, err := r.selectQuery(limit)
+	if err != nil {
+		return nil, meta, err
+	}
+	rows, err := r.coll.client.query(r.context(), r.coll.exec, query, args...)
+	if err != nil {
+		return nil, meta, err
+	}
+	records, err := scanRecords(rows, 0)
+	return records, meta, err
+}
+
+// scanRecords 读取后关闭rows，limit大于0时最多读取limit条记录
+func scanRecords(rows *sql.Rows, limit int) ([]record, error) {
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, db.Errorf(`%w`, err)
+	}
+	var records []record
+	for rows.Next() {
+		rec, err := scanRecord(rows, cols)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+		if limit > 0 && len(records) >= limit {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, db.Errorf(`%w`, err)
+	}
+	return records, nil
+}
+
+func scanRecord(rows *sql.Rows, cols []string) (record, error) {
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, db.Errorf(`%w`, err)
+	}
+	rec := make(record, len(cols))
+	for i, col := range cols {
+		// 驱动复用[]byte缓冲区，需转换为字符串
+		if b, ok := values[i].([]byte); ok {
+			rec[col] = string(b)
+		} else {
+			rec[col] = values[i]
+		}
+	}
+	return rec, nil
+}
+
+// selectColumns 将投影字段转换为列名，以“-”开头的字段表示排除
+func (r *sqlResult) selectColumns() string {
+	var (
+		d        = r.coll.client.dialect()
+		meta     = r.coll.meta
+		includes []string
+		excludes = make(map[string]bool)
+	)
+	for _, item := range r.projection {
+		key := strings.TrimPrefix(item, "-")
+		if isRelationship(meta, key) {
+			continue
+		}
+		if strings.HasPrefix(item, "-") {
+			excludes[nativeKey(meta, key)] = true
+		} else {
+			includes = append(includes, nativeKey(meta, key))
+		}
+	}
+	if len(includes) == 0 && len(excludes) > 0 {
+		for name, f := range meta.Properties {
+			if col := f.MustNativeName(); !isRelationship(meta, name) && !excludes[col] {
+				includes = append(includes, col)
+			}
+		}
+		sort.Strings(includes)
+	}
+	if len(includes) == 0 {
+		return "*"
+	}
+	cols := make([]string, len(includes))
+	for i, col := range includes {
+		cols[i] = d.Quote(col)
+	}
+	return strings.Join(cols, ", ")
+}
+
+func (r *sqlResult) orderClause(meta db.Metadata) string {
+	var (
+		d     = r.coll.client.dialect()
+		parts []string
+	)
+	for _, item := range r.orderBys {
+		key, dir := item, "ASC"
+		if strings.HasPrefix(item, "-") {
+			key, dir = item[1:], "DESC"
+		}
+		if isRelationship(meta, key) {
+			continue
+		}
+		parts = append(parts, d.Quote(nativeKey(meta, key))+" "+dir)
+	}
+	return strings.Join(parts, ", ")
+}
+
+type sqlCursor struct {
+	meta db.Metadata
+	rows *sql.Rows
+	cols []string
+	// HasNext可重复调用，调用Next前不会移动游标
+	unprocessedNext bool
+	lastNextValue   bool
+}
+
+func newCursor(meta db.Metadata, rows *sql.Rows) (db.Cursor, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		_ = rows.Close()
+		return nil, db.Errorf(`%w`, err)
+	}
+	return &sqlCursor{meta: meta, rows: rows, cols: cols}, nil
+}
+
+func (c *sqlCursor) HasNext() bool {
+	if c.unprocessedNext {
+		return c.lastNextValue
+	}
+	c.unprocessedNext = true
+	c.lastNextValue = c.rows.Next()
+	return c.lastNextValue
+}
+
+func (c *sqlCursor) Next(dst interface{}) error {
+	if !c.HasNext() {
+		return db.Errorf(`cursor has no more records`)
+	}
+	c.unprocessedNext = false
+	rec, err := scanRecord(c.rows, c.cols)
+	if err != nil {
+		return err
+	}
+	return decodeOne(c.meta, rec, dst)
+}
+
+func (c *sqlCursor) Close() error {
+	if err := c.rows.Close(); err != nil {
+		return db.Errorf(`%w`, err)
+	}
+	return nil
+}
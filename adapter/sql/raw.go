@@ -0,0 +1,98 @@
+package sql
+
+import (
+	"context"
+	"github.com/iamdanielyin/db"
+)
+
+// sqlRaw 原样执行SQL语句，参数以占位符方式传入
+type sqlRaw struct {
+	client *sqlClient
+	exec   executor
+	query  string
+	args   []interface{}
+	ctx    context.Context
+}
+
+func (r *sqlRaw) WithContext(ctx context.Context) db.RawScript {
+	r.ctx = ctx
+	return r
+}
+
+func (r *sqlRaw) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// Query 返回的结果在调用One、All或Cursor时才会执行
+func (r *sqlRaw) Query() (db.QueryResult, error) {
+	return &sqlRawResult{raw: r}, nil
+}
+
+func (r *sqlRaw) Exec() (db.ExecResult, error) {
+	res, err := r.client.exec(r.context(), r.exec, r.query, r.args...)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, db.Errorf(`%w`, err)
+	}
+	return &execResult{ok: true, recordsAffected: int(n)}, nil
+}
+
+type sqlRawResult struct {
+	raw *sqlRaw
+}
+
+func (r *sqlRawResult) find(limit int) ([]record, error) {
+	raw := r.raw
+	rows, err := raw.client.query(raw.context(), raw.exec, raw.query, raw.args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRecords(rows, limit)
+}
+
+func (r *sqlRawResult) One(dst interface{}, fns ...func(*db.QueryOptions)) error {
+	records, err := r.find(1)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return db.NotFound(fns...)
+	}
+	return decodeOne(db.Metadata{}, records[0], dst)
+}
+
+func (r *sqlRawResult) All(dst interface{}) error {
+	records, err := r.find(0)
+	if err != nil {
+		return err
+	}
+	return decodeAll(db.Metadata{}, records, dst)
+}
+
+func (r *sqlRawResult) Cursor() (db.Cursor, error) {
+	raw := r.raw
+	rows, err := raw.client.query(raw.context(), raw.exec, raw.query, raw.args...)
+	if err != nil {
+		return nil, err
+	}
+	return newCursor(db.Metadata{}, rows)
+}
+
+type execResult struct {
+	ok              bool
+	recordsAffected int
+}
+
+func (r *execResult) OK() bool {
+	return r.ok
+}
+
+func (r *execResult) RecordsAffected() int {
+	return r.recordsAffected
+}
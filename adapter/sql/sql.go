@@ -0,0 +1,40 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/iamdanielyin/db"
+)
+
+type sqlAdapter struct {
+	dialect *Dialect
+}
+
+func init() {
+	for _, d := range []*Dialect{SQLite, PostgreSQL, MySQL} {
+		db.RegisterAdapter(d.Name, &sqlAdapter{dialect: d})
+	}
+}
+
+func (a *sqlAdapter) Name() string {
+	return a.dialect.Name
+}
+
+// Connect DataSource.URI将原样作为驱动的DSN
+func (a *sqlAdapter) Connect(ctx context.Context, source db.DataSource, logger db.Logger) (db.Client, error) {
+	sqlDB, err := sql.Open(a.dialect.DriverName, source.URI)
+	if err != nil {
+		return nil, db.Errorf(`%w`, err)
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
+		return nil, db.Errorf(`%w`, err)
+	}
+	client := &sqlClient{
+		adapter: a,
+		source:  source,
+		db:      sqlDB,
+		logger:  logger,
+	}
+	return client, nil
+}
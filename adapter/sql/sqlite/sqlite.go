@@ -0,0 +1,43 @@
+// Package sqlite 注册内置REGEXP函数的SQLite驱动，导入后即可通过"sqlite"适配器连接，无需单独部署数据库服务
+package sqlite
+
+import (
+	"database/sql"
+	dbsql "github.com/iamdanielyin/db/adapter/sql"
+	"github.com/mattn/go-sqlite3"
+	"regexp"
+	"sync"
+)
+
+const DriverName = "sqlite3_db"
+
+var (
+	regexpCache   = make(map[string]*regexp.Regexp)
+	regexpCacheMu sync.RWMutex
+)
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", match, true)
+		},
+	})
+	dbsql.SQLite.DriverName = DriverName
+}
+
+// match 实现SQLite的REGEXP运算符，“X REGEXP Y”等价于调用regexp(Y, X)
+func match(pattern, s string) (bool, error) {
+	regexpCacheMu.RLock()
+	re, has := regexpCache[pattern]
+	regexpCacheMu.RUnlock()
+	if !has {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return false, err
+		}
+		regexpCacheMu.Lock()
+		regexpCache[pattern] = re
+		regexpCacheMu.Unlock()
+	}
+	return re.MatchString(s), nil
+}
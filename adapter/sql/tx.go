@@ -0,0 +1,59 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/iamdanielyin/db"
+)
+
+type sqlTx struct {
+	client *sqlClient
+	tx     *sql.Tx
+	ctx    context.Context
+}
+
+func (t *sqlTx) Model(name string) db.Collection {
+	meta, err := db.LookupMetadata(name)
+	if err != nil {
+		panic(err)
+	}
+	coll := t.client.Model(meta).(*sqlCollection)
+	coll.exec = t.tx
+	coll.ctx = t.ctx
+	return coll
+}
+
+func (t *sqlTx) Context() context.Context {
+	return t.ctx
+}
+
+func (t *sqlTx) Commit() error {
+	if err := t.tx.Commit(); err != nil {
+		return t.client.wrapError(err)
+	}
+	return nil
+}
+
+func (t *sqlTx) Rollback() error {
+	if err := t.tx.Rollback(); err != nil {
+		return db.Errorf(`%w`, err)
+	}
+	return nil
+}
+
+func (t *sqlTx) Savepoint(name string) error {
+	return t.savepoint("SAVEPOINT " + t.client.dialect().Quote(name))
+}
+
+func (t *sqlTx) RollbackToSavepoint(name string) error {
+	return t.savepoint("ROLLBACK TO SAVEPOINT " + t.client.dialect().Quote(name))
+}
+
+func (t *sqlTx) ReleaseSavepoint(name string) error {
+	return t.savepoint("RELEASE SAVEPOINT " + t.client.dialect().Quote(name))
+}
+
+func (t *sqlTx) savepoint(query string) error {
+	_, err := t.client.exec(t.ctx, t.tx, query)
+	return err
+}
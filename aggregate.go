@@ -0,0 +1,95 @@
+package db
+
+const (
+	AggregateCount         = "COUNT"
+	AggregateCountDistinct = "COUNT_DISTINCT"
+	AggregateSum           = "SUM"
+	AggregateAvg           = "AVG"
+	AggregateMin           = "MIN"
+	AggregateMax           = "MAX"
+)
+
+// Aggregation 聚合函数，计算结果以Alias作为字段名返回
+type Aggregation struct {
+	Func  string
+	Field string
+	Alias string
+}
+
+// Count 统计每个分组的记录数
+func Count(alias string) Aggregation {
+	return Aggregation{Func: AggregateCount, Alias: alias}
+}
+
+// CountDistinct 统计每个分组中字段不同取值的个数
+func CountDistinct(field, alias string) Aggregation {
+	return Aggregation{Func: AggregateCountDistinct, Field: field, Alias: alias}
+}
+
+func Sum(field, alias string) Aggregation {
+	return Aggregation{Func: AggregateSum, Field: field, Alias: alias}
+}
+
+func Avg(field, alias string) Aggregation {
+	return Aggregation{Func: AggregateAvg, Field: field, Alias: alias}
+}
+
+func Min(field, alias string) Aggregation {
+	return Aggregation{Func: AggregateMin, Field: field, Alias: alias}
+}
+
+func Max(field, alias string) Aggregation {
+	return Aggregation{Func: AggregateMax, Field: field, Alias: alias}
+}
+
+// GroupMetadata 返回分组结果对应的元数据，适配器据此转换Having、OrderBy中的字段名及解析结果：
+// 分组字段沿用原元数据中的定义，聚合结果以别名为字段名、别名的蛇形命名为原始名称
+func GroupMetadata(meta Metadata, groupBys []string, aggs []Aggregation) (Metadata, error) {
+	group := Metadata{
+		Name:       meta.Name,
+		NativeName: meta.NativeName,
+		Properties: make(Fields),
+	}
+	for _, name := range groupBys {
+		f, has := meta.FieldByName(name)
+		if !has {
+			return group, Errorf(`unknown group field "%s" in metadata "%s"`, name, meta.Name)
+		}
+		if f.Relationship.Type != "" {
+			return group, Errorf(`cannot group by relationship field "%s"`, name)
+		}
+		group.Properties[f.Name] = f
+	}
+	for _, agg := range aggs {
+		if agg.Alias == "" {
+			return group, Errorf(`missing alias of aggregation %s(%s)`, agg.Func, agg.Field)
+		}
+		if _, has := group.Properties[agg.Alias]; has {
+			return group, Errorf(`duplicate field "%s" in group result`, agg.Alias)
+		}
+		field := Field{Type: Int}
+		switch agg.Func {
+		case AggregateCount:
+		case AggregateCountDistinct, AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+			f, has := meta.FieldByName(agg.Field)
+			if !has {
+				return group, Errorf(`unknown aggregate field "%s" in metadata "%s"`, agg.Field, meta.Name)
+			}
+			if f.Relationship.Type != "" {
+				return group, Errorf(`cannot aggregate relationship field "%s"`, agg.Field)
+			}
+			switch agg.Func {
+			case AggregateAvg:
+				field.Type = Float
+			case AggregateSum, AggregateMin, AggregateMax:
+				field.Type = f.Type
+			}
+		default:
+			return group, Errorf(`unsupported aggregate function: %s`, agg.Func)
+		}
+		group.Properties[agg.Alias] = field
+	}
+	group.Properties = group.Properties.updateFieldNames()
+	group.nativeProperties = group.Properties.nativeFields()
+	return group, nil
+}
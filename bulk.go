@@ -0,0 +1,159 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WriteOp 批量写入中的单个操作，Action可选值为ActionInsertOne、ActionUpdateOne、ActionUpdateMany、ActionDeleteOne及ActionDeleteMany
+type WriteOp struct {
+	Action     string
+	Conditions []Conditional
+	Doc        interface{}
+	Unscoped   bool // 忽略逻辑删除规则
+}
+
+func InsertOp(doc interface{}) WriteOp {
+	return WriteOp{Action: ActionInsertOne, Doc: doc}
+}
+
+func UpdateOneOp(doc interface{}, conditions ...Conditional) WriteOp {
+	return WriteOp{Action: ActionUpdateOne, Doc: doc, Conditions: conditions}
+}
+
+func UpdateManyOp(doc interface{}, conditions ...Conditional) WriteOp {
+	return WriteOp{Action: ActionUpdateMany, Doc: doc, Conditions: conditions}
+}
+
+func DeleteOneOp(conditions ...Conditional) WriteOp {
+	return WriteOp{Action: ActionDeleteOne, Conditions: conditions}
+}
+
+func DeleteManyOp(conditions ...Conditional) WriteOp {
+	return WriteOp{Action: ActionDeleteMany, Conditions: conditions}
+}
+
+func (op WriteOp) find(coll Collection) Result {
+	var args []interface{}
+	for _, item := range op.Conditions {
+		if item != nil && len(item.Conditions()) > 0 {
+			args = append(args, item)
+		}
+	}
+	return coll.Find(args...)
+}
+
+// BulkWriteResult 批量写入的结果，UpdatedCount为修改操作匹配的记录数，逻辑删除的记录同样计入UpdatedCount
+type BulkWriteResult struct {
+	InsertedCount int
+	UpdatedCount  int
+	DeletedCount  int
+	Inserted      map[int]InsertOneResult // 新增操作的结果，以操作下标为键
+}
+
+// WriteError 批量写入中单个操作的错误
+type WriteError struct {
+	Index int
+	Err   error
+}
+
+func (e WriteError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e WriteError) Unwrap() error {
+	return e.Err
+}
+
+// BulkWriteError 批量写入中存在失败的操作，有序写入时首个失败操作之后的操作均未执行
+type BulkWriteError struct {
+	Errors []WriteError // 按操作下标升序排列
+}
+
+func (e *BulkWriteError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, we := range e.Errors {
+		msgs = append(msgs, we.Error())
+	}
+	return "db: bulk write failed: " + strings.Join(msgs, "; ")
+}
+
+// Err 返回指定下标的操作的错误，操作成功或未执行时返回nil
+func (e *BulkWriteError) Err(index int) error {
+	for _, we := range e.Errors {
+		if we.Index == index {
+			return we.Err
+		}
+	}
+	return nil
+}
+
+// newBulkWriteError 没有失败的操作时返回nil
+func newBulkWriteError(errs []WriteError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+	return &BulkWriteError{Errors: errs}
+}
+
+// SequentialBulkWrite 依次执行批量写入中的操作，供不支持原生批量写入的适配器使用
+func SequentialBulkWrite(coll Collection, ops []WriteOp, ordered bool) (BulkWriteResult, error) {
+	var (
+		result = BulkWriteResult{Inserted: make(map[int]InsertOneResult)}
+		errs   []WriteError
+	)
+	for i, op := range ops {
+		if err := result.write(coll, i, op); err != nil {
+			errs = append(errs, WriteError{Index: i, Err: err})
+			if ordered {
+				break
+			}
+		}
+	}
+	return result, newBulkWriteError(errs)
+}
+
+func (r *BulkWriteResult) write(coll Collection, i int, op WriteOp) error {
+	switch op.Action {
+	case ActionInsertOne:
+		res, err := coll.InsertOne(op.Doc)
+		if err != nil {
+			return err
+		}
+		r.InsertedCount++
+		r.Inserted[i] = res
+	case ActionUpdateOne, ActionUpdateMany:
+		var (
+			n   int
+			err error
+		)
+		if op.Action == ActionUpdateOne {
+			n, err = op.find(coll).UpdateOne(op.Doc)
+		} else {
+			n, err = op.find(coll).UpdateMany(op.Doc)
+		}
+		if err != nil {
+			return err
+		}
+		r.UpdatedCount += n
+	case ActionDeleteOne, ActionDeleteMany:
+		var (
+			n   int
+			err error
+		)
+		if op.Action == ActionDeleteOne {
+			n, err = op.find(coll).DeleteOne()
+		} else {
+			n, err = op.find(coll).DeleteMany()
+		}
+		if err != nil {
+			return err
+		}
+		r.DeletedCount += n
+	default:
+		return Errorf(`unsupported write operation: %s`, op.Action)
+	}
+	return nil
+}
@@ -9,17 +9,23 @@ import (
 )
 
 const (
-	ActionInsertOne   = "INSERT_ONE"
-	ActionInsertMany  = "INSERT_MANY"
-	ActionUpdateOne   = "UPDATE_ONE"
-	ActionUpdateMany  = "UPDATE_MANY"
-	ActionDeleteOne   = "DELETE_ONE"
-	ActionDeleteMany  = "DELETE_MANY"
-	ActionQueryOne    = "QUERY_ONE"
-	ActionQueryAll    = "QUERY_ALL"
-	ActionQueryCursor = "QUERY_CURSOR"
-	ActionQueryCount  = "QUERY_COUNT"
-	ActionQueryPage   = "QUERY_PAGE"
+	ActionInsertOne        = "INSERT_ONE"
+	ActionInsertMany       = "INSERT_MANY"
+	ActionUpdateOne        = "UPDATE_ONE"
+	ActionUpdateMany       = "UPDATE_MANY"
+	ActionUpsert           = "UPSERT"
+	ActionFindOneAndUpdate = "FIND_ONE_AND_UPDATE"
+	ActionDeleteOne        = "DELETE_ONE"
+	ActionDeleteMany       = "DELETE_MANY"
+	ActionFindOneAndDelete = "FIND_ONE_AND_DELETE"
+	ActionBulkWrite        = "BULK_WRITE"
+	ActionQueryOne         = "QUERY_ONE"
+	ActionQueryAll         = "QUERY_ALL"
+	ActionQueryCursor      = "QUERY_CURSOR"
+	ActionQueryCount       = "QUERY_COUNT"
+	ActionQueryPage        = "QUERY_PAGE"
+	ActionQueryDistinct    = "QUERY_DISTINCT"
+	ActionQueryScroll      = "QUERY_SCROLL"
 )
 
 func newClientWrapper(raw Client, sess *Connection) *clientWrapper {
@@ -32,13 +38,15 @@ func newClientWrapper(raw Client, sess *Connection) *clientWrapper {
 			"delete": {sess: sess},
 			"row":    {sess: sess},
 			"raw":    {sess: sess},
+			"bulk":   {sess: sess},
 		},
 	}
 }
 
 type clientWrapper struct {
-	processors map[string]*processor
-	rawClient  Client
+	processors  map[string]*processor
+	rawClient   Client
+	retryPolicy *RetryPolicy
 }
 
 func (cs *clientWrapper) Name() string {
@@ -53,7 +61,7 @@ func (cs *clientWrapper) Source() DataSource {
 	return cs.rawClient.Source()
 }
 
-func (cs *clientWrapper) Raw(raw string, values ...interface{}) error {
+func (cs *clientWrapper) Raw(raw string, values ...interface{}) RawScript {
 	return cs.rawClient.Raw(raw, values...)
 }
 
@@ -66,23 +74,22 @@ func (cs *clientWrapper) Model(metadata Metadata) Collection {
 	return &callbacksCollection{client: cs, rawColl: rawColl}
 }
 
-func (cs *clientWrapper) StartTransaction() (Tx, error) {
-	return cs.rawClient.StartTransaction()
-}
-
-func (cs *clientWrapper) WithTransaction(f func(Tx) error) error {
-	return cs.rawClient.WithTransaction(f)
-}
-
 type callbacksCollection struct {
 	client  *clientWrapper
 	rawColl Collection
+	ctx     context.Context
 }
 
 func (cc *callbacksCollection) NewScope(scope *Scope) *Scope {
 	scope.StartTime = time.Now()
 	scope.Session = cc.Session()
 	scope.Metadata = cc.Metadata()
+	if scope.Context == nil {
+		scope.Context = cc.ctx
+	}
+	if scope.Context == nil {
+		scope.Context = context.Background()
+	}
 	if scope.cacheStore == nil {
 		scope.cacheStore = &sync.Map{}
 	}
@@ -101,6 +108,18 @@ func (cc *callbacksCollection) Session() *Connection {
 	return cc.rawColl.Session()
 }
 
+func (cc *callbacksCollection) WithContext(ctx context.Context) Collection {
+	return &callbacksCollection{client: cc.client, rawColl: cc.rawColl, ctx: inheritTx(cc.ctx, ctx)}
+}
+
+// inheritTx 替换上下文时保留原上下文中的事务
+func inheritTx(parent, ctx context.Context) context.Context {
+	if tx := txFromContext(parent); tx != nil && txFromContext(ctx) == nil {
+		return contextWithTx(ctx, tx)
+	}
+	return ctx
+}
+
 func (cc *callbacksCollection) InsertOne(i interface{}, fns ...func(*InsertOptions)) (InsertOneResult, error) {
 	scope := &Scope{
 		Action:       ActionInsertOne,
@@ -133,9 +152,22 @@ func (cc *callbacksCollection) InsertMany(i interface{}, fns ...func(*InsertOpti
 	return scope.InsertManyResult, scope.Error
 }
 
+// BulkWrite 各操作分别经过对应的钩子，删除操作遵循关系的删除规则，新增及修改不处理引用字段；不会自动开启事务，非原子操作，在事务中执行时绑定该事务
+func (cc *callbacksCollection) BulkWrite(ops []WriteOp, ordered bool) (BulkWriteResult, error) {
+	scope := &Scope{
+		Action:   ActionBulkWrite,
+		WriteOps: ops,
+		Ordered:  ordered,
+		Coll:     cc.rawColl,
+	}
+	cc.client.BulkProcessors().Execute(cc.NewScope(scope))
+	return scope.BulkWriteResult, scope.Error
+}
+
+// testKeyValuePairs 参数个数为偶数且第一个参数为字符串时视为键值对，如Find("Name", "foo")
 func (cc *callbacksCollection) testKeyValuePairs(v []interface{}) bool {
 	if len(v) > 0 && len(v)%2 == 0 {
-		if _, ok := v[0].(string); !ok {
+		if _, ok := v[0].(string); ok {
 			return true
 		}
 	}
@@ -218,6 +250,25 @@ func (cr *callbacksResult) OrderBy(s ...string) Result {
 	return cr
 }
 
+func (cr *callbacksResult) GroupBy(fields ...string) Result {
+	cr.scope.GroupBys = append(cr.scope.GroupBys, fields...)
+	return cr
+}
+
+func (cr *callbacksResult) Aggregate(aggs ...Aggregation) Result {
+	cr.scope.Aggregations = append(cr.scope.Aggregations, aggs...)
+	return cr
+}
+
+func (cr *callbacksResult) Having(i ...Conditional) Result {
+	for _, item := range i {
+		if item != nil && len(item.Conditions()) > 0 {
+			cr.scope.Havings = append(cr.scope.Havings, item)
+		}
+	}
+	return cr
+}
+
 func (cr *callbacksResult) Paginate(u uint) Result {
 	cr.scope.PageSize = u
 	return cr
@@ -228,13 +279,28 @@ func (cr *callbacksResult) Page(u uint) Result {
 	return cr
 }
 
+func (cr *callbacksResult) After(token string) Result {
+	cr.scope.AfterToken = token
+	return cr
+}
+
+func (cr *callbacksResult) Before(token string) Result {
+	cr.scope.BeforeToken = token
+	return cr
+}
+
+func (cr *callbacksResult) WithContext(ctx context.Context) Result {
+	cr.scope.Context = inheritTx(cr.cc.ctx, ctx)
+	return cr
+}
+
 func (cr *callbacksResult) Unscoped() Result {
 	cr.scope.Unscoped = true
 	return cr
 }
 
 func (cr *callbacksResult) Preload(path string, fns ...func(options *PreloadOptions)) Result {
-	if path != "" && len(fns) > 0 {
+	if path != "" {
 		if cr.scope.Preloads == nil {
 			cr.scope.Preloads = make(map[string][]func(options *PreloadOptions))
 		}
@@ -243,9 +309,15 @@ func (cr *callbacksResult) Preload(path string, fns ...func(options *PreloadOpti
 	return cr
 }
 
-func (cr *callbacksResult) One(dst interface{}) error {
+func (cr *callbacksResult) One(dst interface{}, fns ...func(*QueryOptions)) error {
 	cr.scope.Dest = dst
 	cr.scope.Action = ActionQueryOne
+	if len(fns) > 0 && fns[0] != nil {
+		cr.scope.QueryOptions = new(QueryOptions)
+		for _, fn := range fns {
+			fn(cr.scope.QueryOptions)
+		}
+	}
 	cr.cc.client.QueryProcessors().Execute(cr.cc.NewScope(cr.scope))
 	return cr.scope.Error
 }
@@ -269,6 +341,21 @@ func (cr *callbacksResult) Count() (int, error) {
 	return cr.scope.TotalRecords, cr.scope.Error
 }
 
+func (cr *callbacksResult) Distinct(field string, dst interface{}) error {
+	cr.scope.Dest = dst
+	cr.scope.DistinctField = field
+	cr.scope.Action = ActionQueryDistinct
+	cr.cc.client.QueryProcessors().Execute(cr.cc.NewScope(cr.scope))
+	return cr.scope.Error
+}
+
+func (cr *callbacksResult) Scroll(dst interface{}) (PageTokens, error) {
+	cr.scope.Dest = dst
+	cr.scope.Action = ActionQueryScroll
+	cr.cc.client.QueryProcessors().Execute(cr.cc.NewScope(cr.scope))
+	return cr.scope.PageTokens, cr.scope.Error
+}
+
 func (cr *callbacksResult) TotalRecords() (int, error) {
 	return cr.Count()
 }
@@ -305,6 +392,41 @@ func (cr *callbacksResult) UpdateMany(i interface{}, fns ...func(*UpdateOptions)
 	return cr.scope.RecordsAffected, cr.scope.Error
 }
 
+func (cr *callbacksResult) Upsert(i interface{}) (UpsertResult, error) {
+	cr.scope.Action = ActionUpsert
+	cr.scope.UpdateDoc = i
+	cr.cc.client.UpdateProcessors().Execute(cr.cc.NewScope(cr.scope))
+	return cr.scope.UpsertResult, cr.scope.Error
+}
+
+func (cr *callbacksResult) FindOneAndUpdate(i interface{}, dst interface{}, returnNew bool, fns ...func(*QueryOptions)) error {
+	cr.scope.Action = ActionFindOneAndUpdate
+	cr.scope.UpdateDoc = i
+	cr.scope.Dest = dst
+	cr.scope.ReturnNew = returnNew
+	if len(fns) > 0 && fns[0] != nil {
+		cr.scope.QueryOptions = new(QueryOptions)
+		for _, fn := range fns {
+			fn(cr.scope.QueryOptions)
+		}
+	}
+	cr.cc.client.UpdateProcessors().Execute(cr.cc.NewScope(cr.scope))
+	return cr.scope.Error
+}
+
+func (cr *callbacksResult) FindOneAndDelete(dst interface{}, fns ...func(*QueryOptions)) error {
+	cr.scope.Action = ActionFindOneAndDelete
+	cr.scope.Dest = dst
+	if len(fns) > 0 && fns[0] != nil {
+		cr.scope.QueryOptions = new(QueryOptions)
+		for _, fn := range fns {
+			fn(cr.scope.QueryOptions)
+		}
+	}
+	cr.cc.client.DeleteProcessors().Execute(cr.cc.NewScope(cr.scope))
+	return cr.scope.Error
+}
+
 func (cr *callbacksResult) DeleteOne(fns ...func(*DeleteOptions)) (int, error) {
 	cr.scope.Action = ActionDeleteOne
 	if len(fns) > 0 && fns[0] != nil {
@@ -387,6 +509,10 @@ func (cs *clientWrapper) RawProcessors() *processor {
 	return cs.processors["raw"]
 }
 
+func (cs *clientWrapper) BulkProcessors() *processor {
+	return cs.processors["bulk"]
+}
+
 func (p *processor) Execute(s *Scope) {
 	for _, f := range p.fns {
 		f(s)
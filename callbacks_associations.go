@@ -0,0 +1,754 @@
+package db
+
+import (
+	"reflect"
+)
+
+// assocChange 单个引用字段的变更
+type assocChange struct {
+	field     Field
+	assocType string
+	value     reflect.Value
+}
+
+// assocRecord 单条主档案的引用变更
+type assocRecord struct {
+	doc     interface{}   // 已剥离引用字段的主档案
+	parent  reflect.Value // 更新前查询到的主档案，新增时为空
+	changes []*assocChange
+}
+
+// scopeDocuments 剥离引用字段前作用域中的档案
+type scopeDocuments struct {
+	insertOne  interface{}
+	insertMany interface{}
+	update     interface{}
+}
+
+type assocWriter struct {
+	scope        *Scope
+	assocTypeMap map[string]string
+	looseMode    bool
+	deleteAssocs bool
+}
+
+func newAssocWriter(s *Scope) *assocWriter {
+	a := &assocWriter{scope: s}
+	switch s.Action {
+	case ActionInsertOne, ActionInsertMany:
+		if opts := s.InsertOptions; opts != nil {
+			a.assocTypeMap, a.looseMode, a.deleteAssocs = opts.AssocTypeMap, opts.LooseMode, opts.DeleteAssocs
+		}
+	case ActionUpdateOne, ActionUpdateMany, ActionUpsert, ActionFindOneAndUpdate:
+		if opts := s.UpdateOptions; opts != nil {
+			a.assocTypeMap, a.looseMode, a.deleteAssocs = opts.AssocTypeMap, opts.LooseMode, opts.DeleteAssocs
+		}
+	}
+	return a
+}
+
+func (a *assocWriter) model(name string) Collection {
+	return a.scope.txModel(name)
+}
+
+func (a *assocWriter) assocType(name string) (string, error) {
+	v := a.assocTypeMap[name]
+	switch v {
+	case "":
+		return AssocTypeReplace, nil
+	case AssocTypeReplace, AssocTypeMerge, AssocTypeRemove, AssocTypeClear:
+		return v, nil
+	}
+	return "", Errorf("invalid assoc type for field %s: %s", name, v)
+}
+
+// saveBeforeAssociationsCallback 剥离主档案中的引用字段，并在主档案写入前完成REF_ONE引用的保存
+func saveBeforeAssociationsCallback(s *Scope) {
+	if s.HasError() {
+		return
+	}
+	// 写入时使用剥离引用字段后的副本，写入后恢复，后置钩子与前置钩子获取到同一档案
+	s.Store().Store("db:documents", &scopeDocuments{
+		insertOne:  s.InsertOneDoc,
+		insertMany: s.InsertManyDocs,
+		update:     s.UpdateDoc,
+	})
+	var (
+		a       = newAssocWriter(s)
+		meta    = &s.Metadata
+		records []*assocRecord
+	)
+	switch s.Action {
+	case ActionInsertOne:
+		r, err := a.split(meta, s.InsertOneDoc)
+		if err != nil {
+			s.AddError(err)
+			return
+		}
+		if r != nil {
+			s.InsertOneDoc = r.doc
+		}
+		records = append(records, r)
+	case ActionInsertMany:
+		docs := indirectValue(reflect.ValueOf(s.InsertManyDocs))
+		if docs.Kind() != reflect.Slice && docs.Kind() != reflect.Array {
+			return
+		}
+		var (
+			changed bool
+			values  = make([]interface{}, docs.Len())
+		)
+		for i := 0; i < docs.Len(); i++ {
+			values[i] = docs.Index(i).Interface()
+			r, err := a.split(meta, values[i])
+			if err != nil {
+				s.AddError(err)
+				return
+			}
+			if r != nil {
+				values[i] = r.doc
+				changed = true
+			}
+			records = append(records, r)
+		}
+		if changed {
+			s.InsertManyDocs = values
+		}
+	case ActionUpdateOne, ActionUpdateMany, ActionUpsert, ActionFindOneAndUpdate:
+		r, err := a.split(meta, s.UpdateDoc)
+		if err != nil {
+			s.AddError(err)
+			return
+		}
+		if r == nil {
+			return
+		}
+		if s.Action == ActionUpdateMany || s.Action == ActionUpsert {
+			s.AddError(Errorf("association changes are only supported by UpdateOne and FindOneAndUpdate: %s", meta.Name))
+			return
+		}
+		s.UpdateDoc = r.doc
+		parent := make(map[string]interface{})
+		if err := s.buildQueryResult().One(&parent); err != nil {
+			s.AddError(err)
+			return
+		}
+		// 未匹配到主档案时更新不会生效，引用也无需变更
+		if len(parent) == 0 {
+			return
+		}
+		r.parent = reflect.ValueOf(parent)
+		records = append(records, r)
+	default:
+		return
+	}
+
+	for _, r := range records {
+		if r == nil {
+			continue
+		}
+		for _, c := range r.changes {
+			if c.field.Relationship.Type != RelationshipRefOne {
+				continue
+			}
+			if c.assocType == AssocTypeReplace || c.assocType == AssocTypeMerge {
+				if err := a.saveRefOne(meta, r, c); err != nil {
+					s.AddError(err)
+					return
+				}
+			}
+		}
+	}
+	s.Store().Store("db:associations", records)
+}
+
+// saveAssociationsCallback 在主档案写入后恢复调用方传入的档案，再保存HAS_ONE、HAS_MANY引用及REF_MANY中间表记录
+func saveAssociationsCallback(s *Scope) {
+	if v, has := s.Store().Load("db:documents"); has {
+		docs := v.(*scopeDocuments)
+		s.InsertOneDoc, s.InsertManyDocs, s.UpdateDoc = docs.insertOne, docs.insertMany, docs.update
+		s.Store().Delete("db:documents")
+	}
+	if s.HasError() {
+		return
+	}
+	v, has := s.Store().Load("db:associations")
+	if !has {
+		return
+	}
+	var (
+		a    = newAssocWriter(s)
+		meta = &s.Metadata
+	)
+	for i, r := range v.([]*assocRecord) {
+		if r == nil {
+			continue
+		}
+		for _, c := range r.changes {
+			// 新增的主档案不存在历史引用，删除类变更无需处理
+			if !r.parent.IsValid() && (c.assocType == AssocTypeRemove || c.assocType == AssocTypeClear) {
+				continue
+			}
+			var err error
+			switch c.field.Relationship.Type {
+			case RelationshipHasOne, RelationshipHasMany:
+				err = a.saveHas(meta, r, i, c)
+			case RelationshipRefOne:
+				if c.assocType == AssocTypeRemove || c.assocType == AssocTypeClear {
+					err = a.removeRefOne(meta, r, c)
+				}
+			case RelationshipRefMany:
+				err = a.saveRefMany(meta, r, i, c)
+			}
+			if err != nil {
+				s.AddError(err)
+				return
+			}
+		}
+	}
+}
+
+// split 返回剥离引用字段后的档案副本，档案中不包含引用变更时返回nil
+func (a *assocWriter) split(meta *Metadata, doc interface{}) (*assocRecord, error) {
+	// 修改操作符仅作用于主档案字段
+	if _, ok := doc.(*Updater); ok {
+		return nil, nil
+	}
+	rv := indirectValue(reflect.ValueOf(doc))
+	if rv.Kind() != reflect.Struct && rv.Kind() != reflect.Map {
+		return nil, nil
+	}
+	var (
+		r       = &assocRecord{doc: doc}
+		removes []string
+	)
+	for _, field := range meta.Properties {
+		if field.Relationship.Type == "" {
+			continue
+		}
+		value, present := assocFieldValue(rv, &field)
+		assocType, err := a.assocType(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		if present {
+			removes = append(removes, field.Name)
+		} else if assocType != AssocTypeClear {
+			continue
+		}
+		r.changes = append(r.changes, &assocChange{field: field, assocType: assocType, value: value})
+	}
+	if len(r.changes) == 0 {
+		return nil, nil
+	}
+	if len(removes) > 0 {
+		cp, err := copyDocument(rv, meta, nil, removes...)
+		if err != nil {
+			return nil, err
+		}
+		r.doc = cp
+	}
+	return r, nil
+}
+
+// parentValue 读取主档案字段值，新增时主键取自写入结果
+func (a *assocWriter) parentValue(meta *Metadata, r *assocRecord, index int, name string) interface{} {
+	if r.parent.IsValid() {
+		return recordFieldValue(r.parent, meta, name)
+	}
+	v := recordFieldValue(reflect.ValueOf(r.doc), meta, name)
+	if isZeroValue(v) && name == meta.PrimaryFieldName() {
+		v = a.insertedID(index)
+	}
+	return v
+}
+
+func (a *assocWriter) insertedID(index int) interface{} {
+	s := a.scope
+	switch s.Action {
+	case ActionInsertOne:
+		if s.InsertOneResult != nil {
+			return resultID(s.InsertOneResult.StringID(), s.InsertOneResult.IntID())
+		}
+	case ActionInsertMany:
+		if s.InsertManyResult != nil {
+			var (
+				id    string
+				intID int
+			)
+			if ids := s.InsertManyResult.StringIDs(); index < len(ids) {
+				id = ids[index]
+			}
+			if ids := s.InsertManyResult.IntIDs(); index < len(ids) {
+				intID = ids[index]
+			}
+			return resultID(id, intID)
+		}
+	}
+	return nil
+}
+
+func resultID(id string, intID int) interface{} {
+	if id != "" {
+		return id
+	}
+	if intID != 0 {
+		return intID
+	}
+	return nil
+}
+
+// saveChild 保存引用档案：无主键值时新增，有主键值和其他字段值时更新，仅有主键值时校验是否存在
+func (a *assocWriter) saveChild(meta *Metadata, child reflect.Value, values map[string]interface{}) (interface{}, error) {
+	var (
+		pk   = meta.PrimaryFieldName()
+		coll = a.model(meta.Name)
+		id   = recordFieldValue(child, meta, pk)
+	)
+	if !isZeroValue(id) {
+		var (
+			n   int
+			err error
+		)
+		if len(values) > 0 || hasFieldValues(child, meta, pk) {
+			var doc interface{}
+			if doc, err = copyDocument(child, meta, values, pk); err != nil {
+				return nil, err
+			}
+			n, err = coll.Find(Cond{pk: id}).UpdateOne(doc)
+		} else {
+			n, err = coll.Find(Cond{pk: id}).Count()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			writeBackFields(child, values)
+			return id, nil
+		}
+		if !a.looseMode {
+			return nil, Errorf("referenced record does not exist: %s(%v)", meta.Name, id)
+		}
+	}
+
+	doc, err := copyDocument(child, meta, values)
+	if err != nil {
+		return nil, err
+	}
+	res, err := coll.InsertOne(doc)
+	if err != nil {
+		return nil, err
+	}
+	if isZeroValue(id) && res != nil {
+		id = resultID(res.StringID(), res.IntID())
+		writeBackFields(child, map[string]interface{}{pk: id})
+	}
+	writeBackFields(child, values)
+	return id, nil
+}
+
+func (a *assocWriter) saveRefOne(meta *Metadata, r *assocRecord, c *assocChange) error {
+	rel := &c.field.Relationship
+	children := collectPreloadRecords(c.value)
+	if len(children) == 0 {
+		return nil
+	}
+	refMeta, err := LookupMetadata(rel.MetadataName)
+	if err != nil {
+		return err
+	}
+	id, err := a.saveChild(&refMeta, children[0], nil)
+	if err != nil {
+		return err
+	}
+	dst := recordFieldValue(children[0], &refMeta, rel.DstFieldName)
+	if isZeroValue(dst) && rel.DstFieldName == refMeta.PrimaryFieldName() {
+		dst = id
+	}
+	if isZeroValue(dst) {
+		return Errorf("relationship %s.%s requires a value for field %s.%s", meta.Name, c.field.Name, refMeta.Name, rel.DstFieldName)
+	}
+	if a.deleteAssocs && r.parent.IsValid() {
+		if old := recordFieldValue(r.parent, meta, rel.SrcFieldName); !isZeroValue(old) && preloadKey(old) != preloadKey(dst) {
+			if _, err := a.model(refMeta.Name).Find(Cond{rel.DstFieldName: old}).DeleteMany(); err != nil {
+				return err
+			}
+		}
+	}
+	return setDocumentField(meta, r.doc, rel.SrcFieldName, dst)
+}
+
+func (a *assocWriter) removeRefOne(meta *Metadata, r *assocRecord, c *assocChange) error {
+	rel := &c.field.Relationship
+	old := recordFieldValue(r.parent, meta, rel.SrcFieldName)
+	if isZeroValue(old) {
+		return nil
+	}
+	refMeta, err := LookupMetadata(rel.MetadataName)
+	if err != nil {
+		return err
+	}
+	if c.assocType == AssocTypeRemove {
+		var matched bool
+		for _, child := range collectPreloadRecords(c.value) {
+			if preloadKey(recordFieldValue(child, &refMeta, rel.DstFieldName)) == preloadKey(old) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil
+		}
+	}
+	pk := meta.PrimaryFieldName()
+	if _, err := a.model(meta.Name).Find(Cond{pk: recordFieldValue(r.parent, meta, pk)}).UpdateOne(map[string]interface{}{rel.SrcFieldName: nil}); err != nil {
+		return err
+	}
+	if a.deleteAssocs {
+		if _, err := a.model(refMeta.Name).Find(Cond{rel.DstFieldName: old}).DeleteMany(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *assocWriter) saveHas(meta *Metadata, r *assocRecord, index int, c *assocChange) error {
+	rel := &c.field.Relationship
+	refMeta, err := LookupMetadata(rel.MetadataName)
+	if err != nil {
+		return err
+	}
+	src := a.parentValue(meta, r, index, rel.SrcFieldName)
+	if isZeroValue(src) {
+		return Errorf("relationship %s.%s requires a value for field %s.%s", meta.Name, c.field.Name, meta.Name, rel.SrcFieldName)
+	}
+	var (
+		pk       = refMeta.PrimaryFieldName()
+		children = collectPreloadRecords(c.value)
+		cond     = Cond{rel.DstFieldName: src}
+	)
+	switch c.assocType {
+	case AssocTypeReplace, AssocTypeMerge:
+		var ids []interface{}
+		for _, child := range children {
+			id, err := a.saveChild(&refMeta, child, map[string]interface{}{rel.DstFieldName: src})
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if c.assocType == AssocTypeMerge || !r.parent.IsValid() {
+			return nil
+		}
+		if len(ids) > 0 {
+			cond = cond.NotIn(pk, ids)
+		}
+	case AssocTypeRemove:
+		var ids []interface{}
+		for _, child := range children {
+			if id := recordFieldValue(child, &refMeta, pk); !isZeroValue(id) {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		cond = cond.In(pk, ids)
+	}
+
+	res := a.model(refMeta.Name).Find(cond)
+	if a.deleteAssocs {
+		_, err = res.DeleteMany()
+	} else {
+		_, err = res.UpdateMany(map[string]interface{}{rel.DstFieldName: nil})
+	}
+	return err
+}
+
+func (a *assocWriter) saveRefMany(meta *Metadata, r *assocRecord, index int, c *assocChange) error {
+	rel := &c.field.Relationship
+	refMeta, err := LookupMetadata(rel.MetadataName)
+	if err != nil {
+		return err
+	}
+	intMeta, err := LookupMetadata(rel.IntermediateMetadataName)
+	if err != nil {
+		return err
+	}
+	src := a.parentValue(meta, r, index, rel.SrcFieldName)
+	if isZeroValue(src) {
+		return Errorf("relationship %s.%s requires a value for field %s.%s", meta.Name, c.field.Name, meta.Name, rel.SrcFieldName)
+	}
+
+	var dsts []interface{}
+	for _, child := range collectPreloadRecords(c.value) {
+		dst := recordFieldValue(child, &refMeta, rel.DstFieldName)
+		if c.assocType == AssocTypeReplace || c.assocType == AssocTypeMerge {
+			id, err := a.saveChild(&refMeta, child, nil)
+			if err != nil {
+				return err
+			}
+			if isZeroValue(dst) && rel.DstFieldName == refMeta.PrimaryFieldName() {
+				dst = id
+			}
+			if isZeroValue(dst) {
+				return Errorf("relationship %s.%s requires a value for field %s.%s", meta.Name, c.field.Name, refMeta.Name, rel.DstFieldName)
+			}
+		}
+		if !isZeroValue(dst) {
+			dsts = append(dsts, dst)
+		}
+	}
+
+	var (
+		intColl  = a.model(intMeta.Name)
+		existing = make(map[string]interface{})
+	)
+	if r.parent.IsValid() {
+		var rows []map[string]interface{}
+		if err := intColl.Find(Cond{rel.IntermediateSrcFieldName: src}).All(&rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			dst := recordFieldValue(reflect.ValueOf(row), &intMeta, rel.IntermediateDstFieldName)
+			if !isZeroValue(dst) {
+				existing[preloadKey(dst)] = dst
+			}
+		}
+	}
+
+	var removes []interface{}
+	switch c.assocType {
+	case AssocTypeReplace, AssocTypeMerge:
+		keys := make(map[string]bool)
+		for _, dst := range dsts {
+			key := preloadKey(dst)
+			if keys[key] {
+				continue
+			}
+			keys[key] = true
+			if _, has := existing[key]; has {
+				continue
+			}
+			row := map[string]interface{}{
+				rel.IntermediateSrcFieldName: src,
+				rel.IntermediateDstFieldName: dst,
+			}
+			if _, err := intColl.InsertOne(row); err != nil {
+				return err
+			}
+		}
+		if c.assocType == AssocTypeReplace {
+			for key, dst := range existing {
+				if !keys[key] {
+					removes = append(removes, dst)
+				}
+			}
+		}
+	case AssocTypeRemove:
+		for _, dst := range dsts {
+			if _, has := existing[preloadKey(dst)]; has {
+				removes = append(removes, dst)
+			}
+		}
+	case AssocTypeClear:
+		for _, dst := range existing {
+			removes = append(removes, dst)
+		}
+	}
+	if len(removes) == 0 {
+		return nil
+	}
+	if _, err := intColl.Find(Cond{rel.IntermediateSrcFieldName: src}.In(rel.IntermediateDstFieldName, removes)).DeleteMany(); err != nil {
+		return err
+	}
+	if a.deleteAssocs {
+		if _, err := a.model(refMeta.Name).Find(Cond{}.In(rel.DstFieldName, removes)).DeleteMany(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assocFieldValue 读取档案中的引用字段，空值视为未传入
+func assocFieldValue(record reflect.Value, field *Field) (reflect.Value, bool) {
+	var v reflect.Value
+	switch record.Kind() {
+	case reflect.Struct:
+		v = record.FieldByName(field.Name)
+	case reflect.Map:
+		v = record.MapIndex(reflect.ValueOf(field.Name))
+		if !v.IsValid() {
+			v = record.MapIndex(reflect.ValueOf(field.MustNativeName()))
+		}
+		if v.IsValid() && v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+	}
+	if !v.IsValid() || v.IsZero() {
+		return reflect.Value{}, false
+	}
+	return v, true
+}
+
+// metadataFieldName 返回档案键对应的字段名，兼容原始字段名
+func metadataFieldName(meta *Metadata, key string) string {
+	if f, has := meta.FieldByName(key); has {
+		return f.Name
+	}
+	return key
+}
+
+func hasFieldValues(record reflect.Value, meta *Metadata, excludes ...string) bool {
+	skip := func(name string) bool {
+		if f, has := meta.FieldByName(name); has && f.Relationship.Type != "" {
+			return true
+		}
+		for _, item := range excludes {
+			if item == name {
+				return true
+			}
+		}
+		return false
+	}
+	record = indirectValue(record)
+	switch record.Kind() {
+	case reflect.Struct:
+		for i := 0; i < record.NumField(); i++ {
+			sf := record.Type().Field(i)
+			if sf.PkgPath != "" || skip(sf.Name) {
+				continue
+			}
+			if !record.Field(i).IsZero() {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := record.MapRange()
+		for iter.Next() {
+			key, ok := iter.Key().Interface().(string)
+			if !ok || skip(metadataFieldName(meta, key)) {
+				continue
+			}
+			if !IsNil(iter.Value().Interface()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyDocument 复制档案并剔除引用字段及指定字段，再写入指定字段值，不修改调用方传入的档案
+func copyDocument(record reflect.Value, meta *Metadata, values map[string]interface{}, excludes ...string) (interface{}, error) {
+	omit := make(map[string]bool)
+	for _, f := range meta.Properties {
+		if f.Relationship.Type != "" {
+			omit[f.Name] = true
+		}
+	}
+	for _, name := range excludes {
+		omit[name] = true
+	}
+	record = indirectValue(record)
+	switch record.Kind() {
+	case reflect.Struct:
+		cp := reflect.New(record.Type()).Elem()
+		cp.Set(record)
+		for name := range omit {
+			if f := cp.FieldByName(name); f.IsValid() && f.CanSet() {
+				f.Set(reflect.Zero(f.Type()))
+			}
+		}
+		for name, v := range values {
+			f := cp.FieldByName(name)
+			if !f.IsValid() || !f.CanSet() || !assignValue(f, v) {
+				return nil, Errorf("cannot assign %v to field %s.%s", v, meta.Name, name)
+			}
+		}
+		return cp.Addr().Interface(), nil
+	case reflect.Map:
+		cp := make(map[string]interface{})
+		iter := record.MapRange()
+		for iter.Next() {
+			key, ok := iter.Key().Interface().(string)
+			if !ok || omit[metadataFieldName(meta, key)] {
+				continue
+			}
+			cp[key] = iter.Value().Interface()
+		}
+		for name, v := range values {
+			if err := setDocumentField(meta, cp, name, v); err != nil {
+				return nil, err
+			}
+		}
+		return cp, nil
+	}
+	return nil, Errorf("unsupported document type: %s", record.Type())
+}
+
+// setDocumentField 写入由copyDocument返回的档案副本，Map档案写入已存在的键，均不存在时使用原始名称
+func setDocumentField(meta *Metadata, doc interface{}, name string, v interface{}) error {
+	rv := indirectValue(reflect.ValueOf(doc))
+	switch rv.Kind() {
+	case reflect.Struct:
+		f := rv.FieldByName(name)
+		if !f.IsValid() || !f.CanSet() || !assignValue(f, v) {
+			return Errorf("cannot assign %v to field %s", v, name)
+		}
+	case reflect.Map:
+		native := meta.MustFieldNativeName(name)
+		written := false
+		for _, key := range []string{name, native} {
+			if rv.MapIndex(reflect.ValueOf(key)).IsValid() {
+				rv.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(v))
+				written = true
+			}
+		}
+		if !written {
+			rv.SetMapIndex(reflect.ValueOf(native), reflect.ValueOf(v))
+		}
+	}
+	return nil
+}
+
+// writeBackFields 将新增主键及外键值回写到调用方传入的引用档案，无法写入时忽略
+func writeBackFields(record reflect.Value, values map[string]interface{}) {
+	record = indirectValue(record)
+	for name, v := range values {
+		switch record.Kind() {
+		case reflect.Struct:
+			if f := record.FieldByName(name); f.IsValid() && f.CanSet() {
+				assignValue(f, v)
+			}
+		case reflect.Map:
+			if vv := reflect.ValueOf(v); vv.IsValid() && vv.Type().AssignableTo(record.Type().Elem()) {
+				record.SetMapIndex(reflect.ValueOf(name), vv)
+			}
+		}
+	}
+}
+
+func assignValue(dst reflect.Value, v interface{}) bool {
+	if IsNil(v) {
+		dst.Set(reflect.Zero(dst.Type()))
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	if dst.Kind() == reflect.Ptr && rv.Kind() != reflect.Ptr {
+		ptr := reflect.New(dst.Type().Elem())
+		if !assignValue(ptr.Elem(), v) {
+			return false
+		}
+		dst.Set(ptr)
+		return true
+	}
+	switch {
+	case rv.Type().AssignableTo(dst.Type()):
+		dst.Set(rv)
+	case rv.Type().ConvertibleTo(dst.Type()) && (rv.Kind() == reflect.String) == (dst.Kind() == reflect.String):
+		dst.Set(rv.Convert(dst.Type()))
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitAssociations(t *testing.T) {
+	var conn Connection
+	if err := conn.RegisterMetadata(&relUser{}, &relIDCard{}, &relBankCard{}, &relCompany{}, &relProject{}, &relUserProject{}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, name := range []string{"relUser", "relIDCard", "relBankCard", "relCompany", "relProject", "relUserProject"} {
+			UnregisterMetadata(name)
+		}
+	}()
+	meta, err := LookupMetadata("relUser")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &assocWriter{assocTypeMap: map[string]string{"Projects": AssocTypeClear}}
+	doc := &relUser{RealName: "Foo", IDCard: &relIDCard{CardNum: "440106"}, BankCards: []relBankCard{{ID: "1"}}}
+	r, err := a.split(&meta, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stripped := r.doc.(*relUser)
+	if stripped == doc || stripped.RealName != "Foo" || stripped.IDCard != nil || stripped.BankCards != nil {
+		t.Errorf("split() doc = %+v", stripped)
+	}
+	if doc.IDCard == nil || len(doc.BankCards) != 1 {
+		t.Errorf("split() modified the original document: %+v", doc)
+	}
+	got := make(map[string]string)
+	for _, c := range r.changes {
+		got[c.field.Name] = c.assocType
+	}
+	want := map[string]string{"IDCard": AssocTypeReplace, "BankCards": AssocTypeReplace, "Projects": AssocTypeClear}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("split() changes = %v, want %v", got, want)
+	}
+
+	m := map[string]interface{}{"RealName": "Foo", "company": map[string]interface{}{"ID": "c1"}}
+	if r, err = (&assocWriter{}).split(&meta, m); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"RealName": "Foo"}; !reflect.DeepEqual(r.doc, want) || len(r.changes) != 1 {
+		t.Errorf("split() doc = %v, changes = %d", r.doc, len(r.changes))
+	}
+	if _, has := m["company"]; !has {
+		t.Errorf("split() modified the original document: %v", m)
+	}
+
+	// Map档案写入已存在的键，均不存在时使用原始名称
+	for _, tt := range []struct {
+		doc  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{doc: map[string]interface{}{"company_id": "c0"}, want: map[string]interface{}{"company_id": "c1"}},
+		{doc: map[string]interface{}{"CompanyID": "c0"}, want: map[string]interface{}{"CompanyID": "c1"}},
+		{doc: map[string]interface{}{}, want: map[string]interface{}{"company_id": "c1"}},
+	} {
+		if err := setDocumentField(&meta, tt.doc, "CompanyID", "c1"); err != nil || !reflect.DeepEqual(tt.doc, tt.want) {
+			t.Errorf("setDocumentField() = %v, %v, want %v", tt.doc, err, tt.want)
+		}
+	}
+
+	// 复制Map档案时同样写入已存在的键，均不存在时使用原始名称
+	for _, tt := range []struct {
+		doc  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{doc: map[string]interface{}{"company_id": "c0"}, want: map[string]interface{}{"company_id": "c1"}},
+		{doc: map[string]interface{}{"CompanyID": "c0"}, want: map[string]interface{}{"CompanyID": "c1"}},
+		{doc: map[string]interface{}{}, want: map[string]interface{}{"company_id": "c1"}},
+	} {
+		cp, err := copyDocument(reflect.ValueOf(tt.doc), &meta, map[string]interface{}{"CompanyID": "c1"})
+		if err != nil || !reflect.DeepEqual(cp, tt.want) {
+			t.Errorf("copyDocument() = %v, %v, want %v", cp, err, tt.want)
+		}
+	}
+
+	if r, err = (&assocWriter{}).split(&meta, &relUser{RealName: "Foo"}); err != nil || r != nil {
+		t.Errorf("split() = %v, %v, want nil", r, err)
+	}
+	if _, err = (&assocWriter{assocTypeMap: map[string]string{"IDCard": "ASSOC_UNKNOWN"}}).split(&meta, doc); err == nil {
+		t.Errorf("split() should fail with an invalid assoc type")
+	}
+}
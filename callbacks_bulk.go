@@ -0,0 +1,249 @@
+package db
+
+import "errors"
+
+func registerBulkCallbacks(callbacks *clientWrapper) *clientWrapper {
+	processor := callbacks.BulkProcessors()
+	processor.Register("db:before_bulk_write", beforeBulkWriteCallback)
+	processor.Register("db:bulk_write", bulkWriteCallback)
+	processor.Register("db:after_bulk_write", afterBulkWriteCallback)
+	return callbacks
+}
+
+// bulkState 批量写入中各操作的作用域及错误
+type bulkState struct {
+	indexes []int          // 交由适配器执行的操作下标
+	scopes  map[int]*Scope // 执行成功的操作的作用域，以操作下标为键
+	errs    []WriteError
+}
+
+func (s *Scope) bulkState() *bulkState {
+	v, _ := s.Store().LoadOrStore("db:bulk", &bulkState{scopes: make(map[int]*Scope)})
+	return v.(*bulkState)
+}
+
+// opScope 返回批量写入中单个操作的作用域，钩子按单条写入的方式处理该作用域
+func (s *Scope) opScope(op WriteOp) *Scope {
+	scope := &Scope{
+		callbacks: s.callbacks,
+		Unscoped:  op.Unscoped,
+		Context:   s.Context,
+		Coll:      s.Coll,
+		StartTime: s.StartTime,
+		Session:   s.Session,
+		Metadata:  s.Metadata,
+		Action:    op.Action,
+	}
+	scope.AddCondition(op.Conditions...)
+	switch op.Action {
+	case ActionInsertOne:
+		scope.InsertOneDoc = op.Doc
+	case ActionUpdateOne, ActionUpdateMany:
+		scope.UpdateDoc = op.Doc
+	}
+	return scope
+}
+
+// writeOp 返回交由适配器执行的操作，修改及删除时附加逻辑删除规则
+func (s *Scope) writeOp() WriteOp {
+	op := WriteOp{Action: s.Action, Conditions: s.Conditions, Unscoped: s.Unscoped}
+	switch s.Action {
+	case ActionInsertOne:
+		op.Doc = s.InsertOneDoc
+		return op
+	case ActionUpdateOne, ActionUpdateMany:
+		op.Doc = s.UpdateDoc
+	case ActionDeleteOne, ActionDeleteMany:
+		if doc := logicDeleteDoc(s.Context, s.Metadata.Name); doc != nil && !s.Unscoped {
+			op.Doc = doc
+			if s.Action == ActionDeleteOne {
+				op.Action = ActionUpdateOne
+			} else {
+				op.Action = ActionUpdateMany
+			}
+		}
+	}
+	if !s.Unscoped {
+		if rule := LookupLogicDeleteRule(s.Metadata.Name); rule != nil && rule.GetValue != nil {
+			op.Conditions = append(op.Conditions[:len(op.Conditions):len(op.Conditions)], rule.GetValue)
+		}
+	}
+	return op
+}
+
+func beforeBulkWriteCallback(s *Scope) {
+	state := s.bulkState()
+	for i, op := range s.WriteOps {
+		scope := s.opScope(op)
+		switch op.Action {
+		case ActionInsertOne:
+			scope.callHooks(HookBeforeSave, s.Metadata.Name)
+			scope.callHooks(HookBeforeCreate, s.Metadata.Name)
+			timestampsCallback(scope)
+			versionCallback(scope)
+			defaultValueCallback(scope)
+			validateCallback(scope)
+		case ActionUpdateOne, ActionUpdateMany:
+			scope.callHooks(HookBeforeSave, s.Metadata.Name)
+			scope.callHooks(HookBeforeUpdate, s.Metadata.Name)
+			timestampsCallback(scope)
+			versionCallback(scope)
+			validateCallback(scope)
+			incrementVersionCallback(scope)
+		case ActionDeleteOne, ActionDeleteMany:
+			scope.callHooks(HookBeforeDelete, s.Metadata.Name)
+		default:
+			scope.AddError(Errorf(`unsupported write operation: %s`, op.Action))
+		}
+		if scope.HasError() {
+			state.errs = append(state.errs, WriteError{Index: i, Err: scope.Error})
+			if s.Ordered {
+				break
+			}
+			continue
+		}
+		state.indexes = append(state.indexes, i)
+		state.scopes[i] = scope
+	}
+}
+
+func bulkWriteCallback(s *Scope) {
+	if s.HasError() {
+		return
+	}
+	state := s.bulkState()
+	s.BulkWriteResult = BulkWriteResult{Inserted: make(map[int]InsertOneResult)}
+	for _, batch := range state.batches() {
+		if !s.bulkWriteBatch(state, batch) && s.Ordered {
+			return
+		}
+	}
+}
+
+// batches 将交由适配器执行的操作分批，需检查版本号的修改操作单独成批，以便按匹配的记录数判断版本号是否匹配；
+// 需处理关系删除规则的删除操作同样单独成批，以便在执行前按之前操作的结果处理引用数据
+func (state *bulkState) batches() [][]int {
+	var (
+		batches [][]int
+		batch   []int
+	)
+	for _, i := range state.indexes {
+		if scope := state.scopes[i]; scope.checkedVersion() == nil && !scope.hasDeleteRules() {
+			batch = append(batch, i)
+			continue
+		}
+		if len(batch) > 0 {
+			batches = append(batches, batch)
+			batch = nil
+		}
+		batches = append(batches, []int{i})
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// hasDeleteRules 删除操作的档案存在关系删除规则时返回true
+func (s *Scope) hasDeleteRules() bool {
+	if s.Action != ActionDeleteOne && s.Action != ActionDeleteMany {
+		return false
+	}
+	return (&assocDeleter{scope: s}).hasActions(&s.Metadata, true)
+}
+
+// bulkWriteBatch 执行一批操作，存在失败的操作时返回false
+func (s *Scope) bulkWriteBatch(state *bulkState, indexes []int) bool {
+	if scope := state.scopes[indexes[0]]; scope.hasDeleteRules() {
+		// 与单条删除一致，先按删除规则处理引用数据，RESTRICT校验失败时不删除
+		deleteAssociationsCallback(scope)
+		if scope.HasError() {
+			return state.fail(s.Ordered, []WriteError{{Index: indexes[0], Err: scope.Error}})
+		}
+	}
+	ops := make([]WriteOp, 0, len(indexes))
+	for _, i := range indexes {
+		ops = append(ops, state.scopes[i].writeOp())
+	}
+	res, err := s.collection().BulkWrite(ops, s.Ordered)
+	var bwe *BulkWriteError
+	if err != nil && !errors.As(err, &bwe) {
+		s.AddError(err)
+		return false
+	}
+	// 适配器结果中的下标对应本批实际执行的操作，需转换为原始操作的下标
+	s.BulkWriteResult.InsertedCount += res.InsertedCount
+	s.BulkWriteResult.UpdatedCount += res.UpdatedCount
+	s.BulkWriteResult.DeletedCount += res.DeletedCount
+	for j, v := range res.Inserted {
+		s.BulkWriteResult.Inserted[indexes[j]] = v
+	}
+	var errs []WriteError
+	if bwe != nil {
+		for _, we := range bwe.Errors {
+			errs = append(errs, WriteError{Index: indexes[we.Index], Err: we.Err})
+		}
+	} else if v := state.scopes[indexes[0]].checkedVersion(); v != nil && res.UpdatedCount == 0 {
+		errs = append(errs, WriteError{Index: indexes[0], Err: staleObjectError(&s.Metadata, v)})
+	}
+	if len(errs) == 0 {
+		return true
+	}
+	return state.fail(s.Ordered, errs)
+}
+
+// fail 记录失败的操作并返回false，有序写入时丢弃首个失败之后的操作
+func (state *bulkState) fail(ordered bool, errs []WriteError) bool {
+	if ordered {
+		// 有序写入在首个失败处停止，之后的操作均未执行
+		first := errs[0].Index
+		var kept []WriteError
+		for _, we := range state.errs {
+			if we.Index < first {
+				kept = append(kept, we)
+			}
+		}
+		state.errs = kept
+		for _, i := range state.indexes {
+			if i >= first {
+				delete(state.scopes, i)
+			}
+		}
+	}
+	for _, we := range errs {
+		state.errs = append(state.errs, we)
+		delete(state.scopes, we.Index)
+	}
+	return false
+}
+
+func afterBulkWriteCallback(s *Scope) {
+	if s.HasError() {
+		return
+	}
+	state := s.bulkState()
+	for _, i := range state.indexes {
+		scope, has := state.scopes[i]
+		if !has {
+			continue
+		}
+		switch scope.Action {
+		case ActionInsertOne:
+			scope.InsertOneResult = s.BulkWriteResult.Inserted[i]
+			scope.callHooks(HookAfterCreate, s.Metadata.Name)
+			scope.callHooks(HookAfterSave, s.Metadata.Name)
+		case ActionUpdateOne, ActionUpdateMany:
+			if v := scope.checkedVersion(); v != nil {
+				v.writeBack()
+			}
+			scope.callHooks(HookAfterUpdate, s.Metadata.Name)
+			scope.callHooks(HookAfterSave, s.Metadata.Name)
+		case ActionDeleteOne, ActionDeleteMany:
+			scope.callHooks(HookAfterDelete, s.Metadata.Name)
+		}
+		if scope.HasError() {
+			state.errs = append(state.errs, WriteError{Index: i, Err: scope.Error})
+		}
+	}
+	s.AddError(newBulkWriteError(state.errs))
+}
@@ -0,0 +1,258 @@
+package db
+
+import (
+	"reflect"
+)
+
+type assocDeleter struct {
+	scope   *Scope
+	visited map[string]bool
+}
+
+type assocDeleteStep struct {
+	field  Field
+	action string
+	values []interface{}
+}
+
+// deleteAssociationsCallback 在删除主档案前按关系的删除规则处理引用数据
+func deleteAssociationsCallback(s *Scope) {
+	if s.HasError() {
+		return
+	}
+	if opts := s.DeleteOptions; opts != nil {
+		for name, v := range opts.AssocTypeMap {
+			if v != AssocTypeClear {
+				s.AddError(Errorf("unsupported assoc type for delete %s.%s: %s", s.Metadata.Name, name, v))
+				return
+			}
+		}
+	}
+	d := &assocDeleter{scope: s, visited: make(map[string]bool)}
+	if !d.hasActions(&s.Metadata, true) {
+		return
+	}
+
+	var (
+		meta    = &s.Metadata
+		res     = s.buildQueryResult()
+		records []map[string]interface{}
+	)
+	switch s.Action {
+	case ActionDeleteOne, ActionFindOneAndDelete:
+		record := make(map[string]interface{})
+		if err := res.One(&record); err != nil {
+			s.AddError(err)
+			return
+		}
+		if len(record) == 0 {
+			return
+		}
+		records = append(records, record)
+		// 固定待删除的主档案，保证删除的记录与处理引用的记录一致
+		pk := meta.PrimaryFieldName()
+		if id := recordFieldValue(reflect.ValueOf(record), meta, pk); !isZeroValue(id) {
+			s.AddCondition(Cond{pk: id})
+		}
+	case ActionDeleteMany:
+		if err := res.All(&records); err != nil {
+			s.AddError(err)
+			return
+		}
+	default:
+		return
+	}
+	for _, record := range records {
+		d.visited[meta.Name+":"+preloadKey(record)] = true
+	}
+	if err := d.deleteAssociations(meta, records, true); err != nil {
+		s.AddError(err)
+	}
+}
+
+// action 返回关系的删除规则，删除参数仅对主档案的关系生效且不能绕过RESTRICT
+func (d *assocDeleter) action(field *Field, root bool) string {
+	var (
+		rel    = &field.Relationship
+		action = rel.OnDelete
+		opts   = d.scope.DeleteOptions
+	)
+	if !root || opts == nil || rel.Type == RelationshipRefOne || action == OnDeleteRestrict {
+		return action
+	}
+	if opts.AssocTypeMap[field.Name] == AssocTypeClear {
+		switch {
+		case opts.DeleteAssocs:
+			return OnDeleteCascade
+		case rel.Type == RelationshipRefMany:
+			return OnDeleteUnlink
+		default:
+			return OnDeleteSetNull
+		}
+	}
+	if action == "" && opts.DeleteAssocs {
+		return OnDeleteCascade
+	}
+	return action
+}
+
+func (d *assocDeleter) hasActions(meta *Metadata, root bool) bool {
+	for _, field := range meta.Properties {
+		if field.Relationship.Type != "" && d.action(&field, root) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *assocDeleter) deleteAssociations(meta *Metadata, records []map[string]interface{}, root bool) error {
+	var steps []*assocDeleteStep
+	for _, field := range meta.Properties {
+		if field.Relationship.Type == "" {
+			continue
+		}
+		action := d.action(&field, root)
+		if action == "" {
+			continue
+		}
+		var (
+			values []interface{}
+			exists = make(map[string]bool)
+		)
+		for _, record := range records {
+			v := recordFieldValue(reflect.ValueOf(record), meta, field.Relationship.SrcFieldName)
+			if key := preloadKey(v); !isZeroValue(v) && !exists[key] {
+				exists[key] = true
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			steps = append(steps, &assocDeleteStep{field: field, action: action, values: values})
+		}
+	}
+
+	// 先校验所有RESTRICT规则，避免部分引用已被处理后才发现不允许删除
+	for _, step := range steps {
+		if step.action == OnDeleteRestrict {
+			if err := d.restrict(meta, step); err != nil {
+				return err
+			}
+		}
+	}
+	for _, step := range steps {
+		var err error
+		switch step.action {
+		case OnDeleteCascade:
+			err = d.cascade(step)
+		case OnDeleteSetNull:
+			rel := &step.field.Relationship
+			_, err = d.find(rel.MetadataName, Cond{}.In(rel.DstFieldName, step.values)).UpdateMany(map[string]interface{}{rel.DstFieldName: nil})
+		case OnDeleteUnlink:
+			rel := &step.field.Relationship
+			err = d.remove(rel.IntermediateMetadataName, Cond{}.In(rel.IntermediateSrcFieldName, step.values))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *assocDeleter) restrict(meta *Metadata, step *assocDeleteStep) error {
+	var (
+		rel  = &step.field.Relationship
+		name = rel.MetadataName
+		key  = rel.DstFieldName
+	)
+	if rel.Type == RelationshipRefMany {
+		name, key = rel.IntermediateMetadataName, rel.IntermediateSrcFieldName
+	}
+	n, err := d.find(name, Cond{}.In(key, step.values)).Count()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return Errorf("cannot delete %s: %d %s record(s) still reference it through %s", meta.Name, n, name, step.field.Name)
+	}
+	return nil
+}
+
+func (d *assocDeleter) cascade(step *assocDeleteStep) error {
+	rel := &step.field.Relationship
+	refMeta, err := LookupMetadata(rel.MetadataName)
+	if err != nil {
+		return err
+	}
+	if rel.Type != RelationshipRefMany {
+		return d.deleteWhere(&refMeta, Cond{}.In(rel.DstFieldName, step.values))
+	}
+
+	intMeta, err := LookupMetadata(rel.IntermediateMetadataName)
+	if err != nil {
+		return err
+	}
+	var rows []map[string]interface{}
+	cond := Cond{}.In(rel.IntermediateSrcFieldName, step.values)
+	if err := d.find(intMeta.Name, cond).All(&rows); err != nil {
+		return err
+	}
+	var dstValues []interface{}
+	for _, row := range rows {
+		if v := recordFieldValue(reflect.ValueOf(row), &intMeta, rel.IntermediateDstFieldName); !isZeroValue(v) {
+			dstValues = append(dstValues, v)
+		}
+	}
+	if err := d.remove(intMeta.Name, cond); err != nil {
+		return err
+	}
+	if len(dstValues) == 0 {
+		return nil
+	}
+	return d.deleteWhere(&refMeta, Cond{}.In(rel.DstFieldName, dstValues))
+}
+
+// deleteWhere 删除满足条件的档案，并递归处理档案自身关系的删除规则
+func (d *assocDeleter) deleteWhere(meta *Metadata, cond Cond) error {
+	if d.hasActions(meta, false) {
+		var records []map[string]interface{}
+		if err := d.find(meta.Name, cond).All(&records); err != nil {
+			return err
+		}
+		var fresh []map[string]interface{}
+		for _, record := range records {
+			// 记录已在本次删除中处理过时跳过，避免循环引用导致无限递归
+			if key := meta.Name + ":" + preloadKey(record); !d.visited[key] {
+				d.visited[key] = true
+				fresh = append(fresh, record)
+			}
+		}
+		if err := d.deleteAssociations(meta, fresh, false); err != nil {
+			return err
+		}
+	}
+	return d.remove(meta.Name, cond)
+}
+
+// remove 删除满足条件的档案，存在逻辑删除规则时执行逻辑删除
+func (d *assocDeleter) remove(name string, cond Cond) error {
+	res := d.find(name, cond)
+	if !d.scope.Unscoped {
+		if doc := logicDeleteDoc(d.scope.Context, name); doc != nil {
+			_, err := res.UpdateMany(doc)
+			return err
+		}
+	}
+	_, err := res.DeleteMany()
+	return err
+}
+
+// find 在当前事务中查询，未指定Unscoped时排除已逻辑删除的档案
+func (d *assocDeleter) find(name string, cond Cond) Result {
+	args := []interface{}{cond}
+	if !d.scope.Unscoped {
+		if rule := LookupLogicDeleteRule(name); rule != nil && rule.GetValue != nil {
+			args = append(args, rule.GetValue)
+		}
+	}
+	return d.scope.txModel(name).Find(args...)
+}
@@ -4,7 +4,13 @@ func registerCreateCallbacks(callbacks *clientWrapper) *clientWrapper {
 	processor := callbacks.CreateProcessors()
 	processor.Register("db:begin_transaction", beginTransactionCallback)
 	processor.Register("db:before_create", beforeCreateCallback)
+	processor.Register("db:timestamps", timestampsCallback)
+	processor.Register("db:version", versionCallback)
+	processor.Register("db:default_value", defaultValueCallback)
+	processor.Register("db:validate", validateCallback)
+	processor.Register("db:save_before_associations", saveBeforeAssociationsCallback)
 	processor.Register("db:create", createCallback)
+	processor.Register("db:save_associations", saveAssociationsCallback)
 	processor.Register("db:after_create", afterCreateCallback)
 	processor.Register("db:commit_or_rollback_transaction", commitOrRollbackTransactionCallback)
 	return callbacks
@@ -22,9 +28,9 @@ func createCallback(s *Scope) {
 
 	switch s.Action {
 	case ActionInsertOne:
-		s.InsertOneResult, s.Error = s.Coll.InsertOne(s.InsertOneDoc)
+		s.InsertOneResult, s.Error = s.collection().InsertOne(s.InsertOneDoc)
 	case ActionInsertMany:
-		s.InsertManyResult, s.Error = s.Coll.InsertMany(s.InsertManyDocs)
+		s.InsertManyResult, s.Error = s.collection().InsertMany(s.InsertManyDocs)
 	}
 }
 
@@ -1,9 +1,12 @@
 package db
 
+import "context"
+
 func registerDeleteCallbacks(callbacks *clientWrapper) *clientWrapper {
 	processor := callbacks.DeleteProcessors()
 	processor.Register("db:begin_transaction", beginTransactionCallback)
 	processor.Register("db:before_delete", beforeDeleteCallback)
+	processor.Register("db:delete_associations", deleteAssociationsCallback)
 	processor.Register("db:logic_delete", logicDeleteCallback)
 	processor.Register("db:delete", deleteCallback)
 	processor.Register("db:after_delete", afterDeleteCallback)
@@ -16,19 +19,35 @@ func logicDeleteCallback(s *Scope) {
 		return
 	}
 
-	rule := LookupLogicDeleteRule(s.Metadata.Name)
-	if rule == nil {
-		return
+	if doc := logicDeleteDoc(s.Context, s.Metadata.Name); doc != nil {
+		s.UpdateDoc = doc
 	}
+}
 
-	if values := rule.ParseSetValue(); values != nil {
-		var doc = make(map[string]interface{})
-		for key, val := range values {
-			key = s.Metadata.MustFieldNativeName(key)
-			doc[key] = val
+// logicDeleteDoc 返回元数据逻辑删除时的更新内容，存在时间戳规则时一并写入修改时间及操作人，未注册规则时返回nil
+func logicDeleteDoc(ctx context.Context, name string) map[string]interface{} {
+	rule := LookupLogicDeleteRule(name)
+	if rule == nil {
+		return nil
+	}
+	values := rule.ParseSetValue()
+	if values == nil {
+		return nil
+	}
+	meta, _ := LookupMetadata(name)
+	doc := make(map[string]interface{})
+	for key, val := range values {
+		key = meta.MustFieldNativeName(key)
+		doc[key] = val
+	}
+	if rule := LookupTimestampsRule(name); rule != nil {
+		for key, val := range rule.values(&meta, ctx, false) {
+			if key = meta.MustFieldNativeName(key); doc[key] == nil {
+				doc[key] = val
+			}
 		}
-		s.UpdateDoc = doc
 	}
+	return doc
 }
 
 func beforeDeleteCallback(s *Scope) {
@@ -53,6 +72,13 @@ func deleteCallback(s *Scope) {
 		} else {
 			s.RecordsAffected, s.Error = res.DeleteMany()
 		}
+	case ActionFindOneAndDelete:
+		// 逻辑删除时返回删除前的记录
+		if s.UpdateDoc != nil {
+			s.Error = res.FindOneAndUpdate(s.UpdateDoc, s.Dest, false, s.queryOptions()...)
+		} else {
+			s.Error = res.FindOneAndDelete(s.Dest, s.queryOptions()...)
+		}
 	}
 }
 
@@ -0,0 +1,446 @@
+package db
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type preloadNode struct {
+	name     string
+	opts     *PreloadOptions
+	children []*preloadNode
+}
+
+func (n *preloadNode) child(name string) *preloadNode {
+	for _, item := range n.children {
+		if item.name == name {
+			return item
+		}
+	}
+	item := &preloadNode{name: name}
+	n.children = append(n.children, item)
+	return item
+}
+
+// parsePreloadTree 将多级路径（如Projects.Owner.Company）解析为树形结构，未显式指定参数的中间层级使用默认参数
+func parsePreloadTree(preloads map[string][]func(options *PreloadOptions)) *preloadNode {
+	var paths []string
+	for path := range preloads {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	root := &preloadNode{}
+	for _, path := range paths {
+		var (
+			node     = root
+			segments = strings.Split(path, ".")
+		)
+		for i, name := range segments {
+			node = node.child(strings.TrimSpace(name))
+			if node.opts == nil {
+				node.opts = &PreloadOptions{Path: strings.Join(segments[:i+1], ".")}
+			}
+		}
+		for _, fn := range preloads[path] {
+			if fn != nil {
+				fn(node.opts)
+			}
+		}
+		node.opts.Path = path
+	}
+	return root
+}
+
+func preloadCallback(s *Scope) {
+	if s.HasError() || len(s.Preloads) == 0 || IsNil(s.Dest) {
+		return
+	}
+	if s.Action != ActionQueryOne && s.Action != ActionQueryAll && s.Action != ActionQueryScroll {
+		return
+	}
+	// 分组结果不对应具体记录
+	if s.grouped() {
+		return
+	}
+
+	root := parsePreloadTree(s.Preloads)
+	records := collectPreloadRecords(reflect.ValueOf(s.Dest))
+	if err := preloadNodes(records, &s.Metadata, root.children, s.model); err != nil {
+		s.AddError(err)
+	}
+}
+
+// preloadNodes 逐层批量加载引用数据，下一层级以本层所有记录加载出的引用数据作为输入
+func preloadNodes(records []reflect.Value, meta *Metadata, nodes []*preloadNode, model func(string) Collection) error {
+	if len(records) == 0 {
+		return nil
+	}
+	for _, node := range nodes {
+		var field Field
+		if f, has := meta.FieldByName(node.name); has {
+			field = f
+		} else {
+			return Errorf("preload field does not exist: %s", node.opts.Path)
+		}
+		if field.Relationship.Type == "" {
+			return Errorf("undefined relationship: %s", node.opts.Path)
+		}
+		if len(node.children) == 0 {
+			if err := execPreload(records, meta, &field, node.opts, model); err != nil {
+				return err
+			}
+			continue
+		}
+
+		refMeta, err := LookupMetadata(field.Relationship.MetadataName)
+		if err != nil {
+			return err
+		}
+		var required []string
+		for _, child := range node.children {
+			if f, has := refMeta.FieldByName(child.name); has && f.Relationship.SrcFieldName != "" {
+				required = append(required, f.Relationship.SrcFieldName)
+			}
+		}
+		if err := execPreload(records, meta, &field, node.opts, model, required...); err != nil {
+			return err
+		}
+		var children []reflect.Value
+		for _, record := range records {
+			children = append(children, collectPreloadRecords(preloadedValue(record, &field))...)
+		}
+		if err := preloadNodes(children, &refMeta, node.children, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preloadedValue 返回记录中已回填的引用字段值
+func preloadedValue(record reflect.Value, field *Field) reflect.Value {
+	switch record.Kind() {
+	case reflect.Struct:
+		return record.FieldByName(field.Name)
+	case reflect.Map:
+		return record.MapIndex(reflect.ValueOf(field.MustNativeName()))
+	}
+	return reflect.Value{}
+}
+
+// collectPreloadRecords 展开指针、接口和数组，返回所有结构体或Map记录
+func collectPreloadRecords(v reflect.Value) (records []reflect.Value) {
+	v = indirectValue(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		records = append(records, v)
+	case reflect.Map:
+		if !v.IsNil() {
+			records = append(records, v)
+		}
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			records = append(records, collectPreloadRecords(v.Index(i))...)
+		}
+	}
+	return
+}
+
+// execPreload 按关系类型批量查询所有记录的引用数据（每个关系仅发起一次$in查询），再在内存中回填到各记录
+func execPreload(records []reflect.Value, meta *Metadata, field *Field, opts *PreloadOptions, model func(string) Collection, required ...string) error {
+	relationship := &field.Relationship
+	refMeta, err := LookupMetadata(relationship.MetadataName)
+	if err != nil {
+		return err
+	}
+
+	var (
+		srcKeys   = make([]string, len(records))
+		srcValues []interface{}
+		srcExists = make(map[string]bool)
+	)
+	for i, record := range records {
+		v := recordFieldValue(record, meta, relationship.SrcFieldName)
+		if isZeroValue(v) {
+			continue
+		}
+		key := preloadKey(v)
+		srcKeys[i] = key
+		if !srcExists[key] {
+			srcExists[key] = true
+			srcValues = append(srcValues, v)
+		}
+	}
+	if len(srcValues) == 0 {
+		return nil
+	}
+
+	elemType, err := preloadElemType(records[0], field)
+	if err != nil {
+		return err
+	}
+	refModel := model(relationship.MetadataName)
+	targetValue := reflect.New(reflect.SliceOf(elemType))
+	groups := make(map[string][]reflect.Value)
+	switch relationship.Type {
+	case RelationshipHasOne, RelationshipHasMany, RelationshipRefOne:
+		res := opts.setBatchResult(refModel.Find(Cond{}.In(relationship.DstFieldName, srcValues)), append(required, relationship.DstFieldName)...)
+		if err := res.All(targetValue.Interface()); err != nil {
+			return err
+		}
+		targets := targetValue.Elem()
+		for i := 0; i < targets.Len(); i++ {
+			item := targets.Index(i)
+			key := preloadKey(recordFieldValue(item, &refMeta, relationship.DstFieldName))
+			groups[key] = append(groups[key], item)
+		}
+	case RelationshipRefMany:
+		intMeta, err := LookupMetadata(relationship.IntermediateMetadataName)
+		if err != nil {
+			return err
+		}
+		intModel := model(relationship.IntermediateMetadataName)
+		var intData []map[string]interface{}
+		if err := intModel.Find(Cond{}.In(relationship.IntermediateSrcFieldName, srcValues)).All(&intData); err != nil {
+			return err
+		}
+		var (
+			dstValues []interface{}
+			dstExists = make(map[string]bool)
+			links     = make(map[string]map[string]bool)
+		)
+		for _, item := range intData {
+			itemValue := reflect.ValueOf(item)
+			srcValue := recordFieldValue(itemValue, &intMeta, relationship.IntermediateSrcFieldName)
+			dstValue := recordFieldValue(itemValue, &intMeta, relationship.IntermediateDstFieldName)
+			if isZeroValue(srcValue) || isZeroValue(dstValue) {
+				continue
+			}
+			srcKey, dstKey := preloadKey(srcValue), preloadKey(dstValue)
+			if links[srcKey] == nil {
+				links[srcKey] = make(map[string]bool)
+			}
+			links[srcKey][dstKey] = true
+			if !dstExists[dstKey] {
+				dstExists[dstKey] = true
+				dstValues = append(dstValues, dstValue)
+			}
+		}
+		if len(dstValues) == 0 {
+			return nil
+		}
+		res := opts.setBatchResult(refModel.Find(Cond{}.In(relationship.DstFieldName, dstValues)), append(required, relationship.DstFieldName)...)
+		if err := res.All(targetValue.Interface()); err != nil {
+			return err
+		}
+		targets := targetValue.Elem()
+		for i := 0; i < targets.Len(); i++ {
+			item := targets.Index(i)
+			dstKey := preloadKey(recordFieldValue(item, &refMeta, relationship.DstFieldName))
+			// 按引用结果的顺序分组，保证排序参数对每条记录生效
+			for srcKey, dstKeys := range links {
+				if dstKeys[dstKey] {
+					groups[srcKey] = append(groups[srcKey], item)
+				}
+			}
+		}
+	default:
+		return Errorf("unsupported relationship type: %s", relationship.Type)
+	}
+
+	many := relationship.Type == RelationshipHasMany || relationship.Type == RelationshipRefMany
+	for i, record := range records {
+		if srcKeys[i] == "" {
+			continue
+		}
+		group := groups[srcKeys[i]]
+		if many {
+			group = opts.paginate(group)
+		}
+		if err := setPreloadValue(record, field, elemType, group, many); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preloadElemType 返回引用查询结果的元素类型，Map记录统一使用map[string]interface{}
+func preloadElemType(record reflect.Value, field *Field) (reflect.Type, error) {
+	many := field.Relationship.Type == RelationshipHasMany || field.Relationship.Type == RelationshipRefMany
+	if record.Kind() == reflect.Map {
+		return reflect.TypeOf(map[string]interface{}{}), nil
+	}
+	sf, ok := record.Type().FieldByName(field.Name)
+	if !ok {
+		return nil, Errorf("preload field does not exist: %s", field.Name)
+	}
+	if many {
+		if sf.Type.Kind() != reflect.Slice && sf.Type.Kind() != reflect.Array {
+			return nil, Errorf("preload field must be a slice: %s", field.Name)
+		}
+		return sf.Type.Elem(), nil
+	}
+	return sf.Type, nil
+}
+
+func setPreloadValue(record reflect.Value, field *Field, elemType reflect.Type, group []reflect.Value, many bool) error {
+	var value reflect.Value
+	if many {
+		value = reflect.MakeSlice(reflect.SliceOf(elemType), 0, len(group))
+		for _, item := range group {
+			value = reflect.Append(value, item)
+		}
+	} else if len(group) > 0 {
+		value = group[0]
+	}
+
+	switch record.Kind() {
+	case reflect.Struct:
+		f := record.FieldByName(field.Name)
+		if !f.CanSet() {
+			return Errorf("preload field is not settable: %s", field.Name)
+		}
+		if !value.IsValid() {
+			return nil
+		}
+		if f.Kind() == reflect.Array {
+			reflect.Copy(f, value)
+		} else {
+			f.Set(value)
+		}
+	case reflect.Map:
+		if !value.IsValid() {
+			return nil
+		}
+		record.SetMapIndex(reflect.ValueOf(field.MustNativeName()), value)
+	}
+	return nil
+}
+
+// recordFieldValue 读取结构体或Map记录中的字段值，Map记录优先匹配原始字段名
+func recordFieldValue(record reflect.Value, meta *Metadata, name string) interface{} {
+	record = indirectValue(record)
+	switch record.Kind() {
+	case reflect.Struct:
+		if f := record.FieldByName(name); f.IsValid() && f.CanInterface() {
+			return f.Interface()
+		}
+	case reflect.Map:
+		return mapIndex(record, meta.MustFieldNativeName(name), name)
+	}
+	return nil
+}
+
+// mapIndex returns the value of the first key that exists in the map
+func mapIndex(m reflect.Value, keys ...string) interface{} {
+	for _, key := range keys {
+		if v := m.MapIndex(reflect.ValueOf(key)); v.IsValid() {
+			return v.Interface()
+		}
+	}
+	return nil
+}
+
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// preloadKey 返回用于匹配关系字段值的键，键中包含值的类别以区分1与"1"，
+// 不同宽度的数值视为同一类别，ObjectID按十六进制字符串处理以匹配字符串类型的外键
+func preloadKey(v interface{}) string {
+	rv := indirectValue(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return ""
+	}
+	if id, ok := rv.Interface().(primitive.ObjectID); ok {
+		return "s:" + id.Hex()
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return "s:" + rv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "n:" + strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "n:" + strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			return "n:" + strconv.FormatInt(int64(f), 10)
+		}
+		return "n:" + strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		return "b:" + strconv.FormatBool(rv.Bool())
+	}
+	return fmt.Sprintf("%T:%v", rv.Interface(), rv.Interface())
+}
+
+func (opts *PreloadOptions) setBatchResult(res Result, required ...string) Result {
+	if !IsNil(opts.Match) {
+		res.And(opts.Match)
+	}
+	if len(opts.Select) > 0 {
+		projection := opts.Select
+		for _, name := range required {
+			projection = ensureProjection(projection, name)
+		}
+		res.Project(projection...)
+	}
+	if len(opts.OrderBys) > 0 {
+		res.OrderBy(opts.OrderBys...)
+	}
+	return res
+}
+
+// ensureProjection 保证关系字段始终被查询，以便回填时进行匹配
+func ensureProjection(projection []string, name string) []string {
+	var (
+		result    []string
+		inclusive bool
+	)
+	for _, item := range projection {
+		if item == "-"+name {
+			continue
+		}
+		if item == name {
+			return projection
+		}
+		if !strings.HasPrefix(item, "-") {
+			inclusive = true
+		}
+		result = append(result, item)
+	}
+	if inclusive {
+		result = append(result, name)
+	}
+	return result
+}
+
+// paginate 对单条记录的引用列表进行内存分页
+func (opts *PreloadOptions) paginate(group []reflect.Value) []reflect.Value {
+	if opts.Size == 0 {
+		return group
+	}
+	page := opts.Page
+	if page == 0 {
+		page = 1
+	}
+	start := int((page - 1) * opts.Size)
+	if start >= len(group) {
+		return nil
+	}
+	end := start + int(opts.Size)
+	if end > len(group) {
+		end = len(group)
+	}
+	return group[start:end]
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestPreloadKey(t *testing.T) {
+	id := primitive.NewObjectID()
+	n := int64(7)
+	tests := []struct {
+		a, b  interface{}
+		equal bool
+	}{
+		{a: 1, b: "1", equal: false},
+		{a: 1, b: int64(1), equal: true},
+		{a: &n, b: float64(7), equal: true},
+		{a: 1.5, b: 1, equal: false},
+		{a: true, b: "true", equal: false},
+		// ObjectID按十六进制字符串匹配
+		{a: id, b: id.Hex(), equal: true},
+		{a: &id, b: id, equal: true},
+	}
+	for _, tt := range tests {
+		if got := preloadKey(tt.a) == preloadKey(tt.b); got != tt.equal {
+			t.Errorf("preloadKey(%#v) == preloadKey(%#v) is %v, want %v", tt.a, tt.b, got, tt.equal)
+		}
+	}
+	if preloadKey(nil) != "" || preloadKey((*int)(nil)) != "" {
+		t.Error("preloadKey() of nil values should be empty")
+	}
+}
@@ -1,12 +1,14 @@
 package db
 
 func beginTransactionCallback(s *Scope) {
-	tx, err := s.Session.StartTransaction()
+	// 上下文中已有事务时开启嵌套事务，失败时仅回滚本次操作，由最外层事务负责提交
+	tx, err := s.Session.StartTransactionContext(s.Context)
 	if err != nil {
 		s.AddError(err).Skip()
 		return
 	}
 	s.Store().Store("db:tx", tx)
+	s.Context = tx.Context()
 }
 
 func commitOrRollbackTransactionCallback(s *Scope) {
@@ -19,3 +21,11 @@ func commitOrRollbackTransactionCallback(s *Scope) {
 		s.AddError(tx.Commit())
 	}
 }
+
+// txModel 返回当前事务中不经过回调链的原始模型，保证引用数据的读写与主档案处于同一事务
+func (s *Scope) txModel(name string) Collection {
+	if tx := txFromContext(s.Context); tx != nil {
+		return tx.rawTx.Model(name).WithContext(s.Context)
+	}
+	return s.model(name)
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"context"
+	"reflect"
 	"sync"
 	"time"
 )
@@ -11,6 +13,7 @@ type Scope struct {
 	skipLeft   bool
 
 	Unscoped         bool
+	Context          context.Context
 	Coll             Collection
 	StartTime        time.Time
 	Error            error
@@ -21,15 +24,28 @@ type Scope struct {
 	Projection       []string
 	Action           string
 	OrderBys         []string
+	GroupBys         []string
+	Aggregations     []Aggregation
+	Havings          []Conditional
+	DistinctField    string
 	PageSize         uint
 	PageNum          uint
+	AfterToken       string
+	BeforeToken      string
+	PageTokens       PageTokens
 	InsertOneDoc     interface{}
 	InsertManyDocs   interface{}
 	UpdateDoc        interface{}
+	QueryOptions     *QueryOptions
 	UpdateOptions    *UpdateOptions
+	UpsertResult     UpsertResult
+	ReturnNew        bool
 	InsertOneResult  InsertOneResult
 	InsertManyResult InsertManyResult
 	InsertOptions    *InsertOptions
+	WriteOps         []WriteOp
+	Ordered          bool
+	BulkWriteResult  BulkWriteResult
 	DeleteOptions    *DeleteOptions
 	RecordsAffected  int
 	TotalRecords     int
@@ -38,6 +54,11 @@ type Scope struct {
 	Cursor           Cursor
 }
 
+// grouped 是否为分组查询
+func (s *Scope) grouped() bool {
+	return len(s.GroupBys) > 0 || len(s.Aggregations) > 0
+}
+
 func (s *Scope) Skip() {
 	s.skipLeft = true
 }
@@ -48,7 +69,7 @@ func (s *Scope) AddCondition(v ...Conditional) *Scope {
 	}
 	for _, item := range v {
 		if item != nil && len(item.Conditions()) > 0 {
-			s.Conditions = append(s.Conditions)
+			s.Conditions = append(s.Conditions, item)
 		}
 	}
 	return s
@@ -89,35 +110,79 @@ func (s *Scope) AddError(err error) *Scope {
 	return s
 }
 
-func (s *Scope) buildQueryResult() Result {
-	if !s.Unscoped {
-		rule := LookupLogicDeleteRule(s.Metadata.Name)
-		if rule != nil && rule.GetValue != nil {
-			s.AddCondition(rule.GetValue)
-		}
+// queryOptions 返回传给适配器的查询选项
+func (s *Scope) queryOptions() []func(*QueryOptions) {
+	if s.QueryOptions == nil {
+		return nil
 	}
+	return []func(*QueryOptions){WithQueryOptionReturnNotFound(s.QueryOptions.ReturnNotFound)}
+}
+
+func (s *Scope) buildQueryResult() Result {
 	var findArgs []interface{}
 	if len(s.Conditions) > 0 {
 		for _, item := range s.Conditions {
 			findArgs = append(findArgs, item)
 		}
 	}
-	res := s.Coll.Find(findArgs...)
+	if !s.Unscoped {
+		rule := LookupLogicDeleteRule(s.Metadata.Name)
+		if rule != nil && rule.GetValue != nil {
+			findArgs = append(findArgs, rule.GetValue)
+		}
+	}
+	res := s.collection().Find(findArgs...)
 	if len(s.Projection) > 0 {
 		res.Project(s.Projection...)
 	}
 	if len(s.OrderBys) > 0 {
 		res.OrderBy(s.OrderBys...)
 	}
+	if len(s.GroupBys) > 0 {
+		res.GroupBy(s.GroupBys...)
+	}
+	if len(s.Aggregations) > 0 {
+		res.Aggregate(s.Aggregations...)
+	}
+	if len(s.Havings) > 0 {
+		res.Having(s.Havings...)
+	}
 	if s.PageSize > 0 {
 		res.Paginate(s.PageSize)
 	}
 	if s.PageNum > 0 {
 		res.Page(s.PageNum)
 	}
+	if s.AfterToken != "" {
+		res.After(s.AfterToken)
+	}
+	if s.BeforeToken != "" {
+		res.Before(s.BeforeToken)
+	}
 	return res
 }
 
+// collection 返回绑定当前上下文的原始集合，上下文中存在事务时使用事务中的集合
+func (s *Scope) collection() Collection {
+	coll := s.Coll
+	if tx := txFromContext(s.Context); tx != nil {
+		coll = tx.rawTx.Model(s.Metadata.Name)
+	}
+	if s.Context != nil {
+		coll = coll.WithContext(s.Context)
+	}
+	return coll
+}
+
+// model 返回绑定当前上下文的模型
+func (s *Scope) model(name string) Collection {
+	coll := s.Session.Model(name)
+	if s.Context != nil {
+		coll = coll.WithContext(s.Context)
+	}
+	return coll
+}
+
 func (s *Scope) callHooks(kind, name string) {
 	if name == "" || kind == "" || s == nil {
 		return
@@ -139,9 +204,9 @@ func (s *Scope) callHooks(kind, name string) {
 				ret = testFieldsHook(hook, s.Action, s.InsertOneDoc)
 			case ActionInsertMany:
 				ret = testFieldsHook(hook, s.Action, s.InsertManyDocs)
-			case ActionUpdateOne, ActionUpdateMany:
+			case ActionUpdateOne, ActionUpdateMany, ActionUpsert, ActionFindOneAndUpdate:
 				ret = testFieldsHook(hook, s.Action, s.UpdateDoc)
-			case ActionDeleteOne, ActionDeleteMany:
+			case ActionDeleteOne, ActionDeleteMany, ActionFindOneAndDelete:
 				ret = testFieldsHook(hook, s.Action, s.Conditions)
 			}
 			if ret {
@@ -152,3 +217,58 @@ func (s *Scope) callHooks(kind, name string) {
 		}
 	}
 }
+
+// eachInsertDoc 依次处理新增的档案，结构体档案以值传入时会替换为指针以便写回字段值，
+// Map档案会复制后再写入，不修改调用方传入的内容
+func (s *Scope) eachInsertDoc(fn func(int, reflect.Value) error) error {
+	if s.Action == ActionInsertOne {
+		doc, record := addressableDoc(s.InsertOneDoc)
+		s.InsertOneDoc = doc
+		return fn(0, record)
+	}
+	values := indirectValue(reflect.ValueOf(s.InsertManyDocs))
+	if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+		return nil
+	}
+	if elemKind := values.Type().Elem().Kind(); elemKind == reflect.Map || elemKind == reflect.Interface {
+		// 元素可能为Map时复制切片，替换元素不影响调用方传入的切片
+		cp := reflect.MakeSlice(reflect.SliceOf(values.Type().Elem()), values.Len(), values.Len())
+		reflect.Copy(cp, values)
+		s.InsertManyDocs = cp.Interface()
+		values = cp
+	}
+	for i := 0; i < values.Len(); i++ {
+		item := values.Index(i)
+		if (item.Kind() == reflect.Interface || item.Kind() == reflect.Map) && item.CanSet() && !item.IsNil() {
+			doc, record := addressableDoc(item.Interface())
+			item.Set(reflect.ValueOf(doc))
+			item = record
+		}
+		if err := fn(i, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addressableDoc 结构体档案以值传入时复制为指针，以便写回去除空白后的字段值；Map档案复制为同类型的Map
+func addressableDoc(doc interface{}) (interface{}, reflect.Value) {
+	rv := reflect.ValueOf(doc)
+	switch rv.Kind() {
+	case reflect.Struct:
+		cp := reflect.New(rv.Type())
+		cp.Elem().Set(rv)
+		return cp.Interface(), cp
+	case reflect.Map:
+		if rv.IsNil() {
+			return doc, rv
+		}
+		cp := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return cp.Interface(), cp
+	}
+	return doc, rv
+}
@@ -0,0 +1,252 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// SavepointTx 支持保存点的事务，嵌套事务回滚时仅回滚到对应保存点
+type SavepointTx interface {
+	Tx
+	Savepoint(name string) error
+	RollbackToSavepoint(name string) error
+	ReleaseSavepoint(name string) error
+}
+
+func (cs *clientWrapper) StartTransaction() (Tx, error) {
+	return cs.startTransaction(nil)
+}
+
+// StartTransactionContext 上下文中已存在事务时开启嵌套事务，否则开启新事务
+func (cs *clientWrapper) StartTransactionContext(ctx context.Context) (Tx, error) {
+	return cs.startTransaction(ctx)
+}
+
+func (cs *clientWrapper) startTransaction(ctx context.Context) (Tx, error) {
+	if parent := txFromContext(ctx); parent != nil && parent.client == cs {
+		return parent.nest(ctx)
+	}
+	tx, err := cs.rawClient.StartTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return cs.wrapTx(ctx, tx), nil
+}
+
+func (cs *clientWrapper) WithTransaction(f func(Tx) error) error {
+	return cs.withTransaction(nil, f, cs.retryPolicy)
+}
+
+// WithTransactionContext 上下文中已存在事务时在嵌套事务中执行，仅最外层事务会真正提交
+func (cs *clientWrapper) WithTransactionContext(ctx context.Context, f func(Tx) error, opts ...func(*TransactionOptions)) error {
+	options := TransactionOptions{RetryPolicy: cs.retryPolicy}
+	for _, fn := range opts {
+		fn(&options)
+	}
+	return cs.withTransaction(ctx, f, options.RetryPolicy)
+}
+
+// withTransaction 未指定重试策略时由适配器负责事务的执行与重试
+func (cs *clientWrapper) withTransaction(ctx context.Context, f func(Tx) error, policy *RetryPolicy) error {
+	if parent := txFromContext(ctx); parent != nil && parent.client == cs {
+		tx, err := parent.nest(ctx)
+		if err != nil {
+			return err
+		}
+		if err := f(tx); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return Errorf("%w; %v", err, rbErr)
+			}
+			return err
+		}
+		return tx.Commit()
+	}
+	if policy == nil {
+		return cs.rawClient.WithTransaction(func(raw Tx) error {
+			tx := cs.wrapTx(ctx, raw)
+			// 由适配器负责提交或回滚，此处仅检查嵌套事务是否要求整体回滚
+			if err := f(tx); err != nil {
+				return err
+			}
+			return tx.checkRollbackOnly()
+		})
+	}
+	for attempt := 1; ; attempt++ {
+		err := cs.runTransaction(ctx, f, policy)
+		if err == nil || attempt >= policy.maxAttempts() || !policy.retryTransaction(err) {
+			return err
+		}
+		if waitErr := policy.wait(ctx, attempt); waitErr != nil {
+			return err
+		}
+	}
+}
+
+// runTransaction 执行一次事务，提交结果未知时按重试策略重试提交
+func (cs *clientWrapper) runTransaction(ctx context.Context, f func(Tx) error, policy *RetryPolicy) error {
+	raw, err := cs.rawClient.StartTransaction()
+	if err != nil {
+		return err
+	}
+	tx := cs.wrapTx(ctx, raw)
+	if err := f(tx); err != nil {
+		if rbErr := raw.Rollback(); rbErr != nil {
+			return Errorf("%w; %v", err, rbErr)
+		}
+		return err
+	}
+	if err := tx.checkRollbackOnly(); err != nil {
+		if rbErr := raw.Rollback(); rbErr != nil {
+			return Errorf("%w; %v", err, rbErr)
+		}
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		err = raw.Commit()
+		if err == nil || attempt >= policy.maxAttempts() || !policy.retryCommit(err) {
+			break
+		}
+		if policy.wait(ctx, attempt) != nil {
+			break
+		}
+	}
+	if err != nil && HasErrorLabel(err, LabelUnknownTransactionCommitResult) {
+		// 放弃重试提交时释放适配器事务占用的资源
+		_ = raw.Rollback()
+	}
+	return err
+}
+
+// wrapTx 未指定上下文时使用适配器事务自身的上下文
+func (cs *clientWrapper) wrapTx(ctx context.Context, raw Tx) *callbacksTx {
+	if ctx == nil {
+		ctx = raw.Context()
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tx := &callbacksTx{client: cs, rawTx: raw}
+	tx.ctx = contextWithTx(ctx, tx)
+	return tx
+}
+
+type txContextKey struct{}
+
+func contextWithTx(ctx context.Context, tx Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// txFromContext 返回上下文中正在进行的事务
+func txFromContext(ctx context.Context) *callbacksTx {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(txContextKey{}).(*callbacksTx)
+	return tx
+}
+
+// callbacksTx 事务中的模型同样经过回调链，且所有操作均绑定到该事务
+type callbacksTx struct {
+	mu        sync.Mutex
+	client    *clientWrapper
+	rawTx     Tx
+	ctx       context.Context
+	parent    *callbacksTx // 嵌套事务的外层事务
+	savepoint string
+	done      bool
+
+	// 以下字段仅最外层事务使用
+	savepoints   int
+	rollbackOnly bool
+}
+
+func (ct *callbacksTx) Model(name string) Collection {
+	return &callbacksCollection{client: ct.client, rawColl: ct.rawTx.Model(name), ctx: ct.ctx}
+}
+
+func (ct *callbacksTx) Context() context.Context {
+	return ct.ctx
+}
+
+func (ct *callbacksTx) root() *callbacksTx {
+	tx := ct
+	for tx.parent != nil {
+		tx = tx.parent
+	}
+	return tx
+}
+
+// nest 开启嵌套事务，适配器支持保存点时创建保存点
+func (ct *callbacksTx) nest(ctx context.Context) (*callbacksTx, error) {
+	tx := &callbacksTx{client: ct.client, rawTx: ct.rawTx, parent: ct}
+	if sp, ok := ct.rawTx.(SavepointTx); ok {
+		root := ct.root()
+		root.mu.Lock()
+		root.savepoints++
+		tx.savepoint = fmt.Sprintf("db_savepoint_%d", root.savepoints)
+		root.mu.Unlock()
+		if err := sp.Savepoint(tx.savepoint); err != nil {
+			return nil, err
+		}
+	}
+	tx.ctx = contextWithTx(ctx, tx)
+	return tx, nil
+}
+
+func (ct *callbacksTx) finish() error {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if ct.done {
+		return Errorf("transaction has already been committed or rolled back")
+	}
+	ct.done = true
+	return nil
+}
+
+// Commit 嵌套事务仅释放保存点，由最外层事务统一提交
+func (ct *callbacksTx) Commit() error {
+	if err := ct.finish(); err != nil {
+		return err
+	}
+	if ct.parent != nil {
+		if ct.savepoint != "" {
+			return ct.rawTx.(SavepointTx).ReleaseSavepoint(ct.savepoint)
+		}
+		return nil
+	}
+	if err := ct.checkRollbackOnly(); err != nil {
+		if rbErr := ct.rawTx.Rollback(); rbErr != nil {
+			return Errorf("%w; %v", err, rbErr)
+		}
+		return err
+	}
+	return ct.rawTx.Commit()
+}
+
+// Rollback 嵌套事务回滚到保存点，适配器不支持保存点时将最外层事务标记为只能回滚
+func (ct *callbacksTx) Rollback() error {
+	if err := ct.finish(); err != nil {
+		return err
+	}
+	if ct.parent != nil {
+		if ct.savepoint != "" {
+			return ct.rawTx.(SavepointTx).RollbackToSavepoint(ct.savepoint)
+		}
+		root := ct.root()
+		root.mu.Lock()
+		root.rollbackOnly = true
+		root.mu.Unlock()
+		return nil
+	}
+	return ct.rawTx.Rollback()
+}
+
+func (ct *callbacksTx) checkRollbackOnly() error {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if ct.rollbackOnly {
+		return Errorf("%w: rolled back by a nested transaction", ErrTxAborted)
+	}
+	return nil
+}
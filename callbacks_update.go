@@ -4,7 +4,14 @@ func registerUpdateCallbacks(callbacks *clientWrapper) *clientWrapper {
 	processor := callbacks.UpdateProcessors()
 	processor.Register("db:begin_transaction", beginTransactionCallback)
 	processor.Register("db:before_update", beforeUpdateCallback)
+	processor.Register("db:timestamps", timestampsCallback)
+	processor.Register("db:version", versionCallback)
+	processor.Register("db:validate", validateCallback)
+	processor.Register("db:save_before_associations", saveBeforeAssociationsCallback)
+	processor.Register("db:increment_version", incrementVersionCallback)
 	processor.Register("db:update", updateCallback)
+	processor.Register("db:check_version", checkVersionCallback)
+	processor.Register("db:save_associations", saveAssociationsCallback)
 	processor.Register("db:after_update", afterUpdateCallback)
 	processor.Register("db:commit_or_rollback_transaction", commitOrRollbackTransactionCallback)
 	return callbacks
@@ -25,6 +32,10 @@ func updateCallback(s *Scope) {
 		s.RecordsAffected, s.Error = res.UpdateOne(s.UpdateDoc)
 	case ActionUpdateMany:
 		s.RecordsAffected, s.Error = res.UpdateMany(s.UpdateDoc)
+	case ActionUpsert:
+		s.UpsertResult, s.Error = res.Upsert(s.UpdateDoc)
+	case ActionFindOneAndUpdate:
+		s.Error = res.FindOneAndUpdate(s.UpdateDoc, s.Dest, s.ReturnNew, s.queryOptions()...)
 	}
 }
 
@@ -166,10 +166,40 @@ func NewUnion(op string, v []Conditional) Conditional {
 	return nil
 }
 
+// EqualValues 返回以And连接的等值条件中的字段值，Upsert新增记录时作为记录的初始值
+func EqualValues(filters ...interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+	var walk func(interface{})
+	walk = func(filter interface{}) {
+		switch v := filter.(type) {
+		case Cond:
+			for _, item := range v.Entries() {
+				if item.Operator == OperatorEq {
+					values[item.Key] = item.Value
+				}
+			}
+		case *Cond:
+			walk(*v)
+		case Union:
+			walk(&v)
+		case *Union:
+			if v.Operator() == OperatorAnd {
+				for _, item := range v.Conditions() {
+					walk(item)
+				}
+			}
+		}
+	}
+	for _, filter := range filters {
+		walk(filter)
+	}
+	return values
+}
+
 func And(v ...Conditional) Conditional {
 	return NewUnion(OperatorAnd, v)
 }
 
 func Or(v ...Conditional) Conditional {
-	return NewUnion(OperatorAnd, v)
+	return NewUnion(OperatorOr, v)
 }
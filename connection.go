@@ -27,7 +27,8 @@ type Connection struct {
 }
 
 type ConnectOptions struct {
-	Logger Logger
+	Logger      Logger
+	RetryPolicy *RetryPolicy // 事务重试策略，未指定时由适配器决定是否重试
 }
 
 func (c Connection) Client() Client {
@@ -50,7 +51,7 @@ func (c Connection) Disconnect() error {
 	return c.client.Disconnect(context.Background())
 }
 
-func (c Connection) Raw(raw string, values ...interface{}) error {
+func (c Connection) Raw(raw string, values ...interface{}) RawScript {
 	return c.client.Raw(raw, values...)
 }
 
@@ -58,8 +59,18 @@ func (c Connection) StartTransaction() (Tx, error) {
 	return c.client.StartTransaction()
 }
 
-func (c Connection) WithTransaction(fn func(Tx) error) error {
-	return c.client.WithTransaction(fn)
+// StartTransactionContext 上下文中已存在事务时开启嵌套事务
+func (c Connection) StartTransactionContext(ctx context.Context) (Tx, error) {
+	return c.client.(*clientWrapper).StartTransactionContext(ctx)
+}
+
+func (c Connection) WithTransaction(fn func(Tx) error, opts ...func(*TransactionOptions)) error {
+	return c.client.(*clientWrapper).WithTransactionContext(nil, fn, opts...)
+}
+
+// WithTransactionContext 上下文中已存在事务时在嵌套事务中执行
+func (c Connection) WithTransactionContext(ctx context.Context, fn func(Tx) error, opts ...func(*TransactionOptions)) error {
+	return c.client.(*clientWrapper).WithTransactionContext(ctx, fn, opts...)
 }
 
 func Connect(source DataSource, opts ...*ConnectOptions) (*Connection, error) {
@@ -98,10 +109,12 @@ func Connect(source DataSource, opts ...*ConnectOptions) (*Connection, error) {
 	}
 	conn := &Connection{cacheStore: &sync.Map{}}
 	wrapperClient := newClientWrapper(client, conn)
+	wrapperClient.retryPolicy = options.RetryPolicy
 	registerCreateCallbacks(wrapperClient)
 	registerQueryCallbacks(wrapperClient)
 	registerUpdateCallbacks(wrapperClient)
 	registerDeleteCallbacks(wrapperClient)
+	registerBulkCallbacks(wrapperClient)
 	conn.client = wrapperClient
 	connMap[source.Name] = conn
 	return conn, nil
@@ -130,17 +143,48 @@ func Disconnect(names ...string) error {
 }
 
 func (c Connection) RegisterMetadata(metaOrStructs ...interface{}) error {
+	// 注册失败时恢复同批元数据注册前的定义，不影响已注册的同名元数据
+	metadataMapMu.RLock()
+	previous := make(map[string]Metadata, len(metadataMap))
+	for name, meta := range metadataMap {
+		previous[name] = meta
+	}
+	metadataMapMu.RUnlock()
+
+	var names []string
+	rollback := func() {
+		metadataMapMu.Lock()
+		defer metadataMapMu.Unlock()
+
+		for _, name := range names {
+			if meta, has := previous[name]; has {
+				metadataMap[name] = meta
+			} else {
+				delete(metadataMap, name)
+			}
+		}
+	}
 	for _, item := range metaOrStructs {
-		if err := c.registerMetadata(item); err != nil {
+		name, err := c.registerMetadata(item)
+		if err != nil {
+			rollback()
 			return err
 		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	// 关系需在同批元数据全部注册后校验，以支持相互引用
+	if err := resolveRelationships(names); err != nil {
+		rollback()
+		return err
 	}
 	return nil
 }
 
-func (c Connection) registerMetadata(metaOrStruct interface{}) error {
+func (c Connection) registerMetadata(metaOrStruct interface{}) (string, error) {
 	if metaOrStruct == nil {
-		return nil
+		return "", nil
 	}
 
 	var metadata Metadata
@@ -152,10 +196,10 @@ func (c Connection) registerMetadata(metaOrStruct interface{}) error {
 	default:
 		parsed, err := parseStructMetadata(v)
 		if err != nil {
-			return Errorf("parse struct failed: %v", err)
+			return "", Errorf("parse struct failed: %v", err)
 		}
 		if parsed == nil {
-			return Errorf("unsupported metadata type: %v", metadata)
+			return "", Errorf("unsupported metadata type: %v", v)
 		}
 		metadata = *parsed
 	}
@@ -164,14 +208,14 @@ func (c Connection) registerMetadata(metaOrStruct interface{}) error {
 	metadata.nativeProperties = metadata.Properties.nativeFields() // 再计算nativeProperties
 	// 校验结构体
 	if _, err := govalidator.ValidateStruct(&metadata); err != nil {
-		return Errorf(err.Error())
+		return "", Errorf(err.Error())
 	}
 
 	metadataMapMu.Lock()
 	metadataMap[metadata.Name] = metadata
 	metadataMapMu.Unlock()
 
-	return nil
+	return metadata.Name, nil
 }
 
 func parseStructMetadata(v interface{}) (*Metadata, error) {
@@ -192,6 +236,19 @@ func parseStructMetadata(v interface{}) (*Metadata, error) {
 		for _, item := range s.Fields() {
 			field := parseStructFieldTag(item.Tag("db"))
 			field.Name = item.Name()
+			if field.Relationship.Type != "" {
+				if field.Relationship.MetadataName == "" {
+					field.Relationship.MetadataName = relationshipElemName(item.ReflectField().Type)
+				}
+				if field.Type == "" {
+					switch field.Relationship.Type {
+					case RelationshipHasMany, RelationshipRefMany:
+						field.Type = Array
+					default:
+						field.Type = Object
+					}
+				}
+			}
 			if field.Type == "" {
 				switch item.Kind() {
 				case reflect.Bool:
@@ -297,12 +354,18 @@ func parseStructFieldTag(tag string) (f Field) {
 			f.NativeName = value
 		case "desc":
 			f.Description = value
+		case "trim":
+			f.Trim = value
 		case "rqd":
 			f.Required = value
 		case "uniq":
 			f.Unique = value
 		case "default":
 			f.DefaultValue = value
+		case "version":
+			f.Version = value
+		case "ref":
+			f.Relationship = parseRelationshipTag(value)
 		}
 	}
 	return
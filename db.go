@@ -1,9 +1,15 @@
 package db
 
+import (
+	"context"
+	"time"
+)
+
 type DataSource struct {
-	Name    string `valid:"required,!empty"`
-	Adapter string `valid:"required,!empty"`
-	URI     string `valid:"required,!empty"`
+	Name    string        `valid:"required,!empty"`
+	Adapter string        `valid:"required,!empty"`
+	URI     string        `valid:"required,!empty"`
+	Timeout time.Duration // 调用方未通过WithContext指定截止时间时，单次操作的默认超时，为0时使用适配器的默认值
 }
 
 func LookupSession(name string) (*Connection, bool) {
@@ -22,12 +28,29 @@ func Session(name string) *Connection {
 	return conn
 }
 
-func Raw(name string, raw string, values ...interface{}) error {
+func Raw(name string, raw string, values ...interface{}) RawScript {
 	conn, has := LookupSession(name)
 	if has {
 		return conn.Raw(raw, values...)
 	}
-	return Errorf(`missing session: %s`, name)
+	return &errorRawScript{err: Errorf(`missing session: %s`, name)}
+}
+
+// errorRawScript 数据源不存在时返回，执行时返回对应错误
+type errorRawScript struct {
+	err error
+}
+
+func (r *errorRawScript) Query() (QueryResult, error) {
+	return nil, r.err
+}
+
+func (r *errorRawScript) Exec() (ExecResult, error) {
+	return nil, r.err
+}
+
+func (r *errorRawScript) WithContext(context.Context) RawScript {
+	return r
 }
 
 func HasModel(name string) bool {
@@ -47,6 +70,14 @@ func StartTransaction(name string) (Tx, error) {
 	return Session(name).StartTransaction()
 }
 
-func WithTransaction(name string, fn func(Tx) error) error {
-	return Session(name).WithTransaction(fn)
+func WithTransaction(name string, fn func(Tx) error, opts ...func(*TransactionOptions)) error {
+	return Session(name).WithTransaction(fn, opts...)
+}
+
+func StartTransactionContext(ctx context.Context, name string) (Tx, error) {
+	return Session(name).StartTransactionContext(ctx)
+}
+
+func WithTransactionContext(ctx context.Context, name string, fn func(Tx) error, opts ...func(*TransactionOptions)) error {
+	return Session(name).WithTransactionContext(ctx, fn, opts...)
 }
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// defaultValueCallback 新增前为未传入的字段填充DefaultValue，结构体中的零值视为未传入
+func defaultValueCallback(s *Scope) {
+	if s.HasError() {
+		return
+	}
+	meta := &s.Metadata
+	if s.Action == ActionInsertOne || s.Action == ActionInsertMany {
+		s.AddError(s.eachInsertDoc(func(_ int, record reflect.Value) error {
+			return applyDefaultValues(meta, record)
+		}))
+	}
+}
+
+func applyDefaultValues(meta *Metadata, record reflect.Value) error {
+	record = indirectValue(record)
+	if record.Kind() != reflect.Struct && record.Kind() != reflect.Map {
+		return nil
+	}
+	for _, name := range sortedFieldNames(meta) {
+		field := meta.Properties[name]
+		if field.DefaultValue == "" || field.Relationship.Type != "" {
+			continue
+		}
+		switch record.Kind() {
+		case reflect.Struct:
+			f := record.FieldByName(field.Name)
+			if !f.IsValid() || !f.CanSet() || !f.IsZero() {
+				continue
+			}
+			v, err := defaultValue(&field)
+			if err != nil {
+				return err
+			}
+			if !assignDefaultValue(f, v) {
+				return Errorf(`cannot assign default value %v to field %s.%s`, v, meta.Name, field.Name)
+			}
+		case reflect.Map:
+			if v := mapIndex(record, field.Name, field.MustNativeName()); !IsNil(v) {
+				continue
+			}
+			v, err := defaultValue(&field)
+			if err != nil {
+				return err
+			}
+			if !setMapValue(record, field.Name, v) {
+				return Errorf(`cannot assign default value %v to field %s.%s`, v, meta.Name, field.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// assignDefaultValue 写入结构体字段，支持time.Time字段及实现sql.Scanner的字段
+func assignDefaultValue(f reflect.Value, v interface{}) bool {
+	if assignValue(f, v) {
+		return true
+	}
+	if t := f.Type(); t == reflect.TypeOf(time.Time{}) || t == reflect.TypeOf(&time.Time{}) {
+		if tv, err := coerceValue(Datetime, v); err == nil {
+			return assignValue(f, tv)
+		}
+	}
+	if f.CanAddr() {
+		if scanner, ok := f.Addr().Interface().(sql.Scanner); ok {
+			return scanner.Scan(v) == nil
+		}
+	}
+	return false
+}
+
+// defaultValue 解析字段默认值中的占位符并转换为字段类型
+func defaultValue(field *Field) (interface{}, error) {
+	v := parseValue(field.DefaultValue)
+	if v == nil {
+		return nil, Errorf(`invalid default value %q of field %s`, field.DefaultValue, field.Name)
+	}
+	cv, err := coerceValue(field.Type, v)
+	if err != nil {
+		return nil, Errorf(`invalid default value %q of field %s: %v`, field.DefaultValue, field.Name, err)
+	}
+	return cv, nil
+}
+
+// coerceValue 将占位符的解析结果转换为字段类型，Datetime字段支持Unix时间戳及RFC3339字符串
+func coerceValue(typ string, v interface{}) (interface{}, error) {
+	switch typ {
+	case String:
+		if _, ok := v.(string); !ok {
+			return fmt.Sprintf("%v", v), nil
+		}
+	case Int:
+		switch val := v.(type) {
+		case string:
+			return strconv.ParseInt(val, 10, 64)
+		case float64:
+			return int64(val), nil
+		}
+	case Float:
+		switch val := v.(type) {
+		case string:
+			return strconv.ParseFloat(val, 64)
+		case int:
+			return float64(val), nil
+		case int64:
+			return float64(val), nil
+		}
+	case Bool:
+		if val, ok := v.(string); ok {
+			return strconv.ParseBool(val)
+		}
+	case Datetime:
+		switch val := v.(type) {
+		case string:
+			return time.Parse(time.RFC3339Nano, val)
+		case int:
+			return time.Unix(int64(val), 0), nil
+		case int64:
+			return time.Unix(val, 0), nil
+		}
+	case Object, Array:
+		if val, ok := v.(string); ok {
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(val), &parsed); err != nil {
+				return nil, err
+			}
+			return parsed, nil
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNotFound 未查询到记录，仅在查询时指定WithQueryOptionReturnNotFound时返回
+	ErrNotFound = errors.New("db: record not found")
+	// ErrDuplicateKey 违反主键或唯一约束
+	ErrDuplicateKey = errors.New("db: duplicate key")
+	// ErrValidation 档案未通过校验
+	ErrValidation = errors.New("db: validation failed")
+	// ErrStaleObject 乐观锁版本号不匹配，档案已被修改或删除
+	ErrStaleObject = errors.New("db: stale object")
+	// ErrTxAborted 事务已中止，需要回滚后重新执行
+	ErrTxAborted = errors.New("db: transaction aborted")
+)
+
+// DuplicateKeyError 违反主键或唯一约束的错误，Err为数据源返回的原始错误
+type DuplicateKeyError struct {
+	Err error
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DuplicateKeyError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Name        string
+	DisplayName string
+	Err         error
+}
+
+func (e FieldError) Error() string {
+	label := e.DisplayName
+	if label == "" {
+		label = e.Name
+	}
+	return fmt.Sprintf("%s: %v", label, e.Err)
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError 档案校验失败的错误，包含所有未通过校验的字段
+type ValidationError struct {
+	Index  int // 未通过校验的档案在InsertMany中的下标
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Error())
+	}
+	return fmt.Sprintf("%v: %s", ErrValidation, strings.Join(msgs, "; "))
+}
+
+// Field 返回指定字段的校验错误，字段通过校验时返回nil
+func (e *ValidationError) Field(name string) error {
+	for _, fe := range e.Errors {
+		if fe.Name == name {
+			return fe.Err
+		}
+	}
+	return nil
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
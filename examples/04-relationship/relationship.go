@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/iamdanielyin/db"
+	_ "github.com/iamdanielyin/db/adapter/mongo"
 	"log"
 	"os"
 )
@@ -66,7 +67,21 @@ func main() {
 		}
 	}()
 
-	// 注册所有元数据
-	sess.RegisterMetadata("test", &User{}, &IDCard{}, &BankCard{}, &Company{}, &Project{})
+	// 注册所有元数据（存在引用关系的元数据需在同一批次注册）
+	if err := sess.RegisterMetadata(&User{}, &IDCard{}, &BankCard{}, &Company{}, &Project{}, &UserProjectRef{}); err != nil {
+		log.Fatalf("元数据注册失败：%v\n", err)
+	}
 
+	// 引用联查
+	var users []User
+	if err := db.Model("User").Find().
+		Preload("IDCard").
+		Preload("BankCards").
+		Preload("Company").
+		Preload("Projects").
+		All(&users); err != nil {
+		log.Fatalf("引用联查失败：%v\n", err)
+	} else {
+		log.Printf("引用联查成功：%v\n", users)
+	}
 }
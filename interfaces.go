@@ -11,7 +11,7 @@ type Client interface {
 	Name() string
 	Logger() Logger
 	Source() DataSource
-	Raw(string, ...interface{}) error
+	Raw(string, ...interface{}) RawScript
 	Disconnect(context.Context) error
 	StartTransaction() (Tx, error)
 	WithTransaction(func(Tx) error) error
@@ -24,32 +24,46 @@ type Collection interface {
 	Session() *Connection
 	InsertOne(interface{}, ...func(*InsertOptions)) (InsertOneResult, error)
 	InsertMany(interface{}, ...func(*InsertOptions)) (InsertManyResult, error)
+	BulkWrite([]WriteOp, bool) (BulkWriteResult, error)
 	Find(...interface{}) Result
+	WithContext(context.Context) Collection
 }
 
 type Result interface {
 	And(...Conditional) Result
 	Or(...Conditional) Result
 	Project(...string) Result
-	One(dst interface{}) error
+	One(dst interface{}, fns ...func(*QueryOptions)) error
 	All(dst interface{}) error
 	Cursor() (Cursor, error)
 	OrderBy(...string) Result
 	Count() (int, error)
+	Distinct(field string, dst interface{}) error
 	Paginate(uint) Result
 	Page(uint) Result
+	After(token string) Result
+	Before(token string) Result
+	Scroll(dst interface{}) (PageTokens, error)
 	TotalRecords() (int, error)
 	Preload(string, ...func(*PreloadOptions)) Result
+	GroupBy(...string) Result
+	Aggregate(...Aggregation) Result
+	Having(...Conditional) Result
 	TotalPages() (int, error)
 	UpdateOne(interface{}, ...func(*UpdateOptions)) (int, error)
 	UpdateMany(interface{}, ...func(*UpdateOptions)) (int, error)
+	Upsert(interface{}) (UpsertResult, error)
+	FindOneAndUpdate(doc interface{}, dst interface{}, returnNew bool, fns ...func(*QueryOptions)) error
+	FindOneAndDelete(dst interface{}, fns ...func(*QueryOptions)) error
 	Unscoped() Result
 	DeleteOne(...func(*DeleteOptions)) (int, error)
 	DeleteMany(...func(*DeleteOptions)) (int, error)
+	WithContext(context.Context) Result
 }
 
 type Tx interface {
 	Model(string) Collection
+	Context() context.Context
 	Commit() error
 	Rollback() error
 }
@@ -65,17 +79,30 @@ type InsertOneResult interface {
 	IntID() int
 }
 
+// UpsertResult 新增或修改的结果，Inserted为true时可获取新记录的主键
+type UpsertResult interface {
+	InsertOneResult
+	Inserted() bool
+}
+
 type InsertManyResult interface {
 	StringIDs() []string
 	IntIDs() []int
 }
 
 type QueryResult interface {
-	One(dst interface{}) error
+	One(dst interface{}, fns ...func(*QueryOptions)) error
 	All(dst interface{}) error
 	Cursor() (Cursor, error)
 }
 
+// RawScript 本地化脚本，Query执行查询类脚本，Exec执行执行类脚本
+type RawScript interface {
+	Query() (QueryResult, error)
+	Exec() (ExecResult, error)
+	WithContext(context.Context) RawScript
+}
+
 type ExecResult interface {
 	OK() bool
 	RecordsAffected() int
@@ -0,0 +1,237 @@
+package db
+
+import (
+	"encoding/base64"
+	jsoniter "github.com/json-iterator/go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PageTokens 键集分页的翻页令牌，没有更多记录时为空
+type PageTokens struct {
+	Next string
+	Prev string
+}
+
+// Keyset 键集分页的查询参数，After与Before不能同时指定
+type Keyset struct {
+	OrderBys   []string
+	Projection []string
+	Size       uint
+	After      string
+	Before     string
+}
+
+// KeysetScroll 按排序字段及主键进行键集分页，res仅需包含查询条件，供适配器实现Result.Scroll
+func KeysetScroll(res Result, meta Metadata, ks Keyset, dst interface{}) (PageTokens, error) {
+	var tokens PageTokens
+	if ks.After != "" && ks.Before != "" {
+		return tokens, Errorf(`After and Before cannot be used together`)
+	}
+	sliceValue := reflect.ValueOf(dst)
+	if sliceValue.Kind() != reflect.Ptr || sliceValue.IsNil() || sliceValue.Elem().Kind() != reflect.Slice {
+		return tokens, Errorf(`destination must be a pointer to slice: %T`, dst)
+	}
+	sliceValue = sliceValue.Elem()
+
+	keys, descs := keysetOrder(meta, ks.OrderBys)
+	var (
+		token    = ks.After
+		backward = ks.Before != ""
+	)
+	if backward {
+		token = ks.Before
+	}
+	if token != "" {
+		values, err := decodePageToken(token, keys)
+		if err != nil {
+			return tokens, err
+		}
+		res.And(keysetCondition(keys, descs, values, backward))
+	}
+	orderBys := make([]string, len(keys))
+	for i, key := range keys {
+		// 向前翻页时反向排序，查询后再恢复原有顺序
+		if descs[i] != backward {
+			orderBys[i] = "-" + key
+		} else {
+			orderBys[i] = key
+		}
+	}
+	res.OrderBy(orderBys...)
+	if len(ks.Projection) > 0 {
+		projection := ks.Projection
+		for _, key := range keys {
+			projection = ensureProjection(projection, key)
+		}
+		res.Project(projection...)
+	}
+	if ks.Size > 0 {
+		// 多查询一条记录以判断是否还有更多记录
+		res.Paginate(ks.Size + 1)
+	}
+	if err := res.All(dst); err != nil {
+		return tokens, err
+	}
+
+	more := ks.Size > 0 && sliceValue.Len() > int(ks.Size)
+	if more {
+		sliceValue.Set(sliceValue.Slice(0, int(ks.Size)))
+	}
+	n := sliceValue.Len()
+	if n == 0 {
+		return tokens, nil
+	}
+	hasNext, hasPrev := more, token != ""
+	if backward {
+		swap := reflect.Swapper(sliceValue.Interface())
+		for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+		hasNext, hasPrev = true, more
+	}
+	var err error
+	if hasNext {
+		if tokens.Next, err = encodePageToken(meta, keys, sliceValue.Index(n-1)); err != nil {
+			return tokens, err
+		}
+	}
+	if hasPrev {
+		if tokens.Prev, err = encodePageToken(meta, keys, sliceValue.Index(0)); err != nil {
+			return tokens, err
+		}
+	}
+	return tokens, nil
+}
+
+// keysetOrder 返回排序字段名称及是否倒序，主键始终作为最后一个排序字段以保证顺序唯一
+func keysetOrder(meta Metadata, orderBys []string) (keys []string, descs []bool) {
+	var (
+		pk  = meta.PrimaryFieldName()
+		has bool
+	)
+	for _, item := range orderBys {
+		key, desc := item, false
+		if strings.HasPrefix(item, "-") {
+			key, desc = item[1:], true
+		}
+		if f, ok := meta.FieldByName(key); ok {
+			key = f.Name
+		}
+		if key == pk {
+			has = true
+		}
+		keys = append(keys, key)
+		descs = append(descs, desc)
+	}
+	if !has {
+		keys = append(keys, pk)
+		descs = append(descs, false)
+	}
+	return
+}
+
+// keysetCondition 返回排在令牌所在记录之后（backward为true时为之前）的记录的查询条件
+func keysetCondition(keys []string, descs []bool, values []interface{}, backward bool) Conditional {
+	var branches []Conditional
+	for i := range keys {
+		cond := make(Cond)
+		for j := 0; j < i; j++ {
+			cond.Eq(keys[j], values[j])
+		}
+		if descs[i] != backward {
+			cond.Lt(keys[i], values[i])
+		} else {
+			cond.Gt(keys[i], values[i])
+		}
+		branches = append(branches, cond)
+	}
+	return Or(branches...)
+}
+
+type pageToken struct {
+	Keys   []string     `json:"k"`
+	Values []tokenValue `json:"v"`
+}
+
+// tokenValue 令牌中的排序字段值，Type用于还原整数、时间及ObjectID类型
+type tokenValue struct {
+	Type  string              `json:"t,omitempty"`
+	Value jsoniter.RawMessage `json:"v"`
+}
+
+func encodePageToken(meta Metadata, keys []string, record reflect.Value) (string, error) {
+	token := pageToken{Keys: keys}
+	for _, key := range keys {
+		v := recordFieldValue(record, &meta, key)
+		if rv := indirectValue(reflect.ValueOf(v)); rv.IsValid() {
+			v = rv.Interface()
+		} else {
+			return "", Errorf(`sort field "%s" of keyset pagination cannot be null`, key)
+		}
+		var tv tokenValue
+		switch val := v.(type) {
+		case time.Time:
+			tv.Type, v = "time", val.Format(time.RFC3339Nano)
+		case primitive.ObjectID:
+			tv.Type, v = "oid", val.Hex()
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			tv.Type = "int"
+		case float32, float64, string, bool:
+		default:
+			return "", Errorf(`unsupported sort value type %T of keyset pagination`, v)
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		tv.Value = data
+		token.Values = append(token.Values, tv)
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodePageToken(s string, keys []string) ([]interface{}, error) {
+	var token pageToken
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &token)
+	}
+	if err != nil || len(token.Keys) != len(keys) || len(token.Values) != len(keys) {
+		return nil, Errorf(`invalid page token`)
+	}
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if token.Keys[i] != key {
+			return nil, Errorf(`page token does not match the sort fields`)
+		}
+		tv := token.Values[i]
+		switch tv.Type {
+		case "int":
+			values[i], err = strconv.ParseInt(string(tv.Value), 10, 64)
+		case "time":
+			var str string
+			if err = json.Unmarshal(tv.Value, &str); err == nil {
+				values[i], err = time.Parse(time.RFC3339Nano, str)
+			}
+		case "oid":
+			var str string
+			if err = json.Unmarshal(tv.Value, &str); err == nil {
+				values[i], err = primitive.ObjectIDFromHex(str)
+			}
+		default:
+			err = json.Unmarshal(tv.Value, &values[i])
+		}
+		if err != nil {
+			return nil, Errorf(`invalid page token`)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPageToken(t *testing.T) {
+	type event struct {
+		ID        primitive.ObjectID
+		Seq       int64
+		CreatedAt time.Time
+	}
+	var (
+		meta = Metadata{Name: "Event"}
+		id   = primitive.NewObjectID()
+		at   = time.Date(2021, 6, 1, 8, 30, 0, 123, time.UTC)
+		keys = []string{"CreatedAt", "Seq", "ID"}
+	)
+	token, err := encodePageToken(meta, keys, reflect.ValueOf(event{ID: id, Seq: 1 << 60, CreatedAt: at}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 整数、时间及ObjectID类型需原样还原，否则数据源中的比较结果会出错
+	values, err := decodePageToken(token, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{at, int64(1 << 60), id}; !reflect.DeepEqual(values, want) {
+		t.Errorf("decodePageToken() = %#v, want %#v", values, want)
+	}
+	if _, err := decodePageToken(token, []string{"Seq", "ID"}); err == nil {
+		t.Error("decodePageToken() should reject a token of different sort fields")
+	}
+	if _, err := encodePageToken(meta, keys, reflect.ValueOf(map[string]interface{}{"ID": "e1"})); err == nil {
+		t.Error("encodePageToken() should reject null sort values")
+	}
+}
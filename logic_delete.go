@@ -2,10 +2,8 @@ package db
 
 import (
 	"github.com/gobwas/glob"
-	"strconv"
 	"strings"
 	"sync"
-	"time"
 )
 
 var (
@@ -34,37 +32,7 @@ func (rule *LogicDeleteRule) ParseSetValue() map[string]interface{} {
 }
 
 func (rule *LogicDeleteRule) parseValue(val string) interface{} {
-	if val != "" {
-		var (
-			ss     = len(val)
-			vInt   = "$int"
-			vFloat = "$float"
-			vBool  = "$bool"
-		)
-		if val == "$now" {
-			return time.Now().Unix()
-		} else if val == "$now_iso" {
-			return time.Now().UTC().Format(time.RFC3339)
-		} else if strings.HasPrefix(val, vInt) {
-			s := val[len(vInt)+1 : ss-1]
-			if v, err := strconv.Atoi(s); err == nil {
-				return v
-			}
-		} else if strings.HasPrefix(val, vFloat) {
-			s := val[len(vFloat)+1 : ss-1]
-			if v, err := strconv.Atoi(s); err == nil {
-				return v
-			}
-		} else if strings.HasPrefix(val, vBool) {
-			s := val[len(vBool)+1 : ss-1]
-			if v, err := strconv.ParseBool(s); err == nil {
-				return v
-			}
-		} else {
-			return val
-		}
-	}
-	return nil
+	return parseValue(val)
 }
 
 func RegisterLogicDeleteRule(pattern string, rule *LogicDeleteRule) {
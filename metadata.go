@@ -2,6 +2,7 @@ package db
 
 import (
 	"github.com/iancoleman/strcase"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -61,6 +62,16 @@ func (m Metadata) FieldByName(name string) (f Field, has bool) {
 	return
 }
 
+// PrimaryFieldName 返回主键字段名称，未声明主键时默认为ID
+func (m Metadata) PrimaryFieldName() string {
+	for name, f := range m.Properties {
+		if ok, _ := strconv.ParseBool(f.Primary); ok {
+			return name
+		}
+	}
+	return "ID"
+}
+
 func (m Metadata) MustFieldNativeName(name string) string {
 	if f, has := m.FieldByName(name); has {
 		return f.MustNativeName()
@@ -119,6 +130,7 @@ type Field struct {
 	Unique       string
 	DefaultValue string
 	Format       string
+	Version      string // 乐观锁版本号字段
 	Relationship Relationship
 }
 
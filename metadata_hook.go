@@ -134,7 +134,20 @@ func testFieldsHook(hook *MetadataHook, action string, value interface{}) bool {
 		fieldMap[field] = true
 	}
 	if action == ActionInsertOne || action == ActionInsertMany ||
-		action == ActionUpdateOne || action == ActionUpdateMany {
+		action == ActionUpdateOne || action == ActionUpdateMany ||
+		action == ActionUpsert || action == ActionFindOneAndUpdate {
+		if u, ok := value.(*Updater); ok {
+			exists := make(map[string]bool)
+			for _, name := range hook.Fields {
+				if u.Has(name) {
+					exists[name] = true
+				}
+			}
+			if hook.FieldOperator == HookFieldOperatorOr {
+				return len(exists) > 0
+			}
+			return len(exists) == len(hook.Fields)
+		}
 		reflectValue := reflect.Indirect(reflect.ValueOf(value))
 		switch reflectValue.Kind() {
 		case reflect.Struct:
@@ -1,5 +1,11 @@
 package db
 
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
 const (
 	RelationshipHasOne  = "HAS_ONE"
 	RelationshipHasMany = "HAS_MANY"
@@ -7,6 +13,14 @@ const (
 	RelationshipRefMany = "REF_MANY"
 )
 
+// 删除主档案时对引用数据的处理规则
+const (
+	OnDeleteCascade  = "CASCADE"  // 一并删除子档案（REF_MANY同时删除中间表记录）
+	OnDeleteSetNull  = "SET_NULL" // 清空子档案中的关系字段，仅适用于HAS_ONE、HAS_MANY
+	OnDeleteRestrict = "RESTRICT" // 存在子档案或中间表记录时禁止删除
+	OnDeleteUnlink   = "UNLINK"   // 仅删除中间表记录，仅适用于REF_MANY
+)
+
 type Relationship struct {
 	Type                     string
 	SrcFieldName             string
@@ -15,4 +29,176 @@ type Relationship struct {
 	IntermediateMetadataName string
 	IntermediateSrcFieldName string
 	IntermediateDstFieldName string
+	OnDelete                 string
+}
+
+// parseRelationshipTag 解析ref属性，格式如：type:HAS_MANY,dst:UserID
+func parseRelationshipTag(tag string) (r Relationship) {
+	for _, item := range strings.Split(tag, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		var (
+			key   string
+			value string
+		)
+		if idx := strings.Index(item, ":"); idx > 0 {
+			key = item[:idx]
+			value = item[idx+1:]
+		} else {
+			key = item
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "type":
+			r.Type = strings.ToUpper(value)
+		case "meta":
+			r.MetadataName = value
+		case "src":
+			r.SrcFieldName = value
+		case "dst":
+			r.DstFieldName = value
+		case "int_meta":
+			r.IntermediateMetadataName = value
+		case "int_src", "int_meta_src":
+			r.IntermediateSrcFieldName = value
+		case "int_dst", "int_meta_dst":
+			r.IntermediateDstFieldName = value
+		case "on_delete":
+			r.OnDelete = strings.ToUpper(value)
+		}
+	}
+	return
+}
+
+// relationshipElemName 返回字段元素类型（忽略指针、数组）的结构体名称
+func relationshipElemName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		return t.Name()
+	}
+	return ""
+}
+
+// resolveRelationships 为指定元数据的关系字段填充默认值并校验引用的元数据和字段是否存在
+func resolveRelationships(names []string) error {
+	for _, name := range names {
+		meta, err := LookupMetadata(name)
+		if err != nil {
+			return err
+		}
+		// 已注册的元数据可能正被并发读取，在副本上填充后再加锁替换
+		var props Fields
+		for key, field := range meta.Properties {
+			if field.Relationship.Type == "" {
+				continue
+			}
+			r, err := resolveRelationship(meta, field)
+			if err != nil {
+				return err
+			}
+			if props == nil {
+				props = make(Fields, len(meta.Properties))
+				for k, f := range meta.Properties {
+					props[k] = f
+				}
+			}
+			field.Relationship = r
+			props[key] = field
+		}
+		if props != nil {
+			meta.Properties = props
+			meta.nativeProperties = props.nativeFields()
+			metadataMapMu.Lock()
+			metadataMap[meta.Name] = meta
+			metadataMapMu.Unlock()
+		}
+	}
+	return nil
+}
+
+func resolveRelationship(meta Metadata, field Field) (Relationship, error) {
+	r := field.Relationship
+	path := fmt.Sprintf("%s.%s", meta.Name, field.Name)
+	switch r.Type {
+	case RelationshipHasOne, RelationshipHasMany, RelationshipRefOne, RelationshipRefMany:
+	default:
+		return r, Errorf(`invalid relationship type "%s": %s`, r.Type, path)
+	}
+	if r.MetadataName == "" {
+		return r, Errorf(`missing relationship metadata: %s`, path)
+	}
+	refMeta, err := LookupMetadata(r.MetadataName)
+	if err != nil {
+		return r, Errorf(`relationship %s references unregistered metadata "%s"`, path, r.MetadataName)
+	}
+	switch r.Type {
+	case RelationshipHasOne, RelationshipHasMany:
+		if r.SrcFieldName == "" {
+			r.SrcFieldName = meta.PrimaryFieldName()
+		}
+	case RelationshipRefOne:
+		if r.DstFieldName == "" {
+			r.DstFieldName = refMeta.PrimaryFieldName()
+		}
+	case RelationshipRefMany:
+		if r.SrcFieldName == "" {
+			r.SrcFieldName = meta.PrimaryFieldName()
+		}
+		if r.DstFieldName == "" {
+			r.DstFieldName = refMeta.PrimaryFieldName()
+		}
+	}
+	if r.SrcFieldName == "" {
+		return r, Errorf(`missing relationship src field: %s`, path)
+	}
+	if r.DstFieldName == "" {
+		return r, Errorf(`missing relationship dst field: %s`, path)
+	}
+	if _, has := meta.FieldByName(r.SrcFieldName); !has {
+		return r, Errorf(`relationship %s references undefined field "%s.%s"`, path, meta.Name, r.SrcFieldName)
+	}
+	if _, has := refMeta.FieldByName(r.DstFieldName); !has {
+		return r, Errorf(`relationship %s references undefined field "%s.%s"`, path, refMeta.Name, r.DstFieldName)
+	}
+	if err := validateOnDelete(r, path); err != nil {
+		return r, err
+	}
+	if r.Type == RelationshipRefMany {
+		if r.IntermediateMetadataName == "" || r.IntermediateSrcFieldName == "" || r.IntermediateDstFieldName == "" {
+			return r, Errorf(`missing intermediate metadata settings: %s`, path)
+		}
+		intMeta, err := LookupMetadata(r.IntermediateMetadataName)
+		if err != nil {
+			return r, Errorf(`relationship %s references unregistered metadata "%s"`, path, r.IntermediateMetadataName)
+		}
+		for _, name := range []string{r.IntermediateSrcFieldName, r.IntermediateDstFieldName} {
+			if _, has := intMeta.FieldByName(name); !has {
+				return r, Errorf(`relationship %s references undefined field "%s.%s"`, path, intMeta.Name, name)
+			}
+		}
+	}
+	return r, nil
+}
+
+func validateOnDelete(r Relationship, path string) error {
+	var valid bool
+	switch r.OnDelete {
+	case "":
+		valid = true
+	case OnDeleteCascade, OnDeleteRestrict:
+		valid = r.Type != RelationshipRefOne
+	case OnDeleteSetNull:
+		valid = r.Type == RelationshipHasOne || r.Type == RelationshipHasMany
+	case OnDeleteUnlink:
+		valid = r.Type == RelationshipRefMany
+	}
+	if !valid {
+		return Errorf(`invalid on_delete rule "%s" for %s relationship: %s`, r.OnDelete, r.Type, path)
+	}
+	return nil
 }
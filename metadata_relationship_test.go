@@ -0,0 +1,210 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type relUser struct {
+	ID        string
+	RealName  string
+	IDCard    *relIDCard    `db:"ref=type:HAS_ONE,dst:UserID"`
+	BankCards []relBankCard `db:"ref=type:HAS_MANY,dst:UserID"`
+	CompanyID string
+	Company   *relCompany  `db:"ref=type:REF_ONE,src:CompanyID"`
+	Projects  []relProject `db:"ref=type:REF_MANY,int_meta:relUserProject,int_src:UserID,int_dst:ProjectID"`
+}
+
+type relIDCard struct {
+	ID      string
+	CardNum string
+	UserID  string
+	User    *relUser `db:"ref=type:REF_ONE,src:UserID"`
+}
+
+type relBankCard struct {
+	ID      string
+	CardNum string
+	UserID  string
+}
+
+type relCompany struct {
+	ID   string
+	Name string
+}
+
+type relProject struct {
+	ID   string
+	Name string
+}
+
+type relUserProject struct {
+	UserID    string
+	ProjectID string
+}
+
+func TestParseRelationshipTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want Relationship
+	}{
+		{
+			tag:  "type:HAS_MANY,dst:UserID",
+			want: Relationship{Type: RelationshipHasMany, DstFieldName: "UserID"},
+		},
+		{
+			tag:  "type:ref_one, src:CompanyID, meta:Company",
+			want: Relationship{Type: RelationshipRefOne, SrcFieldName: "CompanyID", MetadataName: "Company"},
+		},
+		{
+			tag: "type:REF_MANY,int_meta:UserProjectRef,int_src:UserID,int_dst:ProjectID",
+			want: Relationship{
+				Type:                     RelationshipRefMany,
+				IntermediateMetadataName: "UserProjectRef",
+				IntermediateSrcFieldName: "UserID",
+				IntermediateDstFieldName: "ProjectID",
+			},
+		},
+		{
+			tag: "type:REF_MANY,int_meta:UserProjectRef,int_meta_src:UserID,int_meta_dst:ProjectID",
+			want: Relationship{
+				Type:                     RelationshipRefMany,
+				IntermediateMetadataName: "UserProjectRef",
+				IntermediateSrcFieldName: "UserID",
+				IntermediateDstFieldName: "ProjectID",
+			},
+		},
+		{
+			tag:  "type:HAS_MANY,dst:UserID,on_delete:set_null",
+			want: Relationship{Type: RelationshipHasMany, DstFieldName: "UserID", OnDelete: OnDeleteSetNull},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := parseStructFieldTag("ref=" + tt.tag).Relationship; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRelationshipTag() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterRelationshipMetadata(t *testing.T) {
+	var conn Connection
+	if err := conn.RegisterMetadata(&relUser{}, &relIDCard{}, &relBankCard{}, &relCompany{}, &relProject{}, &relUserProject{}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, name := range []string{"relUser", "relIDCard", "relBankCard", "relCompany", "relProject", "relUserProject"} {
+			UnregisterMetadata(name)
+		}
+	}()
+
+	meta, err := LookupMetadata("relUser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		field    string
+		wantType string
+		want     Relationship
+	}{
+		{
+			field:    "IDCard",
+			wantType: Object,
+			want:     Relationship{Type: RelationshipHasOne, MetadataName: "relIDCard", SrcFieldName: "ID", DstFieldName: "UserID"},
+		},
+		{
+			field:    "BankCards",
+			wantType: Array,
+			want:     Relationship{Type: RelationshipHasMany, MetadataName: "relBankCard", SrcFieldName: "ID", DstFieldName: "UserID"},
+		},
+		{
+			field:    "Company",
+			wantType: Object,
+			want:     Relationship{Type: RelationshipRefOne, MetadataName: "relCompany", SrcFieldName: "CompanyID", DstFieldName: "ID"},
+		},
+		{
+			field:    "Projects",
+			wantType: Array,
+			want: Relationship{
+				Type:                     RelationshipRefMany,
+				MetadataName:             "relProject",
+				SrcFieldName:             "ID",
+				DstFieldName:             "ID",
+				IntermediateMetadataName: "relUserProject",
+				IntermediateSrcFieldName: "UserID",
+				IntermediateDstFieldName: "ProjectID",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			f, has := meta.FieldByName(tt.field)
+			if !has {
+				t.Fatalf("missing field %s", tt.field)
+			}
+			if f.Type != tt.wantType {
+				t.Errorf("field type = %s, want %s", f.Type, tt.wantType)
+			}
+			if !reflect.DeepEqual(f.Relationship, tt.want) {
+				t.Errorf("relationship = %+v, want %+v", f.Relationship, tt.want)
+			}
+		})
+	}
+}
+
+type relBadMeta struct {
+	ID      string
+	Company *relMissing `db:"ref=type:REF_ONE,src:ID"`
+}
+
+type relMissing struct {
+	ID string
+}
+
+type relBadOnDelete struct {
+	ID    string
+	Cards []relBankCard `db:"ref=type:HAS_MANY,dst:UserID,on_delete:unlink"`
+}
+
+type relBadField struct {
+	ID    string
+	Cards []relBankCard `db:"ref=type:HAS_MANY,dst:OwnerID"`
+}
+
+func TestRegisterRelationshipMetadataErrors(t *testing.T) {
+	var conn Connection
+	if err := conn.RegisterMetadata(&relBankCard{}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterMetadata("relBankCard")
+
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: &relBadMeta{}, want: `unregistered metadata "relMissing"`},
+		{value: &relBadField{}, want: `undefined field "relBankCard.OwnerID"`},
+		{value: &relBadOnDelete{}, want: `invalid on_delete rule "UNLINK" for HAS_MANY relationship`},
+	}
+	for _, tt := range tests {
+		name := reflect.TypeOf(tt.value).Elem().Name()
+		t.Run(name, func(t *testing.T) {
+			err := conn.RegisterMetadata(tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("RegisterMetadata() error = %v, want %s", err, tt.want)
+			}
+			if HasModel(name) {
+				t.Errorf("metadata %s should not be registered", name)
+			}
+		})
+	}
+	// 同批中已注册的元数据恢复为注册前的定义
+	if err := conn.RegisterMetadata(&relBankCard{}, &relBadMeta{}); err == nil {
+		t.Fatal("RegisterMetadata() should fail")
+	}
+	if !HasModel("relBankCard") || HasModel("relBadMeta") {
+		t.Errorf("RegisterMetadata() rollback = relBankCard %v, relBadMeta %v, want true, false", HasModel("relBankCard"), HasModel("relBadMeta"))
+	}
+}
@@ -82,6 +82,38 @@ func WithDeleteOptionDeleteAssocs(v bool) func(opts *DeleteOptions) {
 	}
 }
 
+// WithTransactionOptionRetryPolicy 指定本次事务的重试策略，传入nil时由适配器决定是否重试
+func WithTransactionOptionRetryPolicy(policy *RetryPolicy) func(opts *TransactionOptions) {
+	return func(opts *TransactionOptions) {
+		opts.RetryPolicy = policy
+	}
+}
+
+// WithQueryOptionReturnNotFound 未查询到记录时返回ErrNotFound，默认返回nil且不修改目标
+func WithQueryOptionReturnNotFound(v bool) func(opts *QueryOptions) {
+	return func(opts *QueryOptions) {
+		opts.ReturnNotFound = v
+	}
+}
+
+// NotFound 按查询选项返回未查询到记录时的错误，供适配器实现Result.One、FindOneAndUpdate、FindOneAndDelete等方法
+func NotFound(fns ...func(*QueryOptions)) error {
+	var opts QueryOptions
+	for _, fn := range fns {
+		if fn != nil {
+			fn(&opts)
+		}
+	}
+	if opts.ReturnNotFound {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type QueryOptions struct {
+	ReturnNotFound bool
+}
+
 type InsertOptions struct {
 	AssocTypeMap map[string]string
 	LooseMode    bool
@@ -99,6 +131,10 @@ type DeleteOptions struct {
 	DeleteAssocs bool
 }
 
+type TransactionOptions struct {
+	RetryPolicy *RetryPolicy
+}
+
 type PreloadOptions struct {
 	Path     string
 	Match    Conditional
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"crypto/rand"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	valueGeneratorMap   = make(map[string]func() interface{})
+	valueGeneratorMapMu sync.RWMutex
+)
+
+// RegisterValueGenerator 注册自定义占位符，name需以$开头，可覆盖内置占位符
+func RegisterValueGenerator(name string, fn func() interface{}) error {
+	name = strings.TrimSpace(name)
+	if !strings.HasPrefix(name, "$") || len(name) == 1 {
+		return Errorf(`value generator name must start with "$": %s`, name)
+	}
+	if fn == nil {
+		return Errorf(`value generator %s is nil`, name)
+	}
+	valueGeneratorMapMu.Lock()
+	defer valueGeneratorMapMu.Unlock()
+
+	valueGeneratorMap[name] = fn
+	return nil
+}
+
+func UnregisterValueGenerator(name string) {
+	valueGeneratorMapMu.Lock()
+	defer valueGeneratorMapMu.Unlock()
+
+	delete(valueGeneratorMap, strings.TrimSpace(name))
+}
+
+func lookupValueGenerator(name string) func() interface{} {
+	valueGeneratorMapMu.RLock()
+	defer valueGeneratorMapMu.RUnlock()
+
+	return valueGeneratorMap[name]
+}
+
+// parseValue 解析占位符，支持$now、$now_iso、$uuid、$objectid、$int(..)、$float(..)、$bool(..)及自定义占位符，
+// 非占位符原样返回，无法解析时返回nil
+func parseValue(val string) interface{} {
+	if val == "" {
+		return nil
+	}
+	if fn := lookupValueGenerator(val); fn != nil {
+		return fn()
+	}
+	switch val {
+	case "$now":
+		return time.Now().Unix()
+	case "$now_iso":
+		return time.Now().UTC().Format(time.RFC3339)
+	case "$uuid":
+		return newUUID()
+	case "$objectid":
+		return primitive.NewObjectID().Hex()
+	}
+	if name, arg, ok := parseCallPlaceholder(val); ok {
+		switch name {
+		case "$int":
+			if v, err := strconv.Atoi(arg); err == nil {
+				return v
+			}
+			return nil
+		case "$float":
+			if v, err := strconv.ParseFloat(arg, 64); err == nil {
+				return v
+			}
+			return nil
+		case "$bool":
+			if v, err := strconv.ParseBool(arg); err == nil {
+				return v
+			}
+			return nil
+		}
+	}
+	return val
+}
+
+// parseCallPlaceholder 拆分$name(arg)格式的占位符
+func parseCallPlaceholder(val string) (name, arg string, ok bool) {
+	idx := strings.Index(val, "(")
+	if !strings.HasPrefix(val, "$") || idx < 0 || !strings.HasSuffix(val, ")") {
+		return "", "", false
+	}
+	return val[:idx], strings.TrimSpace(val[idx+1 : len(val)-1]), true
+}
+
+// newUUID 生成随机UUID（版本4）
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(Errorf("generate uuid failed: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		val  string
+		want interface{}
+	}{
+		{"", nil},
+		{"foo", "foo"},
+		{"$int(3)", 3},
+		{"$int(x)", nil},
+		{"$int", "$int"},
+		{"$float(1.5)", 1.5},
+		{"$bool(true)", true},
+	}
+	for _, tt := range tests {
+		if got := parseValue(tt.val); got != tt.want {
+			t.Errorf("parseValue(%q) = %v, want %v", tt.val, got, tt.want)
+		}
+	}
+	if v, ok := parseValue("$now").(int64); !ok || time.Now().Unix()-v > 1 {
+		t.Errorf("parseValue($now) = %v", v)
+	}
+	if v, _ := parseValue("$uuid").(string); !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(v) {
+		t.Errorf("parseValue($uuid) = %v", v)
+	}
+	if v, _ := parseValue("$objectid").(string); len(v) != 24 {
+		t.Errorf("parseValue($objectid) = %v", v)
+	}
+
+	if err := RegisterValueGenerator("now", func() interface{} { return 0 }); err == nil {
+		t.Error("RegisterValueGenerator() without $ prefix should fail")
+	}
+	_ = RegisterValueGenerator("$now", func() interface{} { return int64(1) })
+	defer UnregisterValueGenerator("$now")
+	if v := parseValue("$now"); v != int64(1) {
+		t.Errorf("parseValue($now) with custom generator = %v, want 1", v)
+	}
+}
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		typ  string
+		v    interface{}
+		want interface{}
+	}{
+		{Int, "42", int64(42)},
+		{Float, 3, float64(3)},
+		{Bool, "true", true},
+		{String, int64(7), "7"},
+		{Datetime, int64(0), time.Unix(0, 0)},
+	}
+	for _, tt := range tests {
+		if got, err := coerceValue(tt.typ, tt.v); err != nil || got != tt.want {
+			t.Errorf("coerceValue(%s, %v) = %v, %v, want %v", tt.typ, tt.v, got, err, tt.want)
+		}
+	}
+	if _, err := coerceValue(Int, "abc"); err == nil {
+		t.Error("coerceValue(int, abc) should fail")
+	}
+}
@@ -0,0 +1,1159 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iamdanielyin/db"
+	_ "github.com/iamdanielyin/db/adapter/memory"
+)
+
+type Author struct {
+	ID        string
+	Name      string
+	Status    int
+	Profile   *Profile `db:"ref=type:HAS_ONE,dst:AuthorID,on_delete:cascade"`
+	Books     []Book   `db:"ref=type:HAS_MANY,dst:AuthorID,on_delete:set_null"`
+	Tags      []Tag    `db:"ref=type:REF_MANY,int_meta:AuthorTag,int_src:AuthorID,int_dst:TagID,on_delete:unlink"`
+	DeletedAt int64
+}
+
+type Profile struct {
+	ID       string
+	Bio      string
+	AuthorID string
+}
+
+type Book struct {
+	ID       string
+	Title    string
+	AuthorID string
+	Author   *Author `db:"ref=type:REF_ONE,src:AuthorID"`
+}
+
+type Tag struct {
+	ID   string
+	Name string
+}
+
+type AuthorTag struct {
+	AuthorID string
+	TagID    string
+}
+
+// connectMemory 连接以测试名称命名的内存数据源并注册元数据，测试结束时注销元数据
+func connectMemory(t *testing.T, models ...interface{}) *db.Connection {
+	sess, err := db.Connect(db.DataSource{Name: t.Name(), Adapter: "memory", URI: "memory://" + t.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.RegisterMetadata(models...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, m := range models {
+			if meta, ok := m.(db.Metadata); ok {
+				db.UnregisterMetadata(meta.Name)
+			} else {
+				db.UnregisterMetadata(reflect.Indirect(reflect.ValueOf(m)).Type().Name())
+			}
+		}
+	})
+	return sess
+}
+
+func setupMemory(t *testing.T) {
+	connectMemory(t, &Author{}, &Profile{}, &Book{}, &Tag{}, &AuthorTag{})
+	db.RegisterLogicDeleteRule("Author", &db.LogicDeleteRule{
+		SetValue: map[string]string{"DeletedAt": "$now"},
+		GetValue: db.Cond{"DeletedAt $exists": false},
+	})
+}
+
+type Memo struct {
+	ID      string
+	Content string
+	Status  int
+}
+
+func TestMemoryHooks(t *testing.T) {
+	connectMemory(t, &Memo{})
+	_ = db.RegisterMiddleware("Memo:beforeCreate", func(s *db.Scope) {
+		if doc, ok := s.InsertOneDoc.(*Memo); ok && doc.Status == 0 {
+			doc.Status = 1
+		}
+	})
+	_ = db.RegisterMiddleware("Memo:beforeUpdate", func(s *db.Scope) {
+		s.AddError(errors.New("readonly"))
+	})
+
+	if _, err := db.Model("Memo").InsertOne(&Memo{ID: "m1", Content: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	var memo Memo
+	if err := db.Model("Memo").Find(db.Cond{"ID": "m1"}).One(&memo); err != nil {
+		t.Fatal(err)
+	}
+	if memo.Status != 1 {
+		t.Errorf("Status = %d, want 1", memo.Status)
+	}
+	if _, err := db.Model("Memo").Find(db.Cond{"ID": "m1"}).UpdateOne(&Memo{Content: "bar"}); err == nil {
+		t.Error("UpdateOne() should be rejected by the middleware")
+	}
+	if err := db.Model("Memo").Find(db.Cond{"ID": "m1"}).FindOneAndUpdate(&Memo{Content: "bar"}, &memo, true); err == nil {
+		t.Error("FindOneAndUpdate() should be rejected by the middleware")
+	}
+	if _, err := db.Model("Memo").Find(db.Cond{"ID": "m2"}).Upsert(&Memo{Content: "bar"}); err == nil {
+		t.Error("Upsert() should be rejected by the middleware")
+	}
+	if err := db.Model("Memo").Find(db.Cond{"ID": "m1"}).One(&memo); err != nil || memo.Content != "foo" {
+		t.Errorf("Content = %q, %v", memo.Content, err)
+	}
+
+	// 批量写入时钩子按操作分别执行
+	_, err := db.Model("Memo").BulkWrite([]db.WriteOp{
+		db.InsertOp(&Memo{ID: "m2", Content: "baz"}),
+		db.UpdateOneOp(&Memo{Content: "bar"}, db.Cond{"ID": "m1"}),
+	}, false)
+	var bwe *db.BulkWriteError
+	if !errors.As(err, &bwe) || len(bwe.Errors) != 1 || bwe.Errors[0].Index != 1 {
+		t.Fatalf("BulkWrite() error = %v, want operation 1 rejected", err)
+	}
+	if err := db.Model("Memo").Find(db.Cond{"ID": "m2"}).One(&memo); err != nil || memo.Status != 1 {
+		t.Errorf("BulkWrite() inserted %+v, %v, want Status 1", memo, err)
+	}
+}
+
+type Account struct {
+	ID          string
+	Name        string
+	Level       int
+	Status      string
+	Phone       string
+	CountryCode string
+	Email       string
+}
+
+func TestMemoryValidate(t *testing.T) {
+	connectMemory(t, db.Metadata{
+		Name: "Account",
+		Properties: db.Fields{
+			"ID":          {Type: db.String},
+			"Name":        {Type: db.String, DisplayName: "名称", Required: "true", Trim: "true"},
+			"Level":       {Type: db.Int},
+			"Phone":       {Type: db.String, Required: "contact"},
+			"CountryCode": {Type: db.String, Required: "+Phone"},
+			"Email":       {Type: db.String, Required: "contact"},
+			"Status": {Type: db.String, DisplayName: "状态", Enum: db.Enum{
+				{Label: "启用", Value: "on"},
+				{Label: "停用", Value: "off"},
+			}},
+		},
+	})
+	model := db.Model("Account")
+
+	_, err := model.InsertOne(map[string]interface{}{"ID": "a0", "Level": "high", "Status": "bad"})
+	var ve *db.ValidationError
+	if !errors.Is(err, db.ErrValidation) || !errors.As(err, &ve) || len(ve.Errors) != 5 {
+		t.Fatalf("InsertOne() error = %v, want 5 invalid fields", err)
+	}
+	if ve.Field("Email") == nil || ve.Field("Phone") == nil || ve.Errors[2].DisplayName != "名称" || ve.Field("CountryCode") != nil {
+		t.Errorf("ValidationError = %v", ve)
+	}
+
+	if _, err := model.InsertOne(&Account{ID: "a1", Name: "foo", Phone: "123"}); !errors.As(err, &ve) || len(ve.Errors) != 1 || ve.Field("CountryCode") == nil {
+		t.Errorf("InsertOne() without CountryCode error = %v", err)
+	}
+	doc := &Account{ID: "a1", Name: "  foo ", Status: "on", Email: "foo@example.com"}
+	if _, err := model.InsertOne(doc); err != nil {
+		t.Fatal(err)
+	}
+	var acct Account
+	if err := model.Find(db.Cond{"ID": "a1"}).One(&acct); err != nil || acct.Name != "foo" || doc.Name != "foo" {
+		t.Errorf("InsertOne() trimmed Name = %q (doc %q), %v", acct.Name, doc.Name, err)
+	}
+
+	// 修改时仅校验传入的字段
+	if _, err := model.Find(db.Cond{"ID": "a1"}).UpdateOne(map[string]interface{}{"Level": 2, "Phone": ""}); err != nil {
+		t.Errorf("UpdateOne() without required fields error = %v", err)
+	}
+	if _, err := model.Find(db.Cond{"ID": "a1"}).UpdateOne(map[string]interface{}{"Name": " "}); !errors.As(err, &ve) || ve.Field("Name") == nil {
+		t.Errorf("UpdateOne() with blank Name error = %v", err)
+	}
+	if _, err := model.Find(db.Cond{"ID": "a1"}).UpdateOne(db.Update().Set("Status", "bad").Unset("Name")); !errors.As(err, &ve) || len(ve.Errors) != 2 {
+		t.Errorf("UpdateOne(Updater) error = %v, want 2 invalid fields", err)
+	}
+
+	// 裁剪空白不修改传入的修改内容
+	rename := db.Update().Set("Name", "  baz ")
+	for i := 0; i < 2; i++ {
+		if _, err := model.Find(db.Cond{"ID": "a1"}).UpdateOne(rename); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if entries := rename.Entries(); entries[0].Value != "  baz " {
+		t.Errorf("UpdateOne() modified Updater value to %q", entries[0].Value)
+	}
+	if err := model.Find(db.Cond{"ID": "a1"}).One(&acct); err != nil || acct.Name != "baz" {
+		t.Errorf("UpdateOne(Updater) trimmed Name = %q, %v", acct.Name, err)
+	}
+
+	_, err = model.InsertMany([]Account{{ID: "a2", Name: "bar", Phone: "456", CountryCode: "86"}, {ID: "a3"}})
+	if !errors.As(err, &ve) || ve.Index != 1 {
+		t.Errorf("InsertMany() error = %v, want document 1 invalid", err)
+	}
+	if n, _ := model.Find().Count(); n != 1 {
+		t.Errorf("Count() = %d, want 1", n)
+	}
+
+	_, err = model.BulkWrite([]db.WriteOp{db.InsertOp(&Account{ID: "a4", Email: "bar@example.com"})}, true)
+	var bwe *db.BulkWriteError
+	if !errors.As(err, &bwe) || !errors.Is(bwe.Err(0), db.ErrValidation) {
+		t.Errorf("BulkWrite() error = %v, want ErrValidation", err)
+	}
+}
+
+type Ticket struct {
+	ID        string    `db:"pk;default=$uuid"`
+	Code      string    `db:"default=$seq"`
+	Ref       string    `db:"default=$objectid"`
+	Status    string    `db:"default=open"`
+	Priority  int       `db:"default=$int(3)"`
+	Score     float64   `db:"default=$float(1.5)"`
+	Active    bool      `db:"default=$bool(true)"`
+	CreatedAt time.Time `db:"type=datetime;default=$now"`
+}
+
+func TestMemoryDefaultValue(t *testing.T) {
+	connectMemory(t, &Ticket{})
+	var seq int
+	if err := db.RegisterValueGenerator("$seq", func() interface{} {
+		seq++
+		return fmt.Sprintf("T%03d", seq)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.UnregisterValueGenerator("$seq")
+	})
+	model := db.Model("Ticket")
+
+	doc := &Ticket{Priority: 5}
+	if _, err := model.InsertOne(doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.ID) != 36 || doc.Code != "T001" || len(doc.Ref) != 24 || doc.Status != "open" || doc.Priority != 5 ||
+		doc.Score != 1.5 || !doc.Active || doc.CreatedAt.IsZero() {
+		t.Errorf("InsertOne() defaults = %+v", doc)
+	}
+
+	m := map[string]interface{}{"ID": "t2", "Status": "closed"}
+	if _, err := model.InsertOne(m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 {
+		t.Errorf("InsertOne(map) modified caller's map: %v", m)
+	}
+	var ticket Ticket
+	if err := model.Find(db.Cond{"ID": "t2"}).One(&ticket); err != nil {
+		t.Fatal(err)
+	}
+	if ticket.Code != "T002" || ticket.Status != "closed" || ticket.Priority != 3 || !ticket.Active {
+		t.Errorf("InsertOne(map) defaults = %+v", ticket)
+	}
+
+	if _, err := model.InsertMany([]Ticket{{ID: "t3"}, {ID: "t4", Code: "X"}}); err != nil {
+		t.Fatal(err)
+	}
+	var tickets []Ticket
+	if err := model.Find(db.Cond{}.In("ID", []string{"t3", "t4"})).OrderBy("ID").All(&tickets); err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 2 || tickets[0].Code != "T003" || tickets[1].Code != "X" || tickets[1].Status != "open" {
+		t.Errorf("InsertMany() defaults = %+v", tickets)
+	}
+
+	docs := []map[string]interface{}{{"ID": "t5"}}
+	if _, err := model.InsertMany(docs); err != nil {
+		t.Fatal(err)
+	}
+	if len(docs[0]) != 1 {
+		t.Errorf("InsertMany(maps) modified caller's map: %v", docs[0])
+	}
+	if err := model.Find(db.Cond{"ID": "t5"}).One(&ticket); err != nil {
+		t.Fatal(err)
+	}
+	if ticket.Code != "T004" || ticket.Status != "open" {
+		t.Errorf("InsertMany(maps) defaults = %+v", ticket)
+	}
+}
+
+type Post struct {
+	ID        string
+	Title     string
+	CreatedAt time.Time `db:"type=datetime"`
+	UpdatedAt int64
+	CreatedBy string
+	UpdatedBy string
+	DeletedAt int64
+}
+
+func TestMemoryTimestamps(t *testing.T) {
+	connectMemory(t, &Post{})
+	db.RegisterTimestampsRule("*", &db.TimestampsRule{UpdatedAt: "UpdatedAt"})
+	db.RegisterTimestampsRule("Po*", &db.TimestampsRule{
+		CreatedAt: "CreatedAt",
+		UpdatedAt: "UpdatedAt",
+		CreatedBy: "CreatedBy",
+		UpdatedBy: "UpdatedBy",
+	})
+	db.RegisterLogicDeleteRule("Post", &db.LogicDeleteRule{
+		SetValue: map[string]string{"DeletedAt": "$now"},
+		GetValue: db.Cond{"DeletedAt $exists": false},
+	})
+	t.Cleanup(func() {
+		db.UnregisterTimestampsRule("*")
+		db.UnregisterTimestampsRule("Po*")
+	})
+	if rule := db.LookupTimestampsRule("Post"); rule == nil || rule.Pattern != "Po*" {
+		t.Fatalf("LookupTimestampsRule() = %+v, want Po*", rule)
+	}
+	model := func(actor string) db.Collection {
+		return db.Model("Post").WithContext(db.WithActor(context.Background(), actor))
+	}
+	find := func(id string) (post Post) {
+		if err := db.Model("Post").Find(db.Cond{"ID": id}).Unscoped().One(&post); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	doc := &Post{ID: "p1", Title: "foo"}
+	if _, err := model("alice").InsertOne(doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.CreatedAt.IsZero() || doc.UpdatedAt == 0 || doc.CreatedBy != "alice" || doc.UpdatedBy != "alice" {
+		t.Errorf("InsertOne() stamps = %+v", doc)
+	}
+	if _, err := db.Model("Post").InsertMany([]Post{{ID: "p2", CreatedBy: "import"}}); err != nil {
+		t.Fatal(err)
+	}
+	if post := find("p2"); post.CreatedBy != "import" || post.UpdatedBy != "" || post.UpdatedAt == 0 {
+		t.Errorf("InsertMany() stamps = %+v", post)
+	}
+	insert := map[string]interface{}{"ID": "p3"}
+	if _, err := model("alice").InsertOne(insert); err != nil {
+		t.Fatal(err)
+	}
+	if post := find("p3"); post.CreatedAt.IsZero() || post.CreatedBy != "alice" || len(insert) != 1 {
+		t.Errorf("InsertOne(map) stamps = %+v, doc = %v", post, insert)
+	}
+
+	update := map[string]interface{}{"Title": "bar"}
+	if _, err := model("bob").Find(db.Cond{"ID": "p1"}).UpdateOne(update); err != nil {
+		t.Fatal(err)
+	}
+	if post := find("p1"); post.Title != "bar" || post.CreatedBy != "alice" || post.UpdatedBy != "bob" || len(update) != 1 {
+		t.Errorf("UpdateOne() stamps = %+v, doc = %v", post, update)
+	}
+	if _, err := model("bob").Find(db.Cond{"ID": "p1"}).UpdateOne(db.Update().Set("UpdatedBy", "mallory")); err != nil {
+		t.Fatal(err)
+	}
+	if post := find("p1"); post.UpdatedBy != "bob" {
+		t.Errorf("UpdateOne(Updater) UpdatedBy = %q, want bob", post.UpdatedBy)
+	}
+
+	// Upsert新增记录时写入创建时间及创建人，修改已有记录时保留
+	if res, err := model("dave").Find(db.Cond{"ID": "p4"}).Upsert(map[string]interface{}{"Title": "baz"}); err != nil || !res.Inserted() {
+		t.Fatalf("Upsert() = %v, %v, want inserted", res, err)
+	}
+	if post := find("p4"); post.CreatedAt.IsZero() || post.CreatedBy != "dave" || post.UpdatedBy != "dave" {
+		t.Errorf("Upsert() insert stamps = %+v", post)
+	}
+	if _, err := model("erin").Find(db.Cond{"ID": "p4"}).Upsert(&Post{Title: "qux"}); err != nil {
+		t.Fatal(err)
+	}
+	if post := find("p4"); post.Title != "qux" || post.CreatedBy != "dave" || post.UpdatedBy != "erin" {
+		t.Errorf("Upsert() update stamps = %+v", post)
+	}
+
+	// 逻辑删除同样写入修改时间及操作人
+	if _, err := model("carol").Find(db.Cond{"ID": "p1"}).DeleteOne(); err != nil {
+		t.Fatal(err)
+	}
+	if post := find("p1"); post.DeletedAt == 0 || post.UpdatedBy != "carol" {
+		t.Errorf("DeleteOne() stamps = %+v", post)
+	}
+}
+
+type Article struct {
+	ID      string
+	Title   string
+	Version int `db:"version"`
+}
+
+func TestMemoryOptimisticLock(t *testing.T) {
+	connectMemory(t, &Article{})
+	model := db.Model("Article")
+	if name := model.Metadata().VersionFieldName(); name != "Version" {
+		t.Fatalf("VersionFieldName() = %q, want Version", name)
+	}
+
+	if _, err := model.InsertOne(&Article{ID: "a1", Title: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	var first, second Article
+	_ = model.Find(db.Cond{"ID": "a1"}).One(&first)
+	_ = model.Find(db.Cond{"ID": "a1"}).One(&second)
+	if first.Version != 1 {
+		t.Fatalf("InsertOne() Version = %d, want 1", first.Version)
+	}
+
+	first.Title = "bar"
+	if n, err := model.Find(db.Cond{"ID": "a1"}).UpdateOne(&first); err != nil || n != 1 || first.Version != 2 {
+		t.Fatalf("UpdateOne() = %d, %v, Version = %d, want 1, nil, 2", n, err, first.Version)
+	}
+	second.Title = "baz"
+	if _, err := model.Find(db.Cond{"ID": "a1"}).UpdateOne(&second); !errors.Is(err, db.ErrStaleObject) || second.Version != 1 {
+		t.Errorf("UpdateOne() with stale version error = %v, Version = %d, want ErrStaleObject, 1", err, second.Version)
+	}
+	if _, err := model.Find(db.Cond{"ID": "a1"}).UpdateOne(map[string]interface{}{"Title": "qux", "Version": 1}); !errors.Is(err, db.ErrStaleObject) {
+		t.Errorf("UpdateOne(map) with stale version error = %v, want ErrStaleObject", err)
+	}
+	if _, err := model.Find(db.Cond{"ID": "a1"}).UpdateOne(db.Update().Set("Title", "qux").Set("Version", 2)); err != nil {
+		t.Errorf("UpdateOne(Updater) error = %v", err)
+	}
+
+	var got Article
+	if err := model.Find(db.Cond{"ID": "a1"}).One(&got); err != nil || got.Title != "qux" || got.Version != 3 {
+		t.Errorf("One() = %+v, %v, want Title qux and Version 3", got, err)
+	}
+	// 未传入版本号时不检查是否匹配，但仍递增版本号，持有旧版本号的修改会失败
+	if _, err := model.Find(db.Cond{"ID": "a1"}).UpdateOne(&Article{Title: "quux"}); err != nil {
+		t.Errorf("UpdateOne() without version error = %v", err)
+	}
+	if _, err := model.Find(db.Cond{"ID": "a1"}).UpdateOne(&got); !errors.Is(err, db.ErrStaleObject) {
+		t.Errorf("UpdateOne() after an unversioned update error = %v, want ErrStaleObject", err)
+	}
+
+	var found Article
+	err := model.Find(db.Cond{"ID": "a1"}).FindOneAndUpdate(db.Update().Set("Title", "x").Set("Version", 3), &found, true)
+	if !errors.Is(err, db.ErrStaleObject) || found.ID != "" {
+		t.Errorf("FindOneAndUpdate() with stale version = %+v, %v, want ErrStaleObject", found, err)
+	}
+	if err := model.Find(db.Cond{"ID": "a1"}).FindOneAndUpdate(&Article{Title: "x", Version: 4}, &found, true); err != nil || found.Version != 5 {
+		t.Errorf("FindOneAndUpdate() = %+v, %v, want Version 5", found, err)
+	}
+
+	res, err := model.Find(db.Cond{"ID": "a2"}).Upsert(&Article{Title: "new"})
+	if err != nil || !res.Inserted() {
+		t.Fatalf("Upsert() = %v, %v, want inserted", res, err)
+	}
+	if _, err := model.Find(db.Cond{"ID": "a2"}).Upsert(map[string]interface{}{"Title": "old", "Version": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Find(db.Cond{"ID": "a2"}).One(&got); err != nil || got.Title != "old" || got.Version != 2 {
+		t.Errorf("Upsert() = %+v, %v, want Title old and Version 2", got, err)
+	}
+
+	// 批量写入中版本号不匹配的操作单独报告ErrStaleObject
+	fresh := &Article{ID: "a2", Title: "bulk", Version: 2}
+	bres, err := model.BulkWrite([]db.WriteOp{
+		db.UpdateOneOp(&Article{Title: "stale", Version: 1}, db.Cond{"ID": "a1"}),
+		db.UpdateOneOp(fresh, db.Cond{"ID": "a2"}),
+		db.UpdateOneOp(map[string]interface{}{"Title": "any"}, db.Cond{"ID": "a1"}),
+	}, false)
+	var bwe *db.BulkWriteError
+	if !errors.As(err, &bwe) || len(bwe.Errors) != 1 || !errors.Is(bwe.Err(0), db.ErrStaleObject) {
+		t.Fatalf("BulkWrite() error = %v, want operation 0 stale", err)
+	}
+	if bres.UpdatedCount != 2 || fresh.Version != 3 {
+		t.Errorf("BulkWrite() UpdatedCount = %d, Version = %d, want 2, 3", bres.UpdatedCount, fresh.Version)
+	}
+	if err := model.Find(db.Cond{"ID": "a1"}).One(&got); err != nil || got.Title != "any" || got.Version != 6 {
+		t.Errorf("BulkWrite() = %+v, %v, want Title any and Version 6", got, err)
+	}
+}
+
+type Event struct {
+	ID   string
+	Kind int
+}
+
+func TestMemoryScroll(t *testing.T) {
+	connectMemory(t, &Event{})
+	model := db.Model("Event")
+	events := []Event{{"e5", 2}, {"e1", 1}, {"e7", 3}, {"e3", 1}, {"e2", 2}, {"e6", 1}, {"e4", 2}}
+	if _, err := model.InsertMany(events); err != nil {
+		t.Fatal(err)
+	}
+	scroll := func(res db.Result) ([]string, db.PageTokens) {
+		t.Helper()
+		var items []Event
+		tokens, err := res.Scroll(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, len(items))
+		for i, item := range items {
+			ids[i] = item.ID
+		}
+		return ids, tokens
+	}
+
+	// 排序字段重复时按主键排序，跨页不重复也不遗漏
+	pages := [][]string{{"e1", "e3", "e6"}, {"e2", "e4", "e5"}, {"e7"}}
+	var tokens []db.PageTokens
+	for i, want := range pages {
+		res := model.Find().OrderBy("Kind").Paginate(3)
+		if i > 0 {
+			res.After(tokens[i-1].Next)
+		}
+		got, tk := scroll(res)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("page %d = %v, want %v", i+1, got, want)
+		}
+		tokens = append(tokens, tk)
+	}
+	if tokens[2].Next != "" {
+		t.Errorf("last page Next = %q, want empty", tokens[2].Next)
+	}
+	for i := len(pages) - 1; i > 0; i-- {
+		got, tk := scroll(model.Find().OrderBy("Kind").Paginate(3).Before(tokens[i].Prev))
+		if !reflect.DeepEqual(got, pages[i-1]) {
+			t.Errorf("Before(page %d) = %v, want %v", i+1, got, pages[i-1])
+		}
+		tokens[i-1] = tk
+	}
+	if tokens[0].Prev != "" {
+		t.Errorf("first page Prev = %q, want empty", tokens[0].Prev)
+	}
+
+	// 倒序时同样以主键升序区分重复的排序字段
+	got, tk := scroll(model.Find().OrderBy("-Kind").Paginate(2))
+	if !reflect.DeepEqual(got, []string{"e7", "e2"}) {
+		t.Errorf("page 1 desc = %v, want [e7 e2]", got)
+	}
+	if got, _ = scroll(model.Find().OrderBy("-Kind").Paginate(2).After(tk.Next)); !reflect.DeepEqual(got, []string{"e4", "e5"}) {
+		t.Errorf("page 2 desc = %v, want [e4 e5]", got)
+	}
+}
+
+func TestMemoryLogicDelete(t *testing.T) {
+	setupMemory(t)
+	if _, err := db.Model("Author").InsertMany([]Author{{ID: "a1", Name: "foo"}, {ID: "a2", Name: "bar"}}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.Model("Author").Find(db.Cond{"ID": "a1"}).DeleteOne(); err != nil || n != 1 {
+		t.Fatalf("DeleteOne() = %d, %v", n, err)
+	}
+	if n, _ := db.Model("Author").Find().Count(); n != 1 {
+		t.Errorf("Count() = %d, want 1", n)
+	}
+	if n, _ := db.Model("Author").Find().Unscoped().Count(); n != 2 {
+		t.Errorf("Unscoped().Count() = %d, want 2", n)
+	}
+	var names []string
+	if err := db.Model("Author").Find().Distinct("Name", &names); err != nil || !reflect.DeepEqual(names, []string{"bar"}) {
+		t.Errorf("Distinct(Name) = %v, %v, want [bar]", names, err)
+	}
+	// 逻辑删除时返回删除前的记录，已删除的记录不会再次匹配
+	var author Author
+	if err := db.Model("Author").Find().FindOneAndDelete(&author); err != nil || author.ID != "a2" || author.DeletedAt != 0 {
+		t.Errorf("FindOneAndDelete() = %+v, %v, want a2", author, err)
+	}
+	if n, _ := db.Model("Author").Find().Count(); n != 0 {
+		t.Errorf("Count() = %d, want 0", n)
+	}
+	if n, _ := db.Model("Author").Find(db.Cond{"DeletedAt $exists": true}).Unscoped().Count(); n != 2 {
+		t.Errorf("Unscoped().Count() of deleted = %d, want 2", n)
+	}
+	res, err := db.Model("Author").Find(db.Cond{"ID": "a3"}).Upsert(&Author{Name: "baz"})
+	if err != nil || !res.Inserted() || res.StringID() != "a3" {
+		t.Fatalf("Upsert() = %v, %v, want a3 inserted", res, err)
+	}
+	// 批量写入中的删除同样遵循逻辑删除规则
+	if _, err := db.Model("Author").BulkWrite([]db.WriteOp{db.DeleteOneOp(db.Cond{"ID": "a3"})}, true); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := db.Model("Author").Find(db.Cond{"DeletedAt $exists": true}).Unscoped().Count(); n != 3 {
+		t.Errorf("Unscoped().Count() of deleted = %d, want 3", n)
+	}
+}
+
+func TestMemoryAssociations(t *testing.T) {
+	setupMemory(t)
+	_, err := db.Model("Author").InsertOne(&Author{
+		ID:      "a1",
+		Name:    "foo",
+		Profile: &Profile{Bio: "hello"},
+		Books:   []Book{{Title: "b1"}, {Title: "b2"}},
+		Tags:    []Tag{{ID: "t1", Name: "go"}},
+	}, db.WithInsertOptionLooseMode(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var author Author
+	err = db.Model("Author").Find(db.Cond{"ID": "a1"}).
+		Preload("Profile").
+		Preload("Books", func(o *db.PreloadOptions) { o.OrderBys = []string{"-Title"} }).
+		Preload("Tags").
+		One(&author)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if author.Profile == nil || author.Profile.Bio != "hello" {
+		t.Errorf("Profile = %+v", author.Profile)
+	}
+	if len(author.Books) != 2 || author.Books[0].Title != "b2" {
+		t.Errorf("Books = %+v", author.Books)
+	}
+	if len(author.Tags) != 1 || author.Tags[0].Name != "go" {
+		t.Errorf("Tags = %+v", author.Tags)
+	}
+
+	var books []Book
+	if err := db.Model("Book").Find().Preload("Author").All(&books); err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 2 || books[0].Author == nil || books[0].Author.Name != "foo" {
+		t.Errorf("Books = %+v", books)
+	}
+	books = nil
+	if _, err := db.Model("Book").Find().Preload("Author").Paginate(1).Scroll(&books); err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 1 || books[0].Author == nil || books[0].Author.Name != "foo" {
+		t.Errorf("Scroll() with Preload = %+v", books)
+	}
+
+	// 删除作者：级联删除档案、置空书籍的作者、解除标签关联
+	if _, err := db.Model("Author").Find(db.Cond{"ID": "a1"}).DeleteOne(); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := db.Model("Profile").Find().Count(); n != 0 {
+		t.Errorf("Profile count = %d, want 0", n)
+	}
+	if n, _ := db.Model("Book").Find(db.Cond{"AuthorID $exists": true}).Count(); n != 0 {
+		t.Errorf("Book with author count = %d, want 0", n)
+	}
+	if n, _ := db.Model("AuthorTag").Find().Count(); n != 0 {
+		t.Errorf("AuthorTag count = %d, want 0", n)
+	}
+	if n, _ := db.Model("Tag").Find().Count(); n != 1 {
+		t.Errorf("Tag count = %d, want 1", n)
+	}
+}
+
+func TestMemoryNestedPreload(t *testing.T) {
+	setupMemory(t)
+	_, err := db.Model("Author").InsertOne(&Author{
+		ID:      "a1",
+		Name:    "foo",
+		Profile: &Profile{Bio: "hello"},
+		Books:   []Book{{Title: "b1"}, {Title: "b2"}},
+	}, db.WithInsertOptionLooseMode(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a2没有书籍，b3没有作者，中间层级为空时跳过下一层级
+	if _, err := db.Model("Author").InsertOne(&Author{ID: "a2", Name: "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Model("Book").InsertOne(&Book{ID: "b3", Title: "b3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 一对多 -> 一对一 -> 一对一，各层级可分别指定参数
+	var authors []Author
+	err = db.Model("Author").Find().OrderBy("ID").
+		Preload("Books", func(o *db.PreloadOptions) { o.OrderBys = []string{"-Title"} }).
+		Preload("Books.Author", func(o *db.PreloadOptions) { o.Select = []string{"Name"} }).
+		Preload("Books.Author.Profile").
+		All(&authors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(authors) != 2 || len(authors[0].Books) != 2 || authors[0].Books[0].Title != "b2" {
+		t.Fatalf("authors = %+v", authors)
+	}
+	for _, book := range authors[0].Books {
+		if book.Author == nil || book.Author.ID != "a1" || book.Author.Name != "foo" {
+			t.Errorf("Books.Author = %+v", book.Author)
+			continue
+		}
+		if book.Author.Profile == nil || book.Author.Profile.Bio != "hello" {
+			t.Errorf("Books.Author.Profile = %+v", book.Author.Profile)
+		}
+	}
+	if len(authors[1].Books) != 0 {
+		t.Errorf("Books of a2 = %+v, want empty", authors[1].Books)
+	}
+
+	// 一对一 -> 一对多
+	var books []Book
+	err = db.Model("Book").Find().OrderBy("Title").
+		Preload("Author.Books", func(o *db.PreloadOptions) { o.OrderBys = []string{"Title"} }).
+		Preload("Author.Profile").
+		All(&books)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 3 {
+		t.Fatalf("books = %+v", books)
+	}
+	for _, book := range books[:2] {
+		if book.Author == nil || len(book.Author.Books) != 2 || book.Author.Books[0].Title != "b1" {
+			t.Errorf("Author.Books of %s = %+v", book.Title, book.Author)
+			continue
+		}
+		if book.Author.Profile == nil || book.Author.Profile.Bio != "hello" {
+			t.Errorf("Author.Profile of %s = %+v", book.Title, book.Author.Profile)
+		}
+	}
+	if books[2].Author != nil {
+		t.Errorf("Author of b3 = %+v, want nil", books[2].Author)
+	}
+}
+
+var (
+	queryCountOnce sync.Once
+	queryCountMu   sync.Mutex
+	queryCounts    map[string]int
+)
+
+// countQueries 统计fn执行期间各元数据发起的查询次数
+func countQueries(t *testing.T, fn func()) map[string]int {
+	t.Helper()
+	queryCountOnce.Do(func() {
+		err := db.RegisterMiddleware("*:beforeQuery", func(s *db.Scope) {
+			queryCountMu.Lock()
+			defer queryCountMu.Unlock()
+			if queryCounts != nil {
+				queryCounts[s.Metadata.Name]++
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	queryCountMu.Lock()
+	queryCounts = make(map[string]int)
+	queryCountMu.Unlock()
+	fn()
+	queryCountMu.Lock()
+	defer queryCountMu.Unlock()
+	counts := queryCounts
+	queryCounts = nil
+	return counts
+}
+
+func TestMemoryPreloadQueries(t *testing.T) {
+	setupMemory(t)
+	if _, err := db.Model("Tag").InsertMany([]Tag{{ID: "t1", Name: "go"}, {ID: "t2", Name: "db"}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprintf("a%d", i)
+		_, err := db.Model("Author").InsertOne(&Author{
+			ID:      id,
+			Name:    id,
+			Profile: &Profile{Bio: id},
+			Books:   []Book{{Title: id + "-b1"}, {Title: id + "-b2"}},
+		}, db.WithInsertOptionLooseMode(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Model("AuthorTag").InsertMany([]AuthorTag{{AuthorID: id, TagID: "t1"}, {AuthorID: id, TagID: "t2"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 每个关系仅发起一次查询，REF_MANY额外查询一次中间表，与记录数无关
+	var authors []Author
+	counts := countQueries(t, func() {
+		if err := db.Model("Author").Find().Preload("Profile").Preload("Books").Preload("Tags").All(&authors); err != nil {
+			t.Fatal(err)
+		}
+	})
+	want := map[string]int{"Author": 1, "Profile": 1, "Book": 1, "AuthorTag": 1, "Tag": 1}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("queries = %v, want %v", counts, want)
+	}
+	for _, author := range authors {
+		if author.Profile == nil || len(author.Books) != 2 || len(author.Tags) != 2 {
+			t.Errorf("author = %+v", author)
+		}
+	}
+
+	var books []Book
+	counts = countQueries(t, func() {
+		if err := db.Model("Book").Find().Preload("Author.Profile").All(&books); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if want := map[string]int{"Book": 1, "Author": 1, "Profile": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("queries = %v, want %v", counts, want)
+	}
+	if len(books) != 6 || books[0].Author == nil || books[0].Author.Profile == nil {
+		t.Errorf("books = %+v", books)
+	}
+}
+
+type Shelf struct {
+	ID     string
+	Items  []ShelfItem  `db:"ref=type:HAS_MANY,dst:ShelfID,on_delete:restrict"`
+	Labels []ShelfLabel `db:"ref=type:HAS_MANY,dst:ShelfID,on_delete:cascade"`
+}
+
+type ShelfItem struct {
+	ID      string
+	ShelfID string
+}
+
+type ShelfLabel struct {
+	ID      string
+	ShelfID string
+}
+
+func TestMemoryBulkDeleteRules(t *testing.T) {
+	connectMemory(t, &Shelf{}, &ShelfItem{}, &ShelfLabel{})
+	if _, err := db.Model("Shelf").InsertMany([]Shelf{{ID: "s1"}, {ID: "s2"}, {ID: "s3"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Model("ShelfItem").InsertOne(&ShelfItem{ID: "i1", ShelfID: "s1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Model("ShelfLabel").InsertMany([]ShelfLabel{{ID: "l1", ShelfID: "s1"}, {ID: "l2", ShelfID: "s2"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 批量写入中的删除同样遵循关系的删除规则，RESTRICT校验失败的操作不影响其余操作
+	res, err := db.Model("Shelf").BulkWrite([]db.WriteOp{
+		db.DeleteOneOp(db.Cond{"ID": "s1"}),
+		db.DeleteOneOp(db.Cond{"ID": "s2"}),
+	}, false)
+	var bwe *db.BulkWriteError
+	if !errors.As(err, &bwe) || len(bwe.Errors) != 1 || bwe.Err(0) == nil {
+		t.Fatalf("BulkWrite() error = %v, want operation 0 restricted", err)
+	}
+	if res.DeletedCount != 1 {
+		t.Errorf("DeletedCount = %d, want 1", res.DeletedCount)
+	}
+	var ids []string
+	if err := db.Model("ShelfLabel").Find().Distinct("ID", &ids); err != nil || !reflect.DeepEqual(ids, []string{"l1"}) {
+		t.Errorf("ShelfLabel ids = %v, %v, want [l1]", ids, err)
+	}
+	if n, _ := db.Model("Shelf").Find(db.Cond{"ID": "s1"}).Count(); n != 1 {
+		t.Errorf("restricted shelf count = %d, want 1", n)
+	}
+
+	// 有序写入时删除规则按操作顺序处理，校验失败后的操作不会执行
+	_, err = db.Model("Shelf").BulkWrite([]db.WriteOp{
+		db.InsertOp(&Shelf{ID: "s4"}),
+		db.DeleteManyOp(db.Cond{"ID $in": []string{"s3", "s4"}}),
+		db.DeleteOneOp(db.Cond{"ID": "s1"}),
+		db.InsertOp(&Shelf{ID: "s5"}),
+	}, true)
+	if !errors.As(err, &bwe) || len(bwe.Errors) != 1 || bwe.Err(2) == nil {
+		t.Fatalf("BulkWrite(ordered) error = %v, want operation 2 restricted", err)
+	}
+	ids = nil
+	if err := db.Model("Shelf").Find().Distinct("ID", &ids); err != nil || !reflect.DeepEqual(ids, []string{"s1"}) {
+		t.Errorf("Shelf ids = %v, %v, want [s1]", ids, err)
+	}
+}
+
+func TestMemoryPreloadDestinations(t *testing.T) {
+	setupMemory(t)
+	_, err := db.Model("Author").InsertOne(&Author{
+		ID:      "a1",
+		Name:    "foo",
+		Profile: &Profile{Bio: "hello"},
+		Books:   []Book{{Title: "b1"}, {Title: "b2"}},
+		Tags:    []Tag{{ID: "t1", Name: "go"}},
+	}, db.WithInsertOptionLooseMode(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Model("Author").InsertOne(&Author{ID: "a2", Name: "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	find := func() db.Result {
+		return db.Model("Author").Find().OrderBy("ID").Preload("Profile").Preload("Books").Preload("Tags")
+	}
+
+	var author Author
+	if err := find().One(&author); err != nil {
+		t.Fatal(err)
+	}
+	if author.Profile == nil || len(author.Books) != 2 || len(author.Tags) != 1 {
+		t.Errorf("struct = %+v", author)
+	}
+
+	var authors []*Author
+	if err := find().All(&authors); err != nil {
+		t.Fatal(err)
+	}
+	if len(authors) != 2 || authors[0].Profile == nil || len(authors[0].Books) != 2 || len(authors[0].Tags) != 1 {
+		t.Fatalf("slice = %+v", authors)
+	}
+	if authors[1].Profile != nil || len(authors[1].Books) != 0 || len(authors[1].Tags) != 0 {
+		t.Errorf("slice[1] = %+v, want no associations", authors[1])
+	}
+
+	// Map记录以原始字段名回填，引用数据同为Map
+	record := make(map[string]interface{})
+	if err := find().One(&record); err != nil {
+		t.Fatal(err)
+	}
+	profile, _ := record["profile"].(map[string]interface{})
+	books, _ := record["books"].([]map[string]interface{})
+	tags, _ := record["tags"].([]map[string]interface{})
+	if profile["bio"] != "hello" || len(books) != 2 || len(tags) != 1 || tags[0]["name"] != "go" {
+		t.Errorf("map = %v", record)
+	}
+
+	var records []map[string]interface{}
+	if err := find().All(&records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("maps = %v", records)
+	}
+	if books, _ := records[0]["books"].([]map[string]interface{}); len(books) != 2 {
+		t.Errorf("maps[0] books = %v", records[0]["books"])
+	}
+	if _, has := records[1]["profile"]; has {
+		t.Errorf("maps[1] profile = %v, want none", records[1]["profile"])
+	}
+}
+
+func TestMemoryFindArguments(t *testing.T) {
+	connectMemory(t, &Memo{})
+	memos := []Memo{{ID: "m1", Content: "foo", Status: 1}, {ID: "m2", Content: "foo", Status: 2}, {ID: "m3", Content: "bar", Status: 2}}
+	if _, err := db.Model("Memo").InsertMany(memos); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		args []interface{}
+		want int
+	}{
+		{name: "key-value pairs", args: []interface{}{"Content", "foo", "Status", 2}, want: 1},
+		{name: "nil values are ignored", args: []interface{}{"Content", "foo", "Status", nil}, want: 2},
+		// 偶数个条件不能被当作键值对，否则条件会被忽略
+		{name: "two conditions", args: []interface{}{db.Cond{"Content": "foo"}, db.Cond{"Status": 2}}, want: 1},
+		{name: "one condition", args: []interface{}{db.Cond{"Status": 2}}, want: 2},
+	}
+	for _, tt := range tests {
+		if n, err := db.Model("Memo").Find(tt.args...).Count(); err != nil || n != tt.want {
+			t.Errorf("%s: Count() = %d, %v, want %d", tt.name, n, err, tt.want)
+		}
+	}
+}
+
+type Folder struct {
+	ID    string
+	Files []FolderFile `db:"ref=type:HAS_MANY,dst:FolderID,on_delete:restrict"`
+	Notes []FolderNote `db:"ref=type:HAS_MANY,dst:FolderID,on_delete:cascade"`
+}
+
+type FolderFile struct {
+	ID       string
+	FolderID string
+}
+
+type FolderNote struct {
+	ID        string
+	FolderID  string
+	DeletedAt int64
+}
+
+func TestMemoryDeleteRules(t *testing.T) {
+	connectMemory(t, &Folder{}, &FolderFile{}, &FolderNote{})
+	db.RegisterLogicDeleteRule("FolderNote", &db.LogicDeleteRule{
+		SetValue: map[string]string{"DeletedAt": "$now"},
+		GetValue: db.Cond{"DeletedAt $exists": false},
+	})
+	if _, err := db.Model("Folder").InsertMany([]Folder{{ID: "f1"}, {ID: "f2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Model("FolderFile").InsertOne(&FolderFile{ID: "x1", FolderID: "f1"}); err != nil {
+		t.Fatal(err)
+	}
+	notes := []FolderNote{{ID: "n1", FolderID: "f1"}, {ID: "n2", FolderID: "f2"}, {ID: "n3", FolderID: "f2"}}
+	if _, err := db.Model("FolderNote").InsertMany(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	// 存在RESTRICT引用时不允许删除，其他规则也不会执行
+	if n, err := db.Model("Folder").Find(db.Cond{"ID": "f1"}).DeleteOne(); err == nil || n != 0 {
+		t.Errorf("DeleteOne() = %d, %v, want a restrict error", n, err)
+	}
+	if n, _ := db.Model("Folder").Find(db.Cond{"ID": "f1"}).Count(); n != 1 {
+		t.Errorf("restricted folder count = %d, want 1", n)
+	}
+	if n, _ := db.Model("FolderNote").Find(db.Cond{"FolderID": "f1"}).Count(); n != 1 {
+		t.Errorf("notes of the restricted folder = %d, want 1", n)
+	}
+
+	// 级联删除的引用数据存在逻辑删除规则时执行逻辑删除
+	if n, err := db.Model("Folder").Find(db.Cond{"ID": "f2"}).DeleteOne(); err != nil || n != 1 {
+		t.Fatalf("DeleteOne() = %d, %v, want 1", n, err)
+	}
+	if n, _ := db.Model("FolderNote").Find(db.Cond{"FolderID": "f2"}).Count(); n != 0 {
+		t.Errorf("notes of the deleted folder = %d, want 0", n)
+	}
+	var deleted []FolderNote
+	if err := db.Model("FolderNote").Find(db.Cond{"FolderID": "f2"}).Unscoped().All(&deleted); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 2 || deleted[0].DeletedAt == 0 || deleted[1].DeletedAt == 0 {
+		t.Errorf("soft deleted notes = %+v, want 2 with DeletedAt", deleted)
+	}
+}
+
+type Owner struct {
+	ID   string
+	Name string
+	Pet  *Pet `db:"ref=type:HAS_ONE,dst:OwnerID"`
+}
+
+type Pet struct {
+	ID      string
+	Name    string
+	OwnerID string
+}
+
+func TestMemoryAssociationHooks(t *testing.T) {
+	connectMemory(t, &Owner{}, &Pet{})
+	docs := make(map[string]interface{})
+	for _, hook := range []string{"beforeCreate", "afterCreate", "beforeUpdate", "afterUpdate"} {
+		hook := hook
+		_ = db.RegisterMiddleware("Owner:"+hook, func(s *db.Scope) {
+			if hook == "beforeCreate" || hook == "afterCreate" {
+				docs[hook] = s.InsertOneDoc
+			} else {
+				docs[hook] = s.UpdateDoc
+			}
+		})
+	}
+
+	// 写入时剥离引用字段，后置钩子仍获取到调用方传入的档案
+	owner := &Owner{ID: "o1", Name: "foo", Pet: &Pet{Name: "cat"}}
+	if _, err := db.Model("Owner").InsertOne(owner); err != nil {
+		t.Fatal(err)
+	}
+	if docs["afterCreate"] != interface{}(owner) || docs["beforeCreate"] != docs["afterCreate"] || owner.Pet == nil {
+		t.Errorf("afterCreate document = %#v, want %#v", docs["afterCreate"], owner)
+	}
+	update := &Owner{Name: "bar", Pet: &Pet{Name: "dog"}}
+	if _, err := db.Model("Owner").Find(db.Cond{"ID": "o1"}).UpdateOne(update); err != nil {
+		t.Fatal(err)
+	}
+	if docs["afterUpdate"] != interface{}(update) || docs["beforeUpdate"] != docs["afterUpdate"] || update.Pet == nil {
+		t.Errorf("afterUpdate document = %#v, want %#v", docs["afterUpdate"], update)
+	}
+	var pet Pet
+	if err := db.Model("Pet").Find(db.Cond{"OwnerID": "o1"}).One(&pet); err != nil || pet.Name != "dog" {
+		t.Errorf("Pet = %+v, %v, want dog", pet, err)
+	}
+}
+
+type Entry struct {
+	ID      string
+	Status  int
+	Version int `db:"version"`
+}
+
+func TestMemoryTransactionCallbacks(t *testing.T) {
+	sess := connectMemory(t, &Entry{})
+	_ = db.RegisterMiddleware("Entry:beforeCreate", func(s *db.Scope) {
+		if doc, ok := s.InsertOneDoc.(*Entry); ok && doc.Status == 0 {
+			doc.Status = 1
+		}
+	})
+
+	// 事务中的模型同样执行中间件及内置回调，回滚后写入全部撤销
+	tx, err := sess.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Model("Entry").InsertOne(&Entry{ID: "e1"}); err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err)
+	}
+	var entry Entry
+	if err := tx.Model("Entry").Find(db.Cond{"ID": "e1"}).One(&entry); err != nil || entry.Status != 1 || entry.Version != 1 {
+		_ = tx.Rollback()
+		t.Fatalf("One() in transaction = %+v, %v, want Status 1 and Version 1", entry, err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := sess.Model("Entry").Find().Count(); n != 0 {
+		t.Errorf("Count() after Rollback() = %d, want 0", n)
+	}
+
+	errAbort := errors.New("abort")
+	err = sess.WithTransaction(func(tx db.Tx) error {
+		if _, err := tx.Model("Entry").InsertOne(&Entry{ID: "e2"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Errorf("WithTransaction() = %v, want %v", err, errAbort)
+	}
+	if n, _ := sess.Model("Entry").Find().Count(); n != 0 {
+		t.Errorf("Count() after aborted WithTransaction() = %d, want 0", n)
+	}
+}
+
+func TestMemoryNestedTransaction(t *testing.T) {
+	sess := connectMemory(t, &Entry{})
+	tx, err := sess.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Model("Entry").InsertOne(&Entry{ID: "e1"}); err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err)
+	}
+	// 嵌套事务回滚到保存点，不影响外层事务的提交
+	inner, err := sess.StartTransactionContext(tx.Context())
+	if err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err)
+	}
+	if _, err := inner.Model("Entry").InsertOne(&Entry{ID: "e2"}); err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err)
+	}
+	if err := inner.Rollback(); err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err)
+	}
+	err = sess.WithTransactionContext(tx.Context(), func(tx db.Tx) error {
+		_, err := tx.Model("Entry").InsertOne(&Entry{ID: "e3"})
+		return err
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	if err := sess.Model("Entry").Find().Distinct("ID", &ids); err != nil || !reflect.DeepEqual(ids, []string{"e1", "e3"}) {
+		t.Errorf("ids after Commit() = %v, %v, want [e1 e3]", ids, err)
+	}
+}
@@ -0,0 +1,286 @@
+package db
+
+import (
+	"context"
+	"github.com/gobwas/glob"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	timestampsRuleMap   = make(map[string]*TimestampsRule)
+	timestampsRuleMapMu sync.RWMutex
+)
+
+// TimestampsRule 自动写入时间及操作人的字段名称，未设置的字段不写入
+type TimestampsRule struct {
+	Pattern   string
+	CreatedAt string // 新增时写入当前时间
+	UpdatedAt string // 新增、修改及逻辑删除时写入当前时间
+	CreatedBy string // 新增时写入上下文中的操作人
+	UpdatedBy string // 新增、修改及逻辑删除时写入上下文中的操作人
+	g         glob.Glob
+}
+
+// RegisterTimestampsRule 注册时间戳规则，pattern为Glob语法，规则优先级为元数据规则 > 组规则 > 全局规则
+func RegisterTimestampsRule(pattern string, rule *TimestampsRule) {
+	if rule == nil {
+		return
+	}
+	pattern = strings.TrimSpace(pattern)
+	if pattern != "" {
+		rule.Pattern = pattern
+	}
+	if rule.Pattern == "" {
+		return
+	}
+	rule.g = glob.MustCompile(rule.Pattern)
+
+	timestampsRuleMapMu.Lock()
+	defer timestampsRuleMapMu.Unlock()
+
+	timestampsRuleMap[rule.Pattern] = rule
+}
+
+func UnregisterTimestampsRule(pattern string) {
+	timestampsRuleMapMu.Lock()
+	defer timestampsRuleMapMu.Unlock()
+
+	delete(timestampsRuleMap, strings.TrimSpace(pattern))
+}
+
+// LookupTimestampsRule 返回元数据生效的时间戳规则，多条组规则匹配时取最长的规则
+func LookupTimestampsRule(name string) *TimestampsRule {
+	timestampsRuleMapMu.RLock()
+	defer timestampsRuleMapMu.RUnlock()
+
+	if rule := timestampsRuleMap[name]; rule != nil {
+		return rule
+	}
+	patterns := make([]string, 0, len(timestampsRuleMap))
+	for pattern := range timestampsRuleMap {
+		if pattern != "*" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, pattern := range patterns {
+		if rule := timestampsRuleMap[pattern]; rule.g.Match(name) {
+			return rule
+		}
+	}
+	return timestampsRuleMap["*"]
+}
+
+type actorContextKey struct{}
+
+// WithActor 返回携带操作人的上下文，用于写入CreatedBy及UpdatedBy字段
+func WithActor(ctx context.Context, actor interface{}) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext 返回上下文中的操作人，未设置时返回nil
+func ActorFromContext(ctx context.Context) interface{} {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Value(actorContextKey{})
+}
+
+// values 返回需要写入的字段值，以字段名称为键，元数据中未声明的字段及上下文中没有操作人时忽略
+func (rule *TimestampsRule) values(meta *Metadata, ctx context.Context, create bool) map[string]interface{} {
+	var (
+		now    = time.Now()
+		actor  = ActorFromContext(ctx)
+		values = make(map[string]interface{})
+	)
+	set := func(name string, v interface{}) {
+		if f, has := meta.FieldByName(name); has && name != "" && v != nil {
+			values[f.Name] = v
+		}
+	}
+	if create {
+		set(rule.CreatedAt, timestampValue(meta, rule.CreatedAt, now))
+		set(rule.CreatedBy, actor)
+	}
+	set(rule.UpdatedAt, timestampValue(meta, rule.UpdatedAt, now))
+	set(rule.UpdatedBy, actor)
+	return values
+}
+
+// timestampValue 按字段类型返回时间，Int及Float字段为Unix时间戳，String字段为ISO格式字符串
+func timestampValue(meta *Metadata, name string, now time.Time) interface{} {
+	f, _ := meta.FieldByName(name)
+	switch f.Type {
+	case Int:
+		return now.Unix()
+	case Float:
+		return float64(now.UnixNano()) / float64(time.Second)
+	case String:
+		return now.UTC().Format(time.RFC3339)
+	}
+	return now
+}
+
+// timestampsCallback 新增时为未传入的字段写入时间及操作人，修改时覆盖档案中的值
+func timestampsCallback(s *Scope) {
+	if s.HasError() {
+		return
+	}
+	rule := LookupTimestampsRule(s.Metadata.Name)
+	if rule == nil {
+		return
+	}
+	meta := &s.Metadata
+	switch s.Action {
+	case ActionInsertOne, ActionInsertMany:
+		values := rule.values(meta, s.Context, true)
+		s.AddError(s.eachInsertDoc(func(_ int, record reflect.Value) error {
+			return stampRecord(meta, record, values)
+		}))
+	case ActionUpdateOne, ActionUpdateMany, ActionFindOneAndUpdate:
+		doc, err := stampUpdateDoc(meta, s.UpdateDoc, rule.values(meta, s.Context, false))
+		if err != nil {
+			s.AddError(err)
+			return
+		}
+		s.UpdateDoc = doc
+	case ActionUpsert:
+		// 新增时才写入的字段通过SetOnInsert写入，修改已有记录时不会覆盖
+		values := rule.values(meta, s.Context, true)
+		created := make(map[string]interface{})
+		for _, name := range []string{rule.CreatedAt, rule.CreatedBy} {
+			if f, has := meta.FieldByName(name); has && values[f.Name] != nil {
+				created[f.Name] = values[f.Name]
+				delete(values, f.Name)
+			}
+		}
+		doc, err := stampUpdateDoc(meta, s.UpdateDoc, values)
+		if err != nil {
+			s.AddError(err)
+			return
+		}
+		s.UpdateDoc = stampInsertOnly(meta, doc, created)
+	}
+}
+
+// stampRecord 写入新增档案中未传入的字段，Map档案已由eachInsertDoc复制
+func stampRecord(meta *Metadata, record reflect.Value, values map[string]interface{}) error {
+	record = indirectValue(record)
+	for name, v := range values {
+		field, _ := meta.FieldByName(name)
+		switch record.Kind() {
+		case reflect.Struct:
+			f := record.FieldByName(name)
+			if !f.IsValid() || !f.CanSet() || !f.IsZero() {
+				continue
+			}
+			if !assignDefaultValue(f, v) {
+				return Errorf(`cannot assign %v to field %s.%s`, v, meta.Name, name)
+			}
+		case reflect.Map:
+			if !IsNil(mapIndex(record, name, field.MustNativeName())) {
+				continue
+			}
+			if !setMapValue(record, name, v) {
+				return Errorf(`cannot assign %v to field %s.%s`, v, meta.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// stampUpdateDoc 返回写入字段后的修改内容，Map及修改操作符会复制后再写入，不修改调用方传入的内容
+func stampUpdateDoc(meta *Metadata, doc interface{}, values map[string]interface{}) (interface{}, error) {
+	if len(values) == 0 {
+		return doc, nil
+	}
+	if u, ok := doc.(*Updater); ok {
+		cp := &Updater{}
+		for _, entry := range u.entries {
+			if _, has := values[metadataFieldName(meta, entry.Key)]; !has {
+				cp.entries = append(cp.entries, entry)
+			}
+		}
+		for _, name := range sortedKeys(values) {
+			cp.Set(name, values[name])
+		}
+		return cp, nil
+	}
+	doc, record := addressableDoc(doc)
+	record = indirectValue(record)
+	switch record.Kind() {
+	case reflect.Struct:
+		for name, v := range values {
+			if f := record.FieldByName(name); f.IsValid() && f.CanSet() && !assignDefaultValue(f, v) {
+				return nil, Errorf(`cannot assign %v to field %s.%s`, v, meta.Name, name)
+			}
+		}
+	case reflect.Map:
+		cp := make(map[string]interface{})
+		iter := record.MapRange()
+		for iter.Next() {
+			key, ok := iter.Key().Interface().(string)
+			if !ok {
+				return doc, nil
+			}
+			if _, has := values[metadataFieldName(meta, key)]; !has {
+				cp[key] = iter.Value().Interface()
+			}
+		}
+		for name, v := range values {
+			cp[name] = v
+		}
+		return cp, nil
+	}
+	return doc, nil
+}
+
+// stampInsertOnly 将修改内容转换为Updater，并为未传入的字段追加SetOnInsert
+func stampInsertOnly(meta *Metadata, doc interface{}, values map[string]interface{}) interface{} {
+	if len(values) == 0 {
+		return doc
+	}
+	u, ok := toUpdater(meta, doc)
+	if !ok {
+		return doc
+	}
+	for _, entry := range u.entries {
+		delete(values, metadataFieldName(meta, entry.Key))
+	}
+	for _, name := range sortedKeys(values) {
+		u.SetOnInsert(name, values[name])
+	}
+	return u
+}
+
+// setMapValue 写入Map档案，值的类型与Map元素类型不兼容时返回false
+func setMapValue(record reflect.Value, name string, v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	elemType := record.Type().Elem()
+	if !rv.Type().AssignableTo(elemType) {
+		if !rv.Type().ConvertibleTo(elemType) || (rv.Kind() == reflect.String) != (elemType.Kind() == reflect.String) {
+			return false
+		}
+		rv = rv.Convert(elemType)
+	}
+	record.SetMapIndex(reflect.ValueOf(name), rv)
+	return true
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
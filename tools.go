@@ -36,6 +36,14 @@ func IsNil(i interface{}) bool {
 	return vi.IsNil()
 }
 
+func isZeroValue(i interface{}) bool {
+	if IsNil(i) {
+		return true
+	}
+	rv := reflect.ValueOf(i)
+	return reflect.DeepEqual(rv.Interface(), reflect.Zero(rv.Type()).Interface())
+}
+
 func JSONAPI() jsoniter.API {
 	return json
 }
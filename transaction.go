@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 事务错误标签
+const (
+	LabelTransientTransactionError      = "TransientTransactionError"
+	LabelUnknownTransactionCommitResult = "UnknownTransactionCommitResult"
+)
+
+// TransactionError 携带错误标签的事务错误，调用方可根据标签判断是否可以重试
+type TransactionError struct {
+	Labels []string
+	Err    error
+}
+
+func (e *TransactionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
+
+// Is 携带TransientTransactionError标签的错误表示事务已中止
+func (e *TransactionError) Is(target error) bool {
+	return target == ErrTxAborted && e.HasLabel(LabelTransientTransactionError)
+}
+
+func (e *TransactionError) HasLabel(label string) bool {
+	for _, l := range e.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorLabels 返回错误携带的事务错误标签
+func ErrorLabels(err error) []string {
+	var te *TransactionError
+	if errors.As(err, &te) {
+		return te.Labels
+	}
+	return nil
+}
+
+// HasErrorLabel 判断错误是否携带指定的事务错误标签
+func HasErrorLabel(err error, label string) bool {
+	var te *TransactionError
+	return errors.As(err, &te) && te.HasLabel(label)
+}
+
+// RetryPolicy 事务重试策略
+type RetryPolicy struct {
+	MaxAttempts    int           // 最大执行次数（包括首次执行），默认3次
+	InitialBackoff time.Duration // 首次重试前的等待时间，之后每次翻倍，默认10ms
+	MaxBackoff     time.Duration // 最长等待时间，默认1s
+	Labels         []string      // 需要重试的错误标签，默认为TransientTransactionError和UnknownTransactionCommitResult
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return 3
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	var (
+		d   = p.InitialBackoff
+		max = p.MaxBackoff
+	)
+	if d <= 0 {
+		d = 10 * time.Millisecond
+	}
+	if max <= 0 {
+		max = time.Second
+	}
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+func (p *RetryPolicy) labels() []string {
+	if len(p.Labels) > 0 {
+		return p.Labels
+	}
+	return []string{LabelTransientTransactionError, LabelUnknownTransactionCommitResult}
+}
+
+// retryTransaction 判断是否需要重新执行整个事务，提交结果未知时事务可能已生效，只能重试提交
+func (p *RetryPolicy) retryTransaction(err error) bool {
+	for _, label := range p.labels() {
+		if label != LabelUnknownTransactionCommitResult && HasErrorLabel(err, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryCommit 判断是否需要重试提交
+func (p *RetryPolicy) retryCommit(err error) bool {
+	for _, label := range p.labels() {
+		if label == LabelUnknownTransactionCommitResult {
+			return HasErrorLabel(err, label) && !HasErrorLabel(err, LabelTransientTransactionError)
+		}
+	}
+	return false
+}
+
+// wait 等待重试，上下文取消时返回错误
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(p.backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
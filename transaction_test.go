@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type retryClient struct {
+	Client
+	commitErrs []error
+	starts     int
+	commits    int
+	rollbacks  int
+}
+
+func (c *retryClient) StartTransaction() (Tx, error) {
+	c.starts++
+	return &retryTx{client: c}, nil
+}
+
+type retryTx struct {
+	Tx
+	client *retryClient
+}
+
+func (t *retryTx) Context() context.Context {
+	return context.Background()
+}
+
+func (t *retryTx) Commit() error {
+	c := t.client
+	c.commits++
+	if len(c.commitErrs) > 0 {
+		err := c.commitErrs[0]
+		c.commitErrs = c.commitErrs[1:]
+		return err
+	}
+	return nil
+}
+
+func (t *retryTx) Rollback() error {
+	t.client.rollbacks++
+	return nil
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	want := []time.Duration{10, 20, 40, 50, 50}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w*time.Millisecond)
+		}
+	}
+}
+
+func TestTransactionErrorIs(t *testing.T) {
+	transient := Errorf("%w", &TransactionError{Labels: []string{LabelTransientTransactionError}, Err: errors.New("conflict")})
+	if !errors.Is(transient, ErrTxAborted) {
+		t.Error("transient transaction error should match ErrTxAborted")
+	}
+	unknown := &TransactionError{Labels: []string{LabelUnknownTransactionCommitResult}, Err: errors.New("timeout")}
+	if errors.Is(unknown, ErrTxAborted) {
+		t.Error("unknown commit result should not match ErrTxAborted")
+	}
+}
+
+func TestWithTransactionRetry(t *testing.T) {
+	var (
+		transient = &TransactionError{Labels: []string{LabelTransientTransactionError}, Err: errors.New("transient")}
+		unknown   = &TransactionError{Labels: []string{LabelUnknownTransactionCommitResult}, Err: errors.New("unknown")}
+		policy    = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	)
+	tests := []struct {
+		name       string
+		commitErrs []error
+		fnErr      error
+		wantErr    error
+		wantRuns   int
+		wantCommit int
+	}{
+		{name: "transient", commitErrs: []error{transient}, wantRuns: 2, wantCommit: 2},
+		{name: "unknown commit result", commitErrs: []error{unknown, unknown}, wantRuns: 1, wantCommit: 3},
+		{name: "exhausted", commitErrs: []error{transient, transient, transient}, wantErr: transient, wantRuns: 3, wantCommit: 3},
+		{name: "permanent", fnErr: errors.New("permanent"), wantRuns: 1},
+		{name: "labeled fn error", fnErr: Errorf("%w", transient), wantErr: transient, wantRuns: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				raw  = &retryClient{commitErrs: tt.commitErrs}
+				cs   = &clientWrapper{rawClient: raw, retryPolicy: policy}
+				runs int
+			)
+			err := cs.WithTransaction(func(Tx) error {
+				runs++
+				return tt.fnErr
+			})
+			switch {
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Errorf("WithTransaction() error = %v, want %v", err, tt.wantErr)
+			case tt.wantErr == nil && tt.fnErr == nil && err != nil:
+				t.Errorf("WithTransaction() error = %v", err)
+			}
+			if runs != tt.wantRuns || raw.commits != tt.wantCommit {
+				t.Errorf("runs = %d, commits = %d, want %d, %d", runs, raw.commits, tt.wantRuns, tt.wantCommit)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"reflect"
+	"sort"
+)
+
+const (
+	UpdateOperatorSet         = "$set"
+	UpdateOperatorSetOnInsert = "$setOnInsert"
+	UpdateOperatorInc         = "$inc"
+	UpdateOperatorPush        = "$push"
+	UpdateOperatorPull        = "$pull"
+	UpdateOperatorUnset       = "$unset"
+)
+
+// UpdateEntry 单个字段的修改
+type UpdateEntry struct {
+	Key      string
+	Operator string
+	Value    interface{}
+}
+
+// Updater 与数据源无关的修改内容，可代替结构体或map传入UpdateOne、UpdateMany等方法
+type Updater struct {
+	entries []UpdateEntry
+}
+
+func Update() *Updater {
+	return &Updater{}
+}
+
+func (u *Updater) add(key, operator string, value interface{}) *Updater {
+	if key != "" {
+		u.entries = append(u.entries, UpdateEntry{Key: key, Operator: operator, Value: value})
+	}
+	return u
+}
+
+// Set 设置字段的值
+func (u *Updater) Set(key string, value interface{}) *Updater {
+	return u.add(key, UpdateOperatorSet, value)
+}
+
+// SetOnInsert 仅在Upsert新增记录时设置字段的值，修改已有记录时忽略
+func (u *Updater) SetOnInsert(key string, value interface{}) *Updater {
+	return u.add(key, UpdateOperatorSetOnInsert, value)
+}
+
+// Inc 将数值字段增加指定的值，传入负数时为减少
+func (u *Updater) Inc(key string, value interface{}) *Updater {
+	return u.add(key, UpdateOperatorInc, value)
+}
+
+// Push 向数组字段末尾追加元素
+func (u *Updater) Push(key string, value interface{}) *Updater {
+	return u.add(key, UpdateOperatorPush, value)
+}
+
+// Pull 从数组字段中移除所有等于指定值的元素
+func (u *Updater) Pull(key string, value interface{}) *Updater {
+	return u.add(key, UpdateOperatorPull, value)
+}
+
+// Unset 清空字段的值
+func (u *Updater) Unset(keys ...string) *Updater {
+	for _, key := range keys {
+		u.add(key, UpdateOperatorUnset, nil)
+	}
+	return u
+}
+
+func (u *Updater) Entries() []UpdateEntry {
+	return append([]UpdateEntry(nil), u.entries...)
+}
+
+// NativeEntries 返回字段名已转换为原始名称的修改内容
+func (u *Updater) NativeEntries(meta Metadata) []UpdateEntry {
+	entries := u.Entries()
+	for i, item := range entries {
+		entries[i].Key = meta.MustFieldNativeName(item.Key)
+	}
+	return entries
+}
+
+// Has 是否修改了指定字段
+func (u *Updater) Has(key string) bool {
+	for _, item := range u.entries {
+		if item.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// toUpdater 将结构体或Map修改内容转换为Updater，与各适配器一致忽略结构体中的零值字段，
+// 修改内容包含关联字段或无法转换时返回false
+func toUpdater(meta *Metadata, doc interface{}) (*Updater, bool) {
+	if u, ok := doc.(*Updater); ok {
+		return &Updater{entries: u.Entries()}, true
+	}
+	u := &Updater{}
+	record := indirectValue(reflect.ValueOf(doc))
+	switch record.Kind() {
+	case reflect.Struct:
+		for i := 0; i < record.NumField(); i++ {
+			sf, f := record.Type().Field(i), record.Field(i)
+			if sf.PkgPath != "" || f.IsZero() {
+				continue
+			}
+			if isRelationshipField(meta, sf.Name) {
+				return nil, false
+			}
+			u.Set(sf.Name, f.Interface())
+		}
+	case reflect.Map:
+		for _, k := range record.MapKeys() {
+			key, ok := k.Interface().(string)
+			if !ok || isRelationshipField(meta, key) {
+				return nil, false
+			}
+			u.Set(key, record.MapIndex(k).Interface())
+		}
+		sort.Slice(u.entries, func(i, j int) bool { return u.entries[i].Key < u.entries[j].Key })
+	default:
+		return nil, false
+	}
+	return u, true
+}
+
+func isRelationshipField(meta *Metadata, name string) bool {
+	f, has := meta.FieldByName(name)
+	return has && f.Relationship.Type != ""
+}
@@ -0,0 +1,383 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var errRequired = errors.New("is required")
+
+// validateCallback 按字段元数据校验新增及修改的档案，修改时仅校验档案中包含的字段
+func validateCallback(s *Scope) {
+	if s.HasError() {
+		return
+	}
+	meta := &s.Metadata
+	switch s.Action {
+	case ActionInsertOne, ActionInsertMany:
+		s.AddError(s.eachInsertDoc(func(i int, record reflect.Value) error {
+			if errs := validateRecord(meta, record, true); len(errs) > 0 {
+				return &ValidationError{Index: i, Errors: errs}
+			}
+			return nil
+		}))
+	case ActionUpdateOne, ActionUpdateMany, ActionUpsert, ActionFindOneAndUpdate:
+		var errs []FieldError
+		if u, ok := s.UpdateDoc.(*Updater); ok {
+			// 在副本上裁剪空白，不修改调用方传入的修改内容
+			cp := &Updater{entries: u.Entries()}
+			s.UpdateDoc = cp
+			errs = validateUpdater(meta, cp)
+		} else {
+			doc, record := addressableDoc(s.UpdateDoc)
+			s.UpdateDoc = doc
+			errs = validateRecord(meta, record, false)
+		}
+		if len(errs) > 0 {
+			s.AddError(&ValidationError{Errors: errs})
+		}
+	}
+}
+
+// fieldState 档案中单个字段的取值情况
+type fieldState struct {
+	present bool
+	blank   bool
+	value   interface{}
+}
+
+// validateRecord 校验结构体或Map档案，create为false时仅校验档案中包含的字段
+func validateRecord(meta *Metadata, record reflect.Value, create bool) []FieldError {
+	record = indirectValue(record)
+	if record.Kind() != reflect.Struct && record.Kind() != reflect.Map {
+		return nil
+	}
+	states := make(map[string]fieldState)
+	for name, field := range meta.Properties {
+		if field.Relationship.Type != "" {
+			continue
+		}
+		value, present := validateFieldValue(record, &field)
+		if !present {
+			states[name] = fieldState{blank: true}
+			continue
+		}
+		if field.Trim != "" {
+			value = trimFieldValue(record, &field, value)
+		}
+		v := value.Interface()
+		states[name] = fieldState{present: true, blank: isBlankValue(v), value: v}
+	}
+	return validateStates(meta, states, create)
+}
+
+// validateUpdater 校验修改操作符，$inc仅校验类型，$push及$pull仅校验枚举值，$set的字符串值会就地裁剪
+func validateUpdater(meta *Metadata, u *Updater) []FieldError {
+	var (
+		states = make(map[string]fieldState)
+		errs   []FieldError
+	)
+	for i, entry := range u.entries {
+		field, has := meta.FieldByName(entry.Key)
+		if !has || field.Relationship.Type != "" {
+			continue
+		}
+		var err error
+		switch entry.Operator {
+		case UpdateOperatorSet, UpdateOperatorSetOnInsert:
+			if s, ok := entry.Value.(string); ok && field.Trim != "" {
+				u.entries[i].Value = trimString(field.Trim, s)
+			}
+			v := u.entries[i].Value
+			states[field.Name] = fieldState{present: true, blank: isBlankValue(v), value: v}
+		case UpdateOperatorUnset:
+			states[field.Name] = fieldState{present: true, blank: true}
+		case UpdateOperatorInc:
+			if !compatibleType(field.Type, entry.Value) {
+				err = fmt.Errorf("expected %s value, got %T", field.Type, entry.Value)
+			}
+		case UpdateOperatorPush, UpdateOperatorPull:
+			if !inEnum(field.Enum, entry.Value) {
+				err = fmt.Errorf("value %v is not in enum", entry.Value)
+			}
+		}
+		if err != nil {
+			errs = append(errs, newFieldError(&field, err))
+		}
+	}
+	errs = append(errs, validateStates(meta, states, false)...)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Name < errs[j].Name })
+	return errs
+}
+
+// validateStates 按字段名称顺序校验必填、类型及枚举值，create为false时未传入的字段视为保持原值
+func validateStates(meta *Metadata, states map[string]fieldState, create bool) (errs []FieldError) {
+	names := sortedFieldNames(meta)
+	groups := make(map[string][]string)
+	for _, name := range names {
+		if kind, arg := requiredRule(meta.Properties[name].Required); kind == requiredGroup {
+			groups[arg] = append(groups[arg], name)
+		}
+	}
+	for _, name := range names {
+		state, has := states[name]
+		if !has {
+			continue
+		}
+		field := meta.Properties[name]
+		if err := checkRequired(&field, state, states, groups, create); err != nil {
+			errs = append(errs, newFieldError(&field, err))
+			continue
+		}
+		if state.blank {
+			continue
+		}
+		if !compatibleType(field.Type, state.value) {
+			errs = append(errs, newFieldError(&field, fmt.Errorf("expected %s value, got %T", field.Type, state.value)))
+		} else if !inEnum(field.Enum, state.value) {
+			errs = append(errs, newFieldError(&field, fmt.Errorf("value %v is not in enum", state.value)))
+		}
+	}
+	return
+}
+
+const (
+	requiredNone = iota
+	requiredAlways
+	requiredWith  // +字段名：指定字段不为空时必填
+	requiredGroup // 分组名称：组内字段不能全部为空
+)
+
+func requiredRule(s string) (int, string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return requiredNone, ""
+	}
+	if v, err := strconv.ParseBool(s); err == nil {
+		if v {
+			return requiredAlways, ""
+		}
+		return requiredNone, ""
+	}
+	if strings.HasPrefix(s, "+") {
+		return requiredWith, strings.TrimSpace(s[1:])
+	}
+	return requiredGroup, s
+}
+
+// checkRequired 修改时仅当相关字段均已传入才能判断是否为空
+func checkRequired(field *Field, state fieldState, states map[string]fieldState, groups map[string][]string, create bool) error {
+	if !state.blank || (!create && !state.present) {
+		return nil
+	}
+	kind, arg := requiredRule(field.Required)
+	switch kind {
+	case requiredAlways:
+		return errRequired
+	case requiredWith:
+		if other, has := states[arg]; has && !other.blank {
+			return fmt.Errorf("is required when %s is set", arg)
+		}
+	case requiredGroup:
+		for _, name := range groups[arg] {
+			if other, has := states[name]; (has && !other.blank) || (!create && !other.present) {
+				return nil
+			}
+		}
+		return fmt.Errorf("at least one of %s is required", strings.Join(groups[arg], ", "))
+	}
+	return nil
+}
+
+func newFieldError(field *Field, err error) FieldError {
+	return FieldError{Name: field.Name, DisplayName: field.DisplayName, Err: err}
+}
+
+// validateFieldValue 读取档案中的字段值，结构体中的零值视为未传入
+func validateFieldValue(record reflect.Value, field *Field) (reflect.Value, bool) {
+	switch record.Kind() {
+	case reflect.Struct:
+		sf, has := record.Type().FieldByName(field.Name)
+		if !has || sf.PkgPath != "" {
+			return reflect.Value{}, false
+		}
+		v := record.FieldByIndex(sf.Index)
+		return v, !v.IsZero()
+	case reflect.Map:
+		for _, key := range []string{field.Name, field.MustNativeName()} {
+			if v := record.MapIndex(reflect.ValueOf(key)); v.IsValid() {
+				return v, true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// trimFieldValue 去除字符串字段值两端的空白并写回档案，无法写回时仅返回去除后的值
+func trimFieldValue(record reflect.Value, field *Field, value reflect.Value) reflect.Value {
+	target := value
+	for target.Kind() == reflect.Ptr || target.Kind() == reflect.Interface {
+		if target.IsNil() {
+			return value
+		}
+		target = target.Elem()
+	}
+	if target.Kind() != reflect.String {
+		return value
+	}
+	trimmed := trimString(field.Trim, target.String())
+	if target.CanSet() {
+		target.SetString(trimmed)
+		return value
+	}
+	tv := reflect.ValueOf(trimmed).Convert(target.Type())
+	if record.Kind() == reflect.Map && value.Kind() != reflect.Ptr && tv.Type().AssignableTo(record.Type().Elem()) {
+		for _, key := range []string{field.Name, field.MustNativeName()} {
+			if record.MapIndex(reflect.ValueOf(key)).IsValid() {
+				record.SetMapIndex(reflect.ValueOf(key), tv)
+				break
+			}
+		}
+	}
+	return tv
+}
+
+// trimString 按Trim设置裁剪空白，支持both、start及end，true等同于both
+func trimString(mode, s string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "start":
+		return strings.TrimLeftFunc(s, unicode.IsSpace)
+	case "end":
+		return strings.TrimRightFunc(s, unicode.IsSpace)
+	case "both":
+		return strings.TrimSpace(s)
+	}
+	if parseBoolString(mode) {
+		return strings.TrimSpace(s)
+	}
+	return s
+}
+
+func sortedFieldNames(meta *Metadata) []string {
+	names := make([]string, 0, len(meta.Properties))
+	for name := range meta.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parseBoolString(s string) bool {
+	v, _ := strconv.ParseBool(s)
+	return v
+}
+
+// validateScalar 返回解引用后的值，实现driver.Valuer的类型返回其数据库值
+func validateScalar(v interface{}) interface{} {
+	rv := indirectValue(reflect.ValueOf(v))
+	if !rv.IsValid() || !rv.CanInterface() {
+		return nil
+	}
+	if valuer, ok := rv.Interface().(driver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil {
+			return dv
+		}
+	}
+	return rv.Interface()
+}
+
+func isBlankValue(v interface{}) bool {
+	v = validateScalar(v)
+	if v == nil {
+		return true
+	}
+	if s, ok := v.(string); ok {
+		return IsBlankString(s)
+	}
+	return false
+}
+
+// compatibleType 判断值是否可写入指定类型的字段，值本身或其数据库值匹配即可，未知类型不做校验
+func compatibleType(typ string, v interface{}) bool {
+	sv := validateScalar(v)
+	if sv == nil {
+		return true
+	}
+	rv := indirectValue(reflect.ValueOf(v))
+	return matchType(typ, rv) || matchType(typ, reflect.ValueOf(sv))
+}
+
+func matchType(typ string, rv reflect.Value) bool {
+	if _, ok := rv.Interface().(time.Time); ok {
+		return typ == Datetime || typ == Object
+	}
+	switch typ {
+	case String:
+		return rv.Kind() == reflect.String
+	case Int:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return true
+		case reflect.Float32, reflect.Float64:
+			// 由JSON解析的数值均为浮点数
+			return rv.Float() == math.Trunc(rv.Float())
+		}
+		return false
+	case Float:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			return true
+		}
+		return false
+	case Bool:
+		return rv.Kind() == reflect.Bool
+	case Datetime:
+		switch rv.Kind() {
+		case reflect.String:
+			_, err := time.Parse(time.RFC3339Nano, rv.String())
+			return err == nil
+		case reflect.Int, reflect.Int32, reflect.Int64, reflect.Float64:
+			// Unix时间戳
+			return true
+		}
+		return false
+	case Object:
+		return rv.Kind() == reflect.Struct || rv.Kind() == reflect.Map
+	case Array:
+		return rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array
+	}
+	return true
+}
+
+// inEnum 判断值是否为枚举值之一，数组逐个校验元素，未声明枚举时不做校验
+func inEnum(enum Enum, v interface{}) bool {
+	if len(enum) == 0 {
+		return true
+	}
+	v = validateScalar(v)
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < rv.Len(); i++ {
+			if !inEnum(enum, rv.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	_, has := enum.ItemByValue(fmt.Sprintf("%v", v))
+	return has
+}
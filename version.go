@@ -0,0 +1,184 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// VersionFieldName 返回乐观锁版本号字段名称，未声明时返回空字符串
+func (m Metadata) VersionFieldName() string {
+	var names []string
+	for name, f := range m.Properties {
+		if ok, _ := strconv.ParseBool(f.Version); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// versionState 乐观锁修改前记录的版本号，修改成功后写回调用方传入的结构体
+type versionState struct {
+	name    string
+	current int64
+	checked bool // 档案中包含版本号，需检查是否匹配
+	record  reflect.Value
+}
+
+// versionCallback 新增时版本号默认为1；修改时若档案中包含版本号，则以其为查询条件
+func versionCallback(s *Scope) {
+	s.Store().Delete("db:version")
+	if s.HasError() {
+		return
+	}
+	name := s.Metadata.VersionFieldName()
+	if name == "" {
+		return
+	}
+	switch s.Action {
+	case ActionInsertOne, ActionInsertMany:
+		s.AddError(s.eachInsertDoc(func(_ int, record reflect.Value) error {
+			return stampRecord(&s.Metadata, record, map[string]interface{}{name: int64(1)})
+		}))
+	case ActionUpdateOne, ActionUpdateMany, ActionUpsert, ActionFindOneAndUpdate:
+		state := versionState{name: name}
+		if err := currentVersion(&s.Metadata, s.UpdateDoc, &state); err != nil {
+			s.AddError(err)
+			return
+		}
+		if state.checked {
+			s.AddCondition(Cond{name: state.current})
+		}
+		s.Store().Store("db:version", &state)
+	}
+}
+
+// incrementVersionCallback 将修改内容转换为Updater并递增版本号，档案中的版本号不会写入
+func incrementVersionCallback(s *Scope) {
+	v, ok := s.Store().Load("db:version")
+	if !ok || s.HasError() {
+		return
+	}
+	state := v.(*versionState)
+	u, ok := toUpdater(&s.Metadata, s.UpdateDoc)
+	if !ok {
+		s.AddError(Errorf(`cannot increment version field %s.%s of %T`, s.Metadata.Name, state.name, s.UpdateDoc))
+		return
+	}
+	cp := &Updater{}
+	for _, entry := range u.entries {
+		if metadataFieldName(&s.Metadata, entry.Key) != state.name {
+			cp.entries = append(cp.entries, entry)
+		}
+	}
+	s.UpdateDoc = cp.Inc(state.name, 1)
+	if s.Action == ActionFindOneAndUpdate && state.checked {
+		// 未匹配到记录时由checkVersionCallback转换为ErrStaleObject
+		s.QueryOptions = &QueryOptions{ReturnNotFound: true}
+	}
+}
+
+// checkVersionCallback 档案中包含版本号但未修改任何记录时返回ErrStaleObject，Upsert未匹配时会新增记录，不做检查
+func checkVersionCallback(s *Scope) {
+	v, ok := s.Store().Load("db:version")
+	if !ok {
+		return
+	}
+	state := v.(*versionState)
+	if !state.checked || s.Action == ActionUpsert {
+		return
+	}
+	stale := false
+	switch {
+	case s.Action == ActionFindOneAndUpdate && errors.Is(s.Error, ErrNotFound):
+		s.Error, stale = nil, true
+	case s.HasError():
+		return
+	case s.Action != ActionFindOneAndUpdate:
+		stale = s.RecordsAffected == 0
+	}
+	if stale {
+		s.AddError(staleObjectError(&s.Metadata, state))
+		return
+	}
+	state.writeBack()
+}
+
+// writeBack 修改成功后将新的版本号写回调用方传入的结构体
+func (state *versionState) writeBack() {
+	if state.record.IsValid() {
+		if f := state.record.FieldByName(state.name); f.IsValid() && f.CanSet() {
+			assignValue(f, state.current+1)
+		}
+	}
+}
+
+// checkedVersion 返回需检查版本号的修改操作的版本号，无需检查时返回nil
+func (s *Scope) checkedVersion() *versionState {
+	if v, ok := s.Store().Load("db:version"); ok && v.(*versionState).checked {
+		return v.(*versionState)
+	}
+	return nil
+}
+
+func staleObjectError(meta *Metadata, state *versionState) error {
+	return Errorf(`%w: %s with %s %d`, ErrStaleObject, meta.Name, state.name, state.current)
+}
+
+// currentVersion 读取档案中的版本号，结构体中的零值视为未传入
+func currentVersion(meta *Metadata, doc interface{}, state *versionState) error {
+	var v interface{}
+	if u, ok := doc.(*Updater); ok {
+		for _, entry := range u.entries {
+			if metadataFieldName(meta, entry.Key) == state.name && entry.Operator == UpdateOperatorSet {
+				v = entry.Value
+			}
+		}
+	} else {
+		rv := reflect.ValueOf(doc)
+		record := indirectValue(rv)
+		switch record.Kind() {
+		case reflect.Struct:
+			f := record.FieldByName(state.name)
+			if !f.IsValid() || f.IsZero() || !f.CanInterface() {
+				return nil
+			}
+			v = f.Interface()
+			if rv.Kind() == reflect.Ptr {
+				state.record = record
+			}
+		case reflect.Map:
+			field, _ := meta.FieldByName(state.name)
+			v = mapIndex(record, state.name, field.MustNativeName())
+		}
+	}
+	if IsNil(v) {
+		return nil
+	}
+	current, err := versionValue(state.name, v)
+	if err != nil {
+		return err
+	}
+	state.current, state.checked = current, true
+	return nil
+}
+
+func versionValue(name string, v interface{}) (int64, error) {
+	rv := indirectValue(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == float64(int64(f)) {
+			return int64(f), nil
+		}
+	}
+	return 0, Errorf(`version field %s must be an integer, got %T`, name, v)
+}